@@ -293,6 +293,26 @@ type (
 	Dictionary struct {
 		Token  token.Token
 		Object map[Expression]Expression
+		Keys   []Expression // keys of Object in source order
+	}
+
+	// DeleteExpression removes a property, delete apple.color or delete apple["color"]
+	DeleteExpression struct {
+		Token      token.Token
+		Expression Expression // either an *Index or a *Member
+	}
+
+	// NewExpression calls a constructor, new Map() or new Set([1, 2])
+	NewExpression struct {
+		Token token.Token
+		Call  *CallExpression
+	}
+
+	// Member represent the access of a property with the dot operator apple.color
+	Member struct {
+		Token      token.Token
+		Identifier Expression
+		Property   *Identifier
 	}
 
 	// Bracket declarations apple[] = <expression>
@@ -509,6 +529,21 @@ func (ie *Index) String() string {
 	return out.String()
 }
 
+func (m *Member) expressionNode()  {}
+func (m *Member) Start() token.Pos { return m.Identifier.Start() }
+func (m *Member) End() token.Pos   { return m.Property.End() }
+func (m *Member) String() string {
+	var out strings.Builder
+
+	out.WriteString("(")
+	out.WriteString(m.Identifier.String())
+	out.WriteString(".")
+	out.WriteString(m.Property.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
 func (n *Null) expressionNode()  {}
 func (n *Null) Start() token.Pos { return n.Token.Start }
 func (n *Null) End() token.Pos   { return n.Token.End }
@@ -521,8 +556,8 @@ func (n *Dictionary) String() string {
 	var out strings.Builder
 
 	keyVal := []string{}
-	for key, value := range n.Object {
-		keyVal = append(keyVal, key.String()+" : "+value.String())
+	for _, key := range n.Keys {
+		keyVal = append(keyVal, key.String()+" : "+n.Object[key].String())
 	}
 
 	out.WriteString("{")
@@ -532,6 +567,18 @@ func (n *Dictionary) String() string {
 	return out.String()
 }
 
+func (d *DeleteExpression) expressionNode()  {}
+func (d *DeleteExpression) Start() token.Pos { return d.Token.Start }
+func (d *DeleteExpression) End() token.Pos   { return d.Expression.End() }
+func (d *DeleteExpression) String() string {
+	return "(delete " + d.Expression.String() + ")"
+}
+
+func (n *NewExpression) expressionNode()  {}
+func (n *NewExpression) Start() token.Pos { return n.Token.Start }
+func (n *NewExpression) End() token.Pos   { return n.Call.End() }
+func (n *NewExpression) String() string   { return "(new " + n.Call.String() + ")" }
+
 func (n *BracketDeclaration) expressionNode()  {}
 func (n *BracketDeclaration) Start() token.Pos { return n.Token.Start }
 func (n *BracketDeclaration) End() token.Pos   { return n.Token.End }
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jf550-kent/jsgo/compiler"
+	"github.com/jf550-kent/jsgo/parser"
+)
+
+// BYTECODE_EXT is the extension of the files written by jsgo build, jsgo runs them without compiling.
+const BYTECODE_EXT = ".jsgoc"
+
+// build compiles a script to a bytecode file: jsgo build <filename> [-o <output>].
+// The output defaults to the script with the .jsgoc extension.
+func build(args []string) (err error) {
+	flags := flag.NewFlagSet("build", flag.ContinueOnError)
+	output := flags.String("o", "", "the bytecode file to write, the script with the "+BYTECODE_EXT+" extension by default")
+
+	// the flags can come before or after the file name
+	var files []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		if flags.NArg() == 0 {
+			break
+		}
+		files = append(files, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+	if len(files) != 1 {
+		return errors.New("usage ./jsgo build <filename> [-o <output>]")
+	}
+	fileName := files[0]
+	if *output == "" {
+		*output = strings.TrimSuffix(fileName, ".js") + BYTECODE_EXT
+	}
+
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	main := parser.Parse(fileName, content)
+
+	com := compiler.New()
+	if err := com.Compile(main); err != nil {
+		return fmt.Errorf("compiler error: %w", err)
+	}
+	data, err := com.ByteCode().Encode()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(*output, data, 0o644)
+}
+
+// loadBytecode reads a file written by build.
+func loadBytecode(fileName string) (*compiler.Bytecode, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	program, err := compiler.Decode(data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+	return program, nil
+}
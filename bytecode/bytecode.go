@@ -17,9 +17,14 @@ func (ins Instructions) String() string {
 	for i < len(ins) {
 		def, err := Lookup(ins[i])
 		if err != nil {
-			fmt.Fprintf(&res, "ERROR: %s\n", err)
+			fmt.Fprintf(&res, "%04d ERROR: %s\n", i, err)
+			i++
 			continue
 		}
+		if i+1+def.ByteSize > len(ins) {
+			fmt.Fprintf(&res, "%04d ERROR: %s cut short\n", i, def.Name)
+			break
+		}
 
 		operands, read := ReadOperands(def, ins[i+1:])
 		fmt.Fprintf(&res, "%04d %s\n", i, ins.fmtInstruction(def, operands))
@@ -38,16 +43,12 @@ func (ins Instructions) fmtInstruction(def *Definition, operands []int) string {
 			len(operands), operandCount)
 	}
 
-	switch operandCount {
-	case 0:
-		return def.Name
-	case 1:
-		return fmt.Sprintf("%s %d", def.Name, operands[0])
-	case 2:
-		return fmt.Sprintf("%s %d %d", def.Name, operands[0], operands[1])
+	var res strings.Builder
+	res.WriteString(def.Name)
+	for _, operand := range operands {
+		fmt.Fprintf(&res, " %d", operand)
 	}
-
-	return fmt.Sprintf("ERROR: unhandled operandCount for %s\n", def.Name)
+	return res.String()
 }
 
 const (
@@ -85,6 +86,9 @@ const (
 	OpCurrentClosure
 	OpIndexAssign
 	OpFor
+	OpDelete
+	OpStrictEqual
+	OpStrictNotEqual
 )
 
 type Definition struct {
@@ -123,12 +127,15 @@ var definitions = map[Opcode]*Definition{
 	OpReturn:         {"OpReturn", []int{}, 0, 0},
 	OpGetLocal:       {"OpGetLocal", []int{1}, 1, 1},
 	OpSetLocal:       {"OpSetLocal", []int{1}, 1, 1},
-	OpGetBuiltIn:     {"OpGetBuiltIn", []int{1}, 1, 1},
+	OpGetBuiltIn:     {"OpGetBuiltIn", []int{2}, 2, 1},
 	OpClosure:        {"OpClosure", []int{2, 1}, 3, 2},
 	OpGetFree:        {"OpGetFree", []int{1}, 1, 1},
 	OpCurrentClosure: {"OpCurrentClosure", []int{}, 0, 0},
 	OpIndexAssign:    {"OpIndexAssign", []int{}, 0, 0},
 	OpFor:            {"OpFor", []int{1, 1, 1}, 3, 3},
+	OpDelete:         {"OpDelete", []int{}, 0, 0},
+	OpStrictEqual:    {"OpStrictEqual", []int{}, 0, 0},
+	OpStrictNotEqual: {"OpStrictNotEqual", []int{}, 0, 0},
 }
 
 func Lookup(op byte) (*Definition, error) {
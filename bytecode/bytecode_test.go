@@ -1,6 +1,11 @@
 package bytecode
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jf550-kent/jsgo/token"
+)
 
 func TestMake(t *testing.T) {
 	tests := []struct {
@@ -53,6 +58,17 @@ func TestInstructionsString(t *testing.T) {
 	}
 }
 
+func TestInstructionsStringMalformed(t *testing.T) {
+	ins := Instructions{255}
+	ins = append(ins, Make(OpFor, 1, 2, 3)...)
+	ins = append(ins, byte(OpConstant), 0)
+
+	expected := "0000 ERROR: opcode 255 undefined\n0001 OpFor 1 2 3\n0005 ERROR: OpConstant cut short\n"
+	if ins.String() != expected {
+		t.Errorf("instructions wrongly formatted\ngot:\n%s \nexpected:\n%s", ins.String(), expected)
+	}
+}
+
 func TestReadOperands(t *testing.T) {
 	tests := []struct {
 		op        Opcode
@@ -84,3 +100,50 @@ func TestReadOperands(t *testing.T) {
 		}
 	}
 }
+
+func TestLineTable(t *testing.T) {
+	var positions Positions
+	positions = positions.Add(0, token.Pos{Line: 1, Col: 1})
+	positions = positions.Add(3, token.Pos{Line: 1, Col: 1, Offset: 2})
+	positions = positions.Add(4, token.Pos{Line: 2, Col: 5})
+	positions = positions.Add(4, token.Pos{Line: 2, Col: 7})
+	positions = positions.Add(9, token.Pos{Line: 3, Col: 1})
+	positions = positions.Add(300, token.Pos{Line: 1, Col: 200})
+
+	if len(positions) != 4 {
+		t.Fatalf("wrong number of entries. want=4 got=%d", len(positions))
+	}
+	lines := positions.Encode()
+	if small := positions[:3].Encode(); len(small) != 9 {
+		t.Errorf("small steps should take three bytes an entry. got=%d bytes", len(small))
+	}
+	if decoded := lines.Positions(); !reflect.DeepEqual(decoded, positions) {
+		t.Errorf("wrong decoded positions. want=%v got=%v", positions, decoded)
+	}
+
+	tests := []struct {
+		offset   int
+		expected token.Pos
+	}{
+		{0, token.Pos{Line: 1, Col: 1}},
+		{3, token.Pos{Line: 1, Col: 1}},
+		{4, token.Pos{Line: 2, Col: 7}},
+		{8, token.Pos{Line: 2, Col: 7}},
+		{20, token.Pos{Line: 3, Col: 1}},
+		{300, token.Pos{Line: 1, Col: 200}},
+	}
+	for _, tt := range tests {
+		pos, ok := lines.Lookup(tt.offset)
+		if !ok || pos != tt.expected {
+			t.Errorf("wrong position at %d. want=%+v got=%+v", tt.offset, tt.expected, pos)
+		}
+	}
+
+	lines = positions.Truncate(9).Encode()
+	if pos, _ := lines.Lookup(20); pos != (token.Pos{Line: 2, Col: 7}) {
+		t.Errorf("truncate should drop the entries from the offset. got=%+v", pos)
+	}
+	if _, ok := LineTable(nil).Lookup(0); ok {
+		t.Errorf("an empty table should not cover any offset")
+	}
+}
@@ -0,0 +1,117 @@
+package bytecode
+
+import (
+	"encoding/binary"
+	"sort"
+
+	"github.com/jf550-kent/jsgo/token"
+)
+
+// Position maps the instructions from Offset up to the next Position to the line and
+// column they were compiled from.
+type Position struct {
+	Offset int
+	Line   int
+	Col    int
+}
+
+// Positions are the positions recorded while instructions are emitted, sorted by offset.
+// Encode turns them into the LineTable kept with the instructions.
+type Positions []Position
+
+// Add records that the instructions from offset were compiled from pos, nothing is added
+// when they continue the line and column of the previous entry.
+func (ps Positions) Add(offset int, pos token.Pos) Positions {
+	p := Position{Offset: offset, Line: pos.Line, Col: pos.Col}
+	if n := len(ps); n > 0 {
+		if ps[n-1].Line == p.Line && ps[n-1].Col == p.Col {
+			return ps
+		}
+		if ps[n-1].Offset == offset {
+			ps[n-1] = p
+			return ps
+		}
+	}
+	return append(ps, p)
+}
+
+// Truncate drops the entries of the instructions from offset on.
+func (ps Positions) Truncate(offset int) Positions {
+	i := sort.Search(len(ps), func(i int) bool { return ps[i].Offset >= offset })
+	return ps[:i]
+}
+
+// Encode returns the positions as a LineTable.
+func (ps Positions) Encode() LineTable {
+	if len(ps) == 0 {
+		return nil
+	}
+	lt := make(LineTable, 0, 3*len(ps))
+	var prev Position
+	for _, p := range ps {
+		lt = binary.AppendUvarint(lt, uint64(p.Offset-prev.Offset))
+		lt = binary.AppendVarint(lt, int64(p.Line-prev.Line))
+		lt = binary.AppendVarint(lt, int64(p.Col-prev.Col))
+		prev = p
+	}
+	return lt
+}
+
+// LineTable maps the offsets of instructions to the line and column they were compiled from.
+// Every entry is the difference to the previous one as varints: the offset, the line and
+// the column, most entries take three bytes.
+type LineTable []byte
+
+// Lookup returns the position of the instruction at offset, false when the table does not
+// cover it. The table is decoded from the start, it is meant for errors and tools.
+func (lt LineTable) Lookup(offset int) (token.Pos, bool) {
+	var pos token.Pos
+	found := false
+	lt.each(func(p Position) bool {
+		if p.Offset > offset {
+			return false
+		}
+		pos, found = token.Pos{Line: p.Line, Col: p.Col}, true
+		return true
+	})
+	return pos, found
+}
+
+// Positions decodes the table.
+func (lt LineTable) Positions() Positions {
+	var ps Positions
+	lt.each(func(p Position) bool {
+		ps = append(ps, p)
+		return true
+	})
+	return ps
+}
+
+// each decodes the entries in order until fn returns false, a malformed table ends at its first bad entry.
+func (lt LineTable) each(fn func(Position) bool) {
+	var p Position
+	for i := 0; i < len(lt); {
+		offset, n := binary.Uvarint(lt[i:])
+		if n <= 0 {
+			return
+		}
+		i += n
+		line, n := binary.Varint(lt[i:])
+		if n <= 0 {
+			return
+		}
+		i += n
+		col, n := binary.Varint(lt[i:])
+		if n <= 0 {
+			return
+		}
+		i += n
+
+		p.Offset += int(offset)
+		p.Line += int(line)
+		p.Col += int(col)
+		if !fn(p) {
+			return
+		}
+	}
+}
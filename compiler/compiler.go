@@ -2,11 +2,12 @@ package compiler
 
 import (
 	"fmt"
-	"sort"
+	"math"
 
 	"github.com/jf550-kent/jsgo/ast"
 	"github.com/jf550-kent/jsgo/bytecode"
 	"github.com/jf550-kent/jsgo/object"
+	"github.com/jf550-kent/jsgo/token"
 )
 
 const (
@@ -20,44 +21,91 @@ type EmittedInstruction struct {
 
 type CompilationScope struct {
 	instructions        bytecode.Instructions
+	positions           bytecode.Positions
+	callees             map[int]string // the variable called by the OpCall at each offset
 	lastInstruction     EmittedInstruction
 	previousInstruction EmittedInstruction
 }
 
 type Bytecode struct {
 	Instructions bytecode.Instructions
+	Lines        bytecode.LineTable
+	Callees      map[int]string // the variable called by the OpCall at each offset
 	Constants    []object.Object
+	Builtins     *object.Registry
+	NumGlobals   int      // the number of globals defined by the program and the ones it continues from
+	Globals      []string // the names of the globals by index
+	File         string
 }
 
 type Compiler struct {
-	constants   []object.Object
-	symbolTable *SymbolTable
+	constants     []object.Object
+	constantIndex map[constantKey]int // the index of every number, float and string constant
+	symbolTable   *SymbolTable
+	builtins      *object.Registry
 
 	scopesStack []CompilationScope
 	scopeIndex  int
+
+	file     string
+	position token.Pos // the position of the node being compiled
 }
 
 // Instructions Example: [OpPop, OpConstant, 0, 3] posNewInstruction = 1
 
 func New() *Compiler {
+	return NewWithBuiltins(object.Builtins)
+}
+
+// NewWithBuiltins returns a compiler resolving globals that are not variables through builtins,
+// names registered after this call are not seen by the compiler.
+func NewWithBuiltins(builtins *object.Registry) *Compiler {
 	globalScope := CompilationScope{
 		instructions:        bytecode.Instructions{},
 		lastInstruction:     EmittedInstruction{},
 		previousInstruction: EmittedInstruction{},
 	}
 	symbolTable := NewSymbolTable()
-	for i, v := range object.Builtins {
-		symbolTable.DefineBuiltIn(i, v.Name)
+	for i, entry := range builtins.Entries() {
+		symbolTable.DefineBuiltIn(i, entry.Name)
 	}
 	return &Compiler{
-		constants:   []object.Object{},
-		symbolTable: symbolTable,
-		scopesStack: []CompilationScope{globalScope},
-		scopeIndex:  0,
+		constants:     []object.Object{},
+		constantIndex: map[constantKey]int{},
+		symbolTable:   symbolTable,
+		builtins:      builtins,
+		scopesStack:   []CompilationScope{globalScope},
+		scopeIndex:    0,
+	}
+}
+
+// NewWithState returns a compiler continuing from the globals and constants of an earlier program,
+// so functions of either program can run on a VM built from the bytecode of the other.
+// symbolTable must come from a compiler using the same builtins.
+func NewWithState(symbolTable *SymbolTable, constants []object.Object, builtins *object.Registry) *Compiler {
+	c := NewWithBuiltins(builtins)
+	c.symbolTable = symbolTable
+	c.constants = constants
+	for i, constant := range constants {
+		if key, ok := keyOf(constant); ok {
+			if _, ok := c.constantIndex[key]; !ok {
+				c.constantIndex[key] = i
+			}
+		}
 	}
+	return c
+}
+
+// SymbolTable returns the global symbol table of the compiler.
+func (c *Compiler) SymbolTable() *SymbolTable {
+	return c.symbolTable
 }
 
 func (c *Compiler) Compile(node ast.Node) error {
+	if _, ok := node.(*ast.Main); !ok {
+		defer c.setPosition(c.setPosition(node.Start()))
+	}
+
 	switch node := node.(type) {
 	case *ast.Main:
 		return c.compileMain(node)
@@ -102,13 +150,26 @@ func (c *Compiler) Compile(node ast.Node) error {
 
 		freeSym := c.symbolTable.FreeSymbols
 		numLocals := c.symbolTable.numberDefinitions
+		locals := c.symbolTable.names()
+		lines := c.scopesStack[c.scopeIndex].positions.Encode()
+		callees := c.scopesStack[c.scopeIndex].callees
 		instructions := c.leaveScope()
 
 		for _, s := range freeSym {
 			c.loadSymbol(s)
 		}
 
-		compiledFunc := &object.BytecodeFunction{Instructions: instructions, NumLocals: numLocals}
+		compiledFunc := &object.BytecodeFunction{
+			Instructions: instructions,
+			NumLocals:    numLocals,
+			DebugInfo: object.DebugInfo{
+				Name:    node.Name,
+				Pos:     node.Token.Start,
+				Lines:   lines,
+				Locals:  locals,
+				Callees: callees,
+			},
+		}
 		c.emit(bytecode.OpClosure, c.addConstant(compiledFunc), len(freeSym))
 
 	case *ast.CallExpression:
@@ -120,7 +181,10 @@ func (c *Compiler) Compile(node ast.Node) error {
 				return err
 			}
 		}
-		c.emit(bytecode.OpCall, len(node.Arguments))
+		pos := c.emit(bytecode.OpCall, len(node.Arguments))
+		if ident, ok := node.Function.(*ast.Identifier); ok {
+			c.addCallee(pos, ident.Literal)
+		}
 
 	case *ast.BinaryExpression:
 		if node.Operator == "<" {
@@ -151,6 +215,10 @@ func (c *Compiler) Compile(node ast.Node) error {
 			c.emit(bytecode.OpEqual)
 		case "!=":
 			c.emit(bytecode.OpNotEqual)
+		case "===":
+			c.emit(bytecode.OpStrictEqual)
+		case "!==":
+			c.emit(bytecode.OpStrictNotEqual)
 		default:
 			return fmt.Errorf("unknown operator %s", node.Operator)
 		}
@@ -245,14 +313,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 		c.emit(bytecode.OpIndexAssign)
 
 	case *ast.Dictionary:
-		keys := []ast.Expression{}
-		for k := range node.Object {
-			keys = append(keys, k)
-		}
-		sort.Slice(keys, func(i, j int) bool {
-			return keys[i].String() < keys[j].String()
-		})
-
+		keys := node.Keys
 		for _, k := range keys {
 			if err := c.Compile(k); err != nil {
 				return err
@@ -273,7 +334,42 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 		c.emit(bytecode.OpIndex)
 
+	case *ast.Member:
+		if err := c.Compile(node.Identifier); err != nil {
+			return err
+		}
+		property := &object.String{Value: node.Property.Literal}
+		c.emit(bytecode.OpConstant, c.addConstant(property))
+		c.emit(bytecode.OpIndex)
+
+	case *ast.DeleteExpression:
+		switch target := node.Expression.(type) {
+		case *ast.Index:
+			if err := c.Compile(target.Identifier); err != nil {
+				return err
+			}
+			if err := c.Compile(target.Index); err != nil {
+				return err
+			}
+		case *ast.Member:
+			if err := c.Compile(target.Identifier); err != nil {
+				return err
+			}
+			property := &object.String{Value: target.Property.Literal}
+			c.emit(bytecode.OpConstant, c.addConstant(property))
+		default:
+			return fmt.Errorf("delete of unsupported expression %s", node.Expression)
+		}
+		c.emit(bytecode.OpDelete)
+
+	case *ast.NewExpression:
+		return c.Compile(node.Call)
+
 	case *ast.ReturnStatement:
+		if node.ReturnExpression == nil {
+			c.emit(bytecode.OpReturn)
+			break
+		}
 		if err := c.Compile(node.ReturnExpression); err != nil {
 			return err
 		}
@@ -330,11 +426,42 @@ func (c *Compiler) Compile(node ast.Node) error {
 	return nil
 }
 
+// addConstant returns the index of obj in the constant pool. A number, float or string equal to
+// one already in the pool is not added again, every use of the literal shares the one object.
 func (c *Compiler) addConstant(obj object.Object) int {
+	key, ok := keyOf(obj)
+	if ok {
+		if i, ok := c.constantIndex[key]; ok {
+			return i
+		}
+	}
 	c.constants = append(c.constants, obj)
+	if ok {
+		c.constantIndex[key] = len(c.constants) - 1
+	}
 	return len(c.constants) - 1
 }
 
+// constantKey identifies a constant by its type and value, a float by its bits so -0 and 0
+// stay two constants.
+type constantKey struct {
+	kind object.ObjectType
+	bits uint64
+	str  string
+}
+
+func keyOf(obj object.Object) (constantKey, bool) {
+	switch obj := obj.(type) {
+	case *object.Number:
+		return constantKey{kind: object.NUMBER_OBJECT, bits: uint64(obj.Value)}, true
+	case *object.Float:
+		return constantKey{kind: object.FLOAT_OBJECT, bits: math.Float64bits(obj.Value)}, true
+	case *object.String:
+		return constantKey{kind: object.STRING_OBJECT, str: obj.Value}, true
+	}
+	return constantKey{}, false
+}
+
 func (c *Compiler) emit(op bytecode.Opcode, operands ...int) int {
 	instrct := bytecode.Make(op, operands...)
 	pos := c.addInstruction(instrct)
@@ -351,13 +478,33 @@ func (c *Compiler) setLastInstruction(op bytecode.Opcode, pos int) {
 	c.scopesStack[c.scopeIndex].lastInstruction = last
 }
 
+// addCallee records that the OpCall at pos calls the variable name, it names the callee
+// in the error of calling something that is not a function.
+func (c *Compiler) addCallee(pos int, name string) {
+	scope := &c.scopesStack[c.scopeIndex]
+	if scope.callees == nil {
+		scope.callees = map[int]string{}
+	}
+	scope.callees[pos] = name
+}
+
 func (c *Compiler) addInstruction(ins []byte) int {
 	posNewInstruction := len(c.currentInstructions())
-	c.scopesStack[c.scopeIndex].instructions = append(c.currentInstructions(), ins...)
+	scope := &c.scopesStack[c.scopeIndex]
+	scope.instructions = append(scope.instructions, ins...)
+	scope.positions = scope.positions.Add(posNewInstruction, c.position)
 	return posNewInstruction
 }
 
+// setPosition sets the position recorded for the instructions emitted next and returns the previous one.
+func (c *Compiler) setPosition(pos token.Pos) token.Pos {
+	previous := c.position
+	c.position = pos
+	return previous
+}
+
 func (c *Compiler) compileMain(node *ast.Main) error {
+	c.file = node.Name
 	for _, stmt := range node.Statements {
 		err := c.Compile(stmt)
 		if err != nil {
@@ -399,7 +546,13 @@ func (c *Compiler) swapInstruction(pos int, newInstruction []byte) {
 func (c *Compiler) ByteCode() *Bytecode {
 	return &Bytecode{
 		Instructions: c.currentInstructions(),
+		Lines:        c.scopesStack[c.scopeIndex].positions.Encode(),
+		Callees:      c.scopesStack[c.scopeIndex].callees,
 		Constants:    c.constants,
+		Builtins:     c.builtins,
+		NumGlobals:   c.symbolTable.numberDefinitions,
+		Globals:      c.symbolTable.names(),
+		File:         c.file,
 	}
 }
 
@@ -423,6 +576,7 @@ func (c *Compiler) removeLastPop() {
 	new := old[:last.Position]
 
 	c.scopesStack[c.scopeIndex].instructions = new
+	c.scopesStack[c.scopeIndex].positions = c.scopesStack[c.scopeIndex].positions.Truncate(last.Position)
 	c.scopesStack[c.scopeIndex].lastInstruction = previous
 }
 
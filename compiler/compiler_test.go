@@ -3,11 +3,13 @@
 package compiler
 
 import (
+	"math"
 	"testing"
 
 	"github.com/jf550-kent/jsgo/bytecode"
 	"github.com/jf550-kent/jsgo/object"
 	"github.com/jf550-kent/jsgo/parser"
+	"github.com/jf550-kent/jsgo/token"
 )
 
 type compilerTestCase struct {
@@ -142,6 +144,26 @@ func TestBooleanExpression(t *testing.T) {
 				bytecode.Make(bytecode.OpPop),
 			},
 		},
+		{
+			input:             "1 === 2",
+			expectedConstants: []any{1, 2},
+			expectedInstructions: []bytecode.Instructions{
+				bytecode.Make(bytecode.OpConstant, 0),
+				bytecode.Make(bytecode.OpConstant, 1),
+				bytecode.Make(bytecode.OpStrictEqual),
+				bytecode.Make(bytecode.OpPop),
+			},
+		},
+		{
+			input:             "1 !== 2",
+			expectedConstants: []any{1, 2},
+			expectedInstructions: []bytecode.Instructions{
+				bytecode.Make(bytecode.OpConstant, 0),
+				bytecode.Make(bytecode.OpConstant, 1),
+				bytecode.Make(bytecode.OpStrictNotEqual),
+				bytecode.Make(bytecode.OpPop),
+			},
+		},
 		{
 			input:             "true == false",
 			expectedConstants: []any{},
@@ -478,6 +500,30 @@ func TestDictionary(t *testing.T) {
 				bytecode.Make(bytecode.OpPop),
 			},
 		},
+		{
+			input:             "{5: 6, 1: 2}",
+			expectedConstants: []any{5, 6, 1, 2},
+			expectedInstructions: []bytecode.Instructions{
+				bytecode.Make(bytecode.OpConstant, 0),
+				bytecode.Make(bytecode.OpConstant, 1),
+				bytecode.Make(bytecode.OpConstant, 2),
+				bytecode.Make(bytecode.OpConstant, 3),
+				bytecode.Make(bytecode.OpDic, 4),
+				bytecode.Make(bytecode.OpPop),
+			},
+		},
+		{
+			input:             `delete {1: 2}[1]`,
+			expectedConstants: []any{1, 2},
+			expectedInstructions: []bytecode.Instructions{
+				bytecode.Make(bytecode.OpConstant, 0),
+				bytecode.Make(bytecode.OpConstant, 1),
+				bytecode.Make(bytecode.OpDic, 2),
+				bytecode.Make(bytecode.OpConstant, 0),
+				bytecode.Make(bytecode.OpDelete),
+				bytecode.Make(bytecode.OpPop),
+			},
+		},
 	}
 
 	testCompilerTests(t, tests)
@@ -487,14 +533,14 @@ func TestIndexing(t *testing.T) {
 	tests := []compilerTestCase{
 		{
 			input:             "[1, 2, 3][1 + 1]",
-			expectedConstants: []any{1, 2, 3, 1, 1},
+			expectedConstants: []any{1, 2, 3},
 			expectedInstructions: []bytecode.Instructions{
 				bytecode.Make(bytecode.OpConstant, 0),
 				bytecode.Make(bytecode.OpConstant, 1),
 				bytecode.Make(bytecode.OpConstant, 2),
 				bytecode.Make(bytecode.OpArray, 3),
-				bytecode.Make(bytecode.OpConstant, 3),
-				bytecode.Make(bytecode.OpConstant, 4),
+				bytecode.Make(bytecode.OpConstant, 0),
+				bytecode.Make(bytecode.OpConstant, 0),
 				bytecode.Make(bytecode.OpAdd),
 				bytecode.Make(bytecode.OpIndex),
 				bytecode.Make(bytecode.OpPop),
@@ -502,13 +548,13 @@ func TestIndexing(t *testing.T) {
 		},
 		{
 			input:             "{1: 2}[2 - 1]",
-			expectedConstants: []any{1, 2, 2, 1},
+			expectedConstants: []any{1, 2},
 			expectedInstructions: []bytecode.Instructions{
 				bytecode.Make(bytecode.OpConstant, 0),
 				bytecode.Make(bytecode.OpConstant, 1),
 				bytecode.Make(bytecode.OpDic, 2),
-				bytecode.Make(bytecode.OpConstant, 2),
-				bytecode.Make(bytecode.OpConstant, 3),
+				bytecode.Make(bytecode.OpConstant, 1),
+				bytecode.Make(bytecode.OpConstant, 0),
 				bytecode.Make(bytecode.OpSub),
 				bytecode.Make(bytecode.OpIndex),
 				bytecode.Make(bytecode.OpPop),
@@ -709,7 +755,9 @@ func TestClosures(t *testing.T) {
 			},
 		},
 		{
-			input: `var sum = 10; function() { var car = 9; function() { var mon = 11 function() { var in = 8 sum + car + mon + in; } } }`,
+			input: `var sum = 10; function() { var car = 9; function() { var mon = 11
+			function() { var in = 8
+			sum + car + mon + in; } } }`,
 			expectedConstants: []any{
 				10,
 				9,
@@ -773,13 +821,12 @@ func TestRecursiveFunctions(t *testing.T) {
 					bytecode.Make(bytecode.OpCall, 1),
 					bytecode.Make(bytecode.OpReturnValue),
 				},
-				1,
 			},
 			expectedInstructions: []bytecode.Instructions{
 				bytecode.Make(bytecode.OpClosure, 1, 0),
 				bytecode.Make(bytecode.OpSetGlobal, 0),
 				bytecode.Make(bytecode.OpGetGlobal, 0),
-				bytecode.Make(bytecode.OpConstant, 2),
+				bytecode.Make(bytecode.OpConstant, 0),
 				bytecode.Make(bytecode.OpCall, 1),
 				bytecode.Make(bytecode.OpPop),
 			},
@@ -796,18 +843,17 @@ func TestRecursiveFunctions(t *testing.T) {
 					bytecode.Make(bytecode.OpCall, 1),
 					bytecode.Make(bytecode.OpReturnValue),
 				},
-				1,
 				[]bytecode.Instructions{
 					bytecode.Make(bytecode.OpClosure, 1, 0),
 					bytecode.Make(bytecode.OpSetLocal, 0),
 					bytecode.Make(bytecode.OpGetLocal, 0),
-					bytecode.Make(bytecode.OpConstant, 2),
+					bytecode.Make(bytecode.OpConstant, 0),
 					bytecode.Make(bytecode.OpCall, 1),
 					bytecode.Make(bytecode.OpReturnValue),
 				},
 			},
 			expectedInstructions: []bytecode.Instructions{
-				bytecode.Make(bytecode.OpClosure, 3, 0),
+				bytecode.Make(bytecode.OpClosure, 2, 0),
 				bytecode.Make(bytecode.OpSetGlobal, 0),
 				bytecode.Make(bytecode.OpGetGlobal, 0),
 				bytecode.Make(bytecode.OpCall, 0),
@@ -878,11 +924,14 @@ func TestBuiltIn(t *testing.T) {
 		{
 			input: "console.log(89);",
 			expectedConstants: []any{
+				"log",
 				89,
 			},
 			expectedInstructions: []bytecode.Instructions{
 				bytecode.Make(bytecode.OpGetBuiltIn, 0),
 				bytecode.Make(bytecode.OpConstant, 0),
+				bytecode.Make(bytecode.OpIndex),
+				bytecode.Make(bytecode.OpConstant, 1),
 				bytecode.Make(bytecode.OpCall, 1),
 				bytecode.Make(bytecode.OpPop),
 			},
@@ -1007,3 +1056,113 @@ func mergeInstructions(s []bytecode.Instructions) bytecode.Instructions {
 
 	return result
 }
+
+func TestDebugInfo(t *testing.T) {
+	input := "var a = 1;\nvar f = function(x) {\n  return x + a;\n};"
+	compiler := New()
+	if err := compiler.Compile(parser.Parse("main.js", []byte(input))); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	bytecodes := compiler.ByteCode()
+	if bytecodes.File != "main.js" {
+		t.Errorf("wrong file. got=%q", bytecodes.File)
+	}
+	if len(bytecodes.Globals) != 2 || bytecodes.Globals[0] != "a" || bytecodes.Globals[1] != "f" {
+		t.Errorf("wrong global names. got=%v", bytecodes.Globals)
+	}
+
+	function := bytecodes.Constants[1].(*object.BytecodeFunction)
+	if function.Name != "f" || function.Pos != (token.Pos{Line: 2, Col: 9, Offset: 19}) {
+		t.Errorf("wrong function name or position. got=%q %+v", function.Name, function.Pos)
+	}
+	if len(function.Locals) != 1 || function.Locals[0] != "x" {
+		t.Errorf("wrong local names. got=%v", function.Locals)
+	}
+
+	tests := []struct {
+		lines    bytecode.LineTable
+		offset   int
+		expected token.Pos
+	}{
+		{bytecodes.Lines, 0, token.Pos{Line: 1, Col: 9}},  // OpConstant 1
+		{bytecodes.Lines, 3, token.Pos{Line: 1, Col: 1}},  // OpSetGlobal a
+		{bytecodes.Lines, 6, token.Pos{Line: 2, Col: 9}},  // OpClosure
+		{bytecodes.Lines, 10, token.Pos{Line: 2, Col: 1}}, // OpSetGlobal f
+		{function.Lines, 0, token.Pos{Line: 3, Col: 10}},  // OpGetLocal x
+		{function.Lines, 2, token.Pos{Line: 3, Col: 14}},  // OpGetGlobal a
+		{function.Lines, 6, token.Pos{Line: 3, Col: 3}},   // OpReturnValue
+	}
+
+	for _, tt := range tests {
+		pos, ok := tt.lines.Lookup(tt.offset)
+		if !ok || pos != tt.expected {
+			t.Errorf("wrong position at %d. want=%+v got=%+v", tt.offset, tt.expected, pos)
+		}
+	}
+}
+
+func TestLocalNames(t *testing.T) {
+	input := "var g = function(a, b) { var c = function() { return a; }; var d = c(); return d; };"
+	compiler := New()
+	if err := compiler.Compile(parser.Parse("", []byte(input))); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	tests := []struct {
+		constant int
+		name     string
+		locals   []string
+	}{
+		{0, "c", []string{}},
+		{1, "g", []string{"a", "b", "c", "d"}},
+	}
+	for _, tt := range tests {
+		function := compiler.ByteCode().Constants[tt.constant].(*object.BytecodeFunction)
+		if function.Name != tt.name {
+			t.Errorf("wrong function name. want=%q got=%q", tt.name, function.Name)
+		}
+		if len(function.Locals) != len(tt.locals) || function.NumLocals != len(tt.locals) {
+			t.Fatalf("wrong number of locals. want=%v got=%v", tt.locals, function.Locals)
+		}
+		for i, name := range tt.locals {
+			if function.Locals[i] != name {
+				t.Errorf("wrong local %d. want=%q got=%q", i, name, function.Locals[i])
+			}
+		}
+	}
+}
+
+func TestConstantDeduplication(t *testing.T) {
+	input := `
+	var total = 0;
+	for (var i = 0; i < 100; i = i + 1) { total = total + 1 + 1.0 + 1.0; }
+	var d = {"name": 1};
+	d.name + d["name"] + "name";
+	`
+	compiler := New()
+	if err := compiler.Compile(parser.Parse("", []byte(input))); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	constants := compiler.ByteCode().Constants
+	// 0, 100, 1, 1.0 and "name"
+	if len(constants) != 5 {
+		t.Fatalf("wrong number of constants. want=5 got=%d %v", len(constants), constants)
+	}
+
+	// floats are kept by their bits and apart from numbers of the same value
+	zero := compiler.addConstant(&object.Float{Value: 0})
+	negativeZero := compiler.addConstant(&object.Float{Value: math.Copysign(0, -1)})
+	if zero == negativeZero || negativeZero != compiler.addConstant(&object.Float{Value: math.Copysign(0, -1)}) {
+		t.Errorf("-0 should be one constant apart from 0. got=%d and %d", zero, negativeZero)
+	}
+	if compiler.addConstant(&object.Number{Value: 0}) == zero {
+		t.Errorf("the number 0 should not share the constant of the float 0")
+	}
+
+	// a compiler continuing from the constants of an earlier program reuses them
+	next := NewWithState(compiler.SymbolTable(), compiler.ByteCode().Constants, object.Builtins)
+	name := next.addConstant(&object.String{Value: "name"})
+	if name != 4 {
+		t.Errorf("wrong index of an earlier constant. want=4 got=%d", name)
+	}
+}
@@ -0,0 +1,196 @@
+package compiler
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jf550-kent/jsgo/bytecode"
+	"github.com/jf550-kent/jsgo/object"
+)
+
+// Disassemble returns the instructions of the main program followed by the ones of every
+// function in the constant pool. Jump targets are labelled, constants, variables and builtins
+// are shown next to the instructions using them and, when source is given, every line of
+// source is printed before the instructions compiled from it.
+func (b *Bytecode) Disassemble(source []byte) string {
+	d := disassembler{program: b}
+	if source != nil {
+		d.source = strings.Split(string(source), "\n")
+	}
+	builtins := b.Builtins
+	if builtins == nil {
+		builtins = object.Builtins
+	}
+	for _, entry := range builtins.Entries() {
+		d.builtins = append(d.builtins, entry.Name)
+	}
+
+	header := "<main>"
+	if b.File != "" {
+		header += " " + b.File
+	}
+	fmt.Fprintf(&d.res, "== %s\n  %d globals: %s\n", header, b.NumGlobals, strings.Join(b.Globals, ", "))
+	d.function(b.Instructions, b.Lines, nil)
+
+	for i, constant := range b.Constants {
+		fn, ok := constant.(*object.BytecodeFunction)
+		if !ok {
+			continue
+		}
+		d.res.WriteString("\n")
+		header := fmt.Sprintf("%s constant %d", functionName(fn), i)
+		if fn.Pos.Line > 0 {
+			header += fmt.Sprintf(" at %d:%d", fn.Pos.Line, fn.Pos.Col)
+		}
+		fmt.Fprintf(&d.res, "== %s\n  %d locals: %s\n", header, fn.NumLocals, strings.Join(fn.Locals, ", "))
+		d.function(fn.Instructions, fn.Lines, fn.Locals)
+	}
+	return d.res.String()
+}
+
+type disassembler struct {
+	program  *Bytecode
+	source   []string
+	builtins []string
+	res      strings.Builder
+}
+
+// function writes the instructions of one function, locals are the names of its local variables.
+func (d *disassembler) function(ins bytecode.Instructions, lines bytecode.LineTable, locals []string) {
+	labels := jumpLabels(ins)
+	positions := lines.Positions()
+	line := 0
+	for i := 0; i < len(ins); {
+		for len(positions) > 0 && positions[0].Offset <= i {
+			if positions[0].Line != line {
+				line = positions[0].Line
+				d.sourceLine(line)
+			}
+			positions = positions[1:]
+		}
+		if label, ok := labels[i]; ok {
+			fmt.Fprintf(&d.res, "%s:\n", label)
+		}
+
+		def, err := bytecode.Lookup(ins[i])
+		if err != nil {
+			fmt.Fprintf(&d.res, "  %04d  ERROR: %s\n", i, err)
+			i++
+			continue
+		}
+		if i+1+def.ByteSize > len(ins) {
+			fmt.Fprintf(&d.res, "  %04d  ERROR: %s cut short\n", i, def.Name)
+			return
+		}
+		operands, read := bytecode.ReadOperands(def, ins[i+1:])
+
+		instruction := def.Name
+		for j, operand := range operands {
+			if j == 0 && isJump(bytecode.Opcode(ins[i])) {
+				instruction += " " + labels[operand]
+				continue
+			}
+			instruction += " " + strconv.Itoa(operand)
+		}
+		if comment := d.comment(bytecode.Opcode(ins[i]), operands, locals); comment != "" {
+			fmt.Fprintf(&d.res, "  %04d  %-24s ; %s\n", i, instruction, comment)
+		} else {
+			fmt.Fprintf(&d.res, "  %04d  %s\n", i, instruction)
+		}
+		i += 1 + read
+	}
+	// a jump past the last instruction
+	if label, ok := labels[len(ins)]; ok {
+		fmt.Fprintf(&d.res, "%s:\n", label)
+	}
+}
+
+func (d *disassembler) sourceLine(line int) {
+	if line < 1 || line > len(d.source) {
+		return
+	}
+	fmt.Fprintf(&d.res, "%6d | %s\n", line, strings.TrimSpace(d.source[line-1]))
+}
+
+// comment names what the operands of an instruction refer to, empty when there is nothing to name.
+func (d *disassembler) comment(op bytecode.Opcode, operands []int, locals []string) string {
+	switch op {
+	case bytecode.OpConstant, bytecode.OpClosure:
+		if operands[0] < len(d.program.Constants) {
+			return constantString(d.program.Constants[operands[0]])
+		}
+	case bytecode.OpGetGlobal, bytecode.OpSetGlobal:
+		if operands[0] < len(d.program.Globals) {
+			return d.program.Globals[operands[0]]
+		}
+	case bytecode.OpGetLocal, bytecode.OpSetLocal:
+		if operands[0] < len(locals) {
+			return locals[operands[0]]
+		}
+	case bytecode.OpGetBuiltIn:
+		if operands[0] < len(d.builtins) {
+			return d.builtins[operands[0]]
+		}
+	}
+	return ""
+}
+
+func isJump(op bytecode.Opcode) bool {
+	return op == bytecode.OpJump || op == bytecode.OpJumpNotTrue
+}
+
+// jumpLabels names the targets of the jumps in ins L0, L1, ... in the order of their offsets.
+func jumpLabels(ins bytecode.Instructions) map[int]string {
+	var targets []int
+	seen := map[int]bool{}
+	for i := 0; i < len(ins); {
+		def, err := bytecode.Lookup(ins[i])
+		if err != nil {
+			i++
+			continue
+		}
+		if i+1+def.ByteSize > len(ins) {
+			break
+		}
+		operands, read := bytecode.ReadOperands(def, ins[i+1:])
+		if isJump(bytecode.Opcode(ins[i])) && !seen[operands[0]] {
+			seen[operands[0]] = true
+			targets = append(targets, operands[0])
+		}
+		i += 1 + read
+	}
+
+	sort.Ints(targets)
+	labels := make(map[int]string, len(targets))
+	for i, target := range targets {
+		labels[target] = fmt.Sprintf("L%d", i)
+	}
+	return labels
+}
+
+func constantString(obj object.Object) string {
+	switch obj := obj.(type) {
+	case *object.String:
+		return strconv.Quote(obj.Value)
+	case *object.Float:
+		// keep the float apart from a number of the same value
+		s := strconv.FormatFloat(obj.Value, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".eIN") {
+			s += ".0"
+		}
+		return s
+	case *object.BytecodeFunction:
+		return "function " + functionName(obj)
+	default:
+		return obj.String()
+	}
+}
+
+func functionName(fn *object.BytecodeFunction) string {
+	if fn.Name == "" {
+		return "<anonymous>"
+	}
+	return fn.Name
+}
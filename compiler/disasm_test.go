@@ -0,0 +1,74 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jf550-kent/jsgo/object"
+)
+
+func TestDisassemble(t *testing.T) {
+	input := `var max = function(a, b) {
+  if (a > b) { return a; }
+  return b;
+};
+console.log(max(1, 2.0), "x");`
+
+	expected := `== <main> main.js
+  1 globals: max
+     1 | var max = function(a, b) {
+  0000  OpClosure 0 0            ; function max
+  0004  OpSetGlobal 0            ; max
+     5 | console.log(max(1, 2.0), "x");
+  0007  OpGetBuiltIn 0           ; console
+  0010  OpConstant 1             ; "log"
+  0013  OpIndex
+  0014  OpGetGlobal 0            ; max
+  0017  OpConstant 2             ; 1
+  0020  OpConstant 3             ; 2.0
+  0023  OpCall 2
+  0025  OpConstant 4             ; "x"
+  0028  OpCall 2
+  0030  OpPop
+
+== max constant 0 at 1:11
+  2 locals: a, b
+     2 | if (a > b) { return a; }
+  0000  OpGetLocal 0             ; a
+  0002  OpGetLocal 1             ; b
+  0004  OpGreaterThan
+  0005  OpJumpNotTrue L0
+  0008  OpGetLocal 0             ; a
+  0010  OpReturnValue
+  0011  OpJump L1
+L0:
+  0014  OpNull
+L1:
+  0015  OpPop
+     3 | return b;
+  0016  OpGetLocal 1             ; b
+  0018  OpReturnValue
+`
+
+	program := compileProgram(t, input)
+	if got := program.Disassemble([]byte(input)); got != expected {
+		t.Errorf("wrong disassembly.\nwant=\n%s\ngot=\n%s", expected, got)
+	}
+
+	// without source only the instructions are printed
+	if got := program.Disassemble(nil); strings.Contains(got, " | ") {
+		t.Errorf("source printed without source.\n%s", got)
+	}
+}
+
+func TestDisassembleMalformed(t *testing.T) {
+	program := &Bytecode{
+		Instructions: []byte{255, 0},
+		Constants:    []object.Object{&object.Number{Value: 1}},
+		Builtins:     object.NewRegistry(),
+	}
+	expected := "== <main>\n  0 globals: \n  0000  ERROR: opcode 255 undefined\n  0001  ERROR: OpConstant cut short\n"
+	if got := program.Disassemble(nil); got != expected {
+		t.Errorf("wrong disassembly.\nwant=%q\ngot=%q", expected, got)
+	}
+}
@@ -0,0 +1,577 @@
+package compiler
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/jf550-kent/jsgo/bytecode"
+	"github.com/jf550-kent/jsgo/object"
+	"github.com/jf550-kent/jsgo/token"
+)
+
+// FormatVersion is the version of the encoding written by [Bytecode.Encode]. It changes with the
+// layout of the file and with the opcodes, a file of another version is not decoded.
+const FormatVersion = 2
+
+// magic starts every encoded program.
+const magic = "\x00JSGOC"
+
+var (
+	ErrFormat  = errors.New("not a valid jsgo bytecode file")
+	ErrVersion = errors.New("incompatible jsgo bytecode version")
+)
+
+// The tags of the constants in the constant pool.
+const (
+	tagNumber byte = iota + 1
+	tagFloat
+	tagString
+	tagBoolean
+	tagNull
+	tagFunction
+)
+
+// Encode returns the program in the jsgo bytecode format:
+//
+//	magic "\x00JSGOC", version as a uvarint
+//	file, the names of the globals and of the builtins by index
+//	constants, each a tag followed by its value
+//	instructions, line table and callees of the main program
+//
+// Integers are varints and strings or byte slices are prefixed by their length. Builtins are
+// stored by name so the file runs with any registry defining the builtins it uses.
+func (b *Bytecode) Encode() ([]byte, error) {
+	builtins := b.Builtins
+	if builtins == nil {
+		builtins = object.Builtins
+	}
+
+	var e encoder
+	e.buf = append(e.buf, magic...)
+	e.uvarint(FormatVersion)
+
+	e.string(b.File)
+	e.uvarint(uint64(b.NumGlobals))
+	e.strings(b.Globals)
+	names := make([]string, builtins.Len())
+	for i, entry := range builtins.Entries() {
+		names[i] = entry.Name
+	}
+	e.strings(names)
+
+	e.uvarint(uint64(len(b.Constants)))
+	for i, constant := range b.Constants {
+		if err := e.constant(constant); err != nil {
+			return nil, fmt.Errorf("constant %d: %w", i, err)
+		}
+	}
+
+	e.bytes(b.Instructions)
+	e.bytes(b.Lines)
+	e.callees(b.Callees)
+	return e.buf, nil
+}
+
+// Decode reads a program written by [Bytecode.Encode], its builtins are resolved by name in builtins,
+// object.Builtins when nil.
+func Decode(data []byte, builtins *object.Registry) (*Bytecode, error) {
+	if builtins == nil {
+		builtins = object.Builtins
+	}
+	if len(data) < len(magic) || string(data[:len(magic)]) != magic {
+		return nil, ErrFormat
+	}
+	d := decoder{buf: data[len(magic):]}
+	if version := d.uvarint(); d.err == nil && version != FormatVersion {
+		return nil, fmt.Errorf("%w: the file is version %d, this jsgo reads version %d", ErrVersion, version, FormatVersion)
+	}
+
+	b := &Bytecode{Builtins: builtins}
+	b.File = d.string()
+	if b.NumGlobals = d.int(); b.NumGlobals > math.MaxUint16+1 {
+		d.fail("%d globals", b.NumGlobals)
+	}
+	b.Globals = d.strings()
+	names := d.strings()
+
+	numConstants := d.int()
+	for i := 0; i < numConstants && d.err == nil; i++ {
+		b.Constants = append(b.Constants, d.constant())
+	}
+
+	b.Instructions = d.bytes()
+	b.Lines = d.bytes()
+	b.Callees = d.callees()
+	if d.err != nil {
+		return nil, d.err
+	}
+	if len(d.buf) != 0 {
+		return nil, fmt.Errorf("%w: %d trailing bytes", ErrFormat, len(d.buf))
+	}
+
+	link := linker{names: names, builtins: builtins, constants: b.Constants, numGlobals: b.NumGlobals,
+		free: map[*object.BytecodeFunction]int{}}
+	main, err := decode(b.Instructions)
+	if err == nil {
+		err = link.operands(b.Instructions, main, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	functions := map[*object.BytecodeFunction][]instruction{}
+	for _, constant := range b.Constants {
+		if fn, ok := constant.(*object.BytecodeFunction); ok {
+			instructions, err := decode(fn.Instructions)
+			if err == nil {
+				err = link.operands(fn.Instructions, instructions, fn)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("function %s: %w", fn.Name, err)
+			}
+			functions[fn] = instructions
+		}
+	}
+
+	if err := link.stack(main, len(b.Instructions), false); err != nil {
+		return nil, err
+	}
+	for _, constant := range b.Constants {
+		if fn, ok := constant.(*object.BytecodeFunction); ok {
+			if err := link.stack(functions[fn], len(fn.Instructions), true); err != nil {
+				return nil, fmt.Errorf("function %s: %w", fn.Name, err)
+			}
+		}
+	}
+	return b, nil
+}
+
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) uvarint(n uint64) { e.buf = binary.AppendUvarint(e.buf, n) }
+func (e *encoder) varint(n int64)   { e.buf = binary.AppendVarint(e.buf, n) }
+
+func (e *encoder) bytes(b []byte) {
+	e.uvarint(uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) string(s string) {
+	e.uvarint(uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+func (e *encoder) strings(ss []string) {
+	e.uvarint(uint64(len(ss)))
+	for _, s := range ss {
+		e.string(s)
+	}
+}
+
+// callees writes the offsets and names of callees ordered by offset so the encoding is stable.
+func (e *encoder) callees(callees map[int]string) {
+	offsets := make([]int, 0, len(callees))
+	for offset := range callees {
+		offsets = append(offsets, offset)
+	}
+	slices.Sort(offsets)
+	e.uvarint(uint64(len(offsets)))
+	for _, offset := range offsets {
+		e.uvarint(uint64(offset))
+		e.string(callees[offset])
+	}
+}
+
+func (e *encoder) pos(pos token.Pos) {
+	e.uvarint(uint64(pos.Line))
+	e.uvarint(uint64(pos.Col))
+	e.uvarint(uint64(pos.Offset))
+}
+
+func (e *encoder) constant(obj object.Object) error {
+	switch obj := obj.(type) {
+	case *object.Number:
+		e.buf = append(e.buf, tagNumber)
+		e.varint(obj.Value)
+	case *object.Float:
+		e.buf = append(e.buf, tagFloat)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, math.Float64bits(obj.Value))
+	case *object.String:
+		e.buf = append(e.buf, tagString)
+		e.string(obj.Value)
+	case *object.Boolean:
+		e.buf = append(e.buf, tagBoolean)
+		if obj.Value {
+			e.buf = append(e.buf, 1)
+		} else {
+			e.buf = append(e.buf, 0)
+		}
+	case *object.Null:
+		e.buf = append(e.buf, tagNull)
+	case *object.BytecodeFunction:
+		e.buf = append(e.buf, tagFunction)
+		e.bytes(obj.Instructions)
+		e.uvarint(uint64(obj.NumLocals))
+		e.string(obj.Name)
+		e.pos(obj.Pos)
+		e.bytes(obj.Lines)
+		e.strings(obj.Locals)
+		e.callees(obj.Callees)
+	default:
+		return fmt.Errorf("cannot encode a constant of type %s", obj.Type())
+	}
+	return nil
+}
+
+// decoder reads the values written by encoder, the first error is kept and every later read
+// returns a zero value.
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) fail(format string, a ...any) {
+	if d.err == nil {
+		d.err = fmt.Errorf("%w: %s", ErrFormat, fmt.Sprintf(format, a...))
+	}
+}
+
+func (d *decoder) byte() byte {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.buf) == 0 {
+		d.fail("unexpected end of file")
+		return 0
+	}
+	b := d.buf[0]
+	d.buf = d.buf[1:]
+	return b
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	n, size := binary.Uvarint(d.buf)
+	if size <= 0 {
+		d.fail("bad integer")
+		return 0
+	}
+	d.buf = d.buf[size:]
+	return n
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	n, size := binary.Varint(d.buf)
+	if size <= 0 {
+		d.fail("bad integer")
+		return 0
+	}
+	d.buf = d.buf[size:]
+	return n
+}
+
+func (d *decoder) int() int {
+	n := d.uvarint()
+	if n > math.MaxInt32 {
+		d.fail("%d out of range", n)
+		return 0
+	}
+	return int(n)
+}
+
+func (d *decoder) bytes() []byte {
+	n := d.uvarint()
+	if d.err != nil {
+		return nil
+	}
+	if n > uint64(len(d.buf)) {
+		d.fail("unexpected end of file")
+		return nil
+	}
+	b := make([]byte, n)
+	copy(b, d.buf)
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes())
+}
+
+func (d *decoder) strings() []string {
+	n := d.uvarint()
+	if n > uint64(len(d.buf)) {
+		d.fail("%d strings out of range", n)
+		return nil
+	}
+	ss := make([]string, 0, n)
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		ss = append(ss, d.string())
+	}
+	return ss
+}
+
+func (d *decoder) callees() map[int]string {
+	n := d.uvarint()
+	if n > uint64(len(d.buf)) {
+		d.fail("%d callees out of range", n)
+		return nil
+	}
+	if n == 0 {
+		return nil
+	}
+	callees := make(map[int]string, n)
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		offset := d.int()
+		callees[offset] = d.string()
+	}
+	return callees
+}
+
+func (d *decoder) pos() token.Pos {
+	return token.Pos{Line: d.int(), Col: d.int(), Offset: d.int()}
+}
+
+func (d *decoder) constant() object.Object {
+	switch tag := d.byte(); tag {
+	case tagNumber:
+		return &object.Number{Value: d.varint()}
+	case tagFloat:
+		if len(d.buf) < 8 {
+			d.fail("unexpected end of file")
+			return nil
+		}
+		bits := binary.BigEndian.Uint64(d.buf)
+		d.buf = d.buf[8:]
+		return &object.Float{Value: math.Float64frombits(bits)}
+	case tagString:
+		return &object.String{Value: d.string()}
+	case tagBoolean:
+		return &object.Boolean{Value: d.byte() == 1}
+	case tagNull:
+		return &object.Null{}
+	case tagFunction:
+		fn := &object.BytecodeFunction{Instructions: d.bytes(), NumLocals: d.int()}
+		fn.Name = d.string()
+		fn.Pos = d.pos()
+		fn.Lines = d.bytes()
+		fn.Locals = d.strings()
+		fn.Callees = d.callees()
+		return fn
+	default:
+		d.fail("unknown constant tag %d", tag)
+		return nil
+	}
+}
+
+// linker checks that decoded instructions cannot run out of the bounds of the vm and points
+// their builtins at the indices of the registry the program runs with.
+type linker struct {
+	names      []string
+	builtins   *object.Registry
+	constants  []object.Object
+	numGlobals int
+	// free is the number of free variables read by each function
+	free map[*object.BytecodeFunction]int
+}
+
+// instruction is a decoded instruction at offset, next is the offset of the one after it.
+type instruction struct {
+	op       bytecode.Opcode
+	name     string
+	offset   int
+	next     int
+	operands []int
+}
+
+// decode splits ins into instructions, every opcode must be defined and have all of its operands.
+func decode(ins bytecode.Instructions) ([]instruction, error) {
+	var res []instruction
+	for i := 0; i < len(ins); {
+		def, err := bytecode.Lookup(ins[i])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s at %d", ErrFormat, err, i)
+		}
+		if i+1+def.ByteSize > len(ins) {
+			return nil, fmt.Errorf("%w: %s at %d is cut short", ErrFormat, def.Name, i)
+		}
+		operands, read := bytecode.ReadOperands(def, ins[i+1:])
+		res = append(res, instruction{op: bytecode.Opcode(ins[i]), name: def.Name, offset: i, next: i + 1 + read, operands: operands})
+		i += 1 + read
+	}
+	return res, nil
+}
+
+// operands checks the operands of the instructions of fn, the main program when fn is nil, against
+// the constants, globals, locals and builtins of the program and relinks the builtins in ins.
+func (l *linker) operands(ins bytecode.Instructions, instructions []instruction, fn *object.BytecodeFunction) error {
+	numLocals := 0
+	if fn != nil {
+		numLocals = fn.NumLocals
+	}
+	offsets := make(map[int]bool, len(instructions)+1)
+	for _, in := range instructions {
+		offsets[in.offset] = true
+	}
+	offsets[len(ins)] = true
+
+	for _, in := range instructions {
+		switch in.op {
+		case bytecode.OpConstant, bytecode.OpClosure:
+			if in.operands[0] >= len(l.constants) {
+				return fmt.Errorf("%w: %s at %d uses constant %d of %d", ErrFormat, in.name, in.offset, in.operands[0], len(l.constants))
+			}
+			if _, ok := l.constants[in.operands[0]].(*object.BytecodeFunction); !ok && in.op == bytecode.OpClosure {
+				return fmt.Errorf("%w: OpClosure at %d on a constant that is not a function", ErrFormat, in.offset)
+			}
+		case bytecode.OpJump, bytecode.OpJumpNotTrue:
+			if !offsets[in.operands[0]] {
+				return fmt.Errorf("%w: %s at %d jumps to %d which is not an instruction", ErrFormat, in.name, in.offset, in.operands[0])
+			}
+			// a loop jumps back with OpJump, which counts a step of the budget
+			if in.op == bytecode.OpJumpNotTrue && in.operands[0] <= in.offset {
+				return fmt.Errorf("%w: OpJumpNotTrue at %d jumps back to %d", ErrFormat, in.offset, in.operands[0])
+			}
+		case bytecode.OpGetGlobal, bytecode.OpSetGlobal:
+			if in.operands[0] >= l.numGlobals {
+				return fmt.Errorf("%w: %s at %d uses global %d of %d", ErrFormat, in.name, in.offset, in.operands[0], l.numGlobals)
+			}
+		case bytecode.OpGetLocal, bytecode.OpSetLocal:
+			if in.operands[0] >= numLocals {
+				return fmt.Errorf("%w: %s at %d uses local %d of %d", ErrFormat, in.name, in.offset, in.operands[0], numLocals)
+			}
+		case bytecode.OpGetFree:
+			if fn == nil {
+				return fmt.Errorf("%w: OpGetFree at %d outside of a function", ErrFormat, in.offset)
+			}
+			l.free[fn] = max(l.free[fn], in.operands[0]+1)
+		case bytecode.OpDic:
+			if in.operands[0]%2 != 0 {
+				return fmt.Errorf("%w: OpDic at %d with %d values, not key and value pairs", ErrFormat, in.offset, in.operands[0])
+			}
+		case bytecode.OpFor:
+			// the compiler does not emit it and the vm does not run it
+			return fmt.Errorf("%w: OpFor at %d", ErrFormat, in.offset)
+		case bytecode.OpGetBuiltIn:
+			if in.operands[0] >= len(l.names) {
+				return fmt.Errorf("%w: OpGetBuiltIn at %d uses builtin %d of %d", ErrFormat, in.offset, in.operands[0], len(l.names))
+			}
+			index, ok := l.builtins.Index(l.names[in.operands[0]])
+			if !ok {
+				return fmt.Errorf("the program uses the builtin %s which is not defined", l.names[in.operands[0]])
+			}
+			copy(ins[in.offset:], bytecode.Make(bytecode.OpGetBuiltIn, index))
+		}
+	}
+	return nil
+}
+
+// stack follows every path through the instructions of a function, or of the main program when
+// function is false, and checks that no instruction takes more values from the stack than every
+// path to it leaves there and that a function returns instead of running past its last instruction.
+// Closures must capture the free variables their function reads, so the operands of every function
+// are checked first.
+//
+// The compiler does not keep the paths of an if statement at the same height, a branch ending with
+// an assignment leaves no value for the OpPop or OpReturnValue after it. The vm pops nothing from an
+// empty stack, those two are let through and the height where paths meet is the lowest of them.
+func (l *linker) stack(instructions []instruction, size int, function bool) error {
+	if len(instructions) == 0 {
+		if function {
+			return fmt.Errorf("%w: the function has no instructions", ErrFormat)
+		}
+		return nil
+	}
+	at := make(map[int]int, len(instructions))
+	heights := make([]int, len(instructions))
+	for i, in := range instructions {
+		at[in.offset] = i
+		heights[i] = -1
+	}
+
+	heights[0] = 0
+	work := []int{0}
+	for len(work) > 0 {
+		i := work[len(work)-1]
+		work = work[:len(work)-1]
+		in := instructions[i]
+
+		if in.op == bytecode.OpClosure {
+			fn := l.constants[in.operands[0]].(*object.BytecodeFunction)
+			if in.operands[1] < l.free[fn] {
+				return fmt.Errorf("%w: OpClosure at %d captures %d free variables, the function reads %d", ErrFormat, in.offset, in.operands[1], l.free[fn])
+			}
+		}
+		pops, pushes := stackEffect(in)
+		height := heights[i]
+		switch {
+		case height >= pops:
+			height -= pops
+		case in.op == bytecode.OpPop || in.op == bytecode.OpReturnValue:
+			height = 0
+		default:
+			return fmt.Errorf("%w: %s at %d takes %d values from a stack of %d", ErrFormat, in.name, in.offset, pops, height)
+		}
+		height += pushes
+
+		var next []int
+		switch in.op {
+		case bytecode.OpReturn, bytecode.OpReturnValue:
+		case bytecode.OpJump:
+			next = []int{in.operands[0]}
+		case bytecode.OpJumpNotTrue:
+			next = []int{in.operands[0], in.next}
+		default:
+			next = []int{in.next}
+		}
+		for _, offset := range next {
+			if offset == size {
+				if function {
+					return fmt.Errorf("%w: %s at %d runs past the end of the function", ErrFormat, in.name, in.offset)
+				}
+				continue
+			}
+			if j := at[offset]; heights[j] == -1 || height < heights[j] {
+				heights[j] = height
+				work = append(work, j)
+			}
+		}
+	}
+	return nil
+}
+
+// stackEffect returns the number of values an instruction pops from the stack and pushes on it.
+func stackEffect(in instruction) (pops, pushes int) {
+	switch in.op {
+	case bytecode.OpConstant, bytecode.OpTrue, bytecode.OpFalse, bytecode.OpNull, bytecode.OpGetGlobal,
+		bytecode.OpGetLocal, bytecode.OpGetBuiltIn, bytecode.OpGetFree, bytecode.OpCurrentClosure:
+		return 0, 1
+	case bytecode.OpAdd, bytecode.OpSub, bytecode.OpMul, bytecode.OpDiv, bytecode.OpSHL, bytecode.OpXOR,
+		bytecode.OpEqual, bytecode.OpNotEqual, bytecode.OpGreaterThan, bytecode.OpStrictEqual,
+		bytecode.OpStrictNotEqual, bytecode.OpIndex, bytecode.OpDelete:
+		return 2, 1
+	case bytecode.OpMinus, bytecode.OpBang:
+		return 1, 1
+	case bytecode.OpPop, bytecode.OpJumpNotTrue, bytecode.OpSetGlobal, bytecode.OpSetLocal, bytecode.OpReturnValue:
+		return 1, 0
+	case bytecode.OpIndexAssign:
+		return 3, 0
+	case bytecode.OpArray, bytecode.OpDic:
+		return in.operands[0], 1
+	case bytecode.OpClosure:
+		return in.operands[1], 1
+	case bytecode.OpCall:
+		// the function and its arguments are replaced by the result
+		return in.operands[0] + 1, 1
+	}
+	return 0, 0
+}
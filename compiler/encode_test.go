@@ -0,0 +1,223 @@
+package compiler
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jf550-kent/jsgo/bytecode"
+	"github.com/jf550-kent/jsgo/object"
+	"github.com/jf550-kent/jsgo/parser"
+)
+
+const encodeInput = `
+var scale = 1.5;
+var neg = -0.0;
+var make = function(n) {
+	var add = function(x) { return x + n; };
+	return add;
+};
+var names = ["a", "b"].map(function(s) { return s + "!"; });
+console.log(make(2)(3) * scale, true, null, Math.max(1, 2));
+`
+
+func TestEncodeRoundTrip(t *testing.T) {
+	program := compileProgram(t, encodeInput)
+	data, err := program.Encode()
+	if err != nil {
+		t.Fatalf("encode error: %s", err)
+	}
+	decoded, err := Decode(data, object.Builtins)
+	if err != nil {
+		t.Fatalf("decode error: %s", err)
+	}
+
+	if !reflect.DeepEqual(decoded.Instructions, program.Instructions) {
+		t.Errorf("wrong instructions.\nwant=%s\ngot=%s", program.Instructions, decoded.Instructions)
+	}
+	if !reflect.DeepEqual(decoded.Lines, program.Lines) {
+		t.Errorf("wrong line table. want=%v got=%v", program.Lines, decoded.Lines)
+	}
+	if len(program.Callees) == 0 || !reflect.DeepEqual(decoded.Callees, program.Callees) {
+		t.Errorf("wrong callees. want=%v got=%v", program.Callees, decoded.Callees)
+	}
+	if decoded.File != "main.js" || decoded.NumGlobals != program.NumGlobals || !reflect.DeepEqual(decoded.Globals, program.Globals) {
+		t.Errorf("wrong globals. want=%d %v got=%q %d %v", program.NumGlobals, program.Globals, decoded.File, decoded.NumGlobals, decoded.Globals)
+	}
+	if len(decoded.Constants) != len(program.Constants) {
+		t.Fatalf("wrong number of constants. want=%d got=%d", len(program.Constants), len(decoded.Constants))
+	}
+	for i, want := range program.Constants {
+		got := decoded.Constants[i]
+		switch want := want.(type) {
+		case *object.Float:
+			if math.Float64bits(want.Value) != math.Float64bits(got.(*object.Float).Value) {
+				t.Errorf("constant %d: wrong float. want=%v got=%v", i, want.Value, got)
+			}
+		default:
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("constant %d: want=%#v got=%#v", i, want, got)
+			}
+		}
+	}
+}
+
+func TestDecodeResolvesBuiltinsByName(t *testing.T) {
+	program := compileProgram(t, "Math.max(1, 2);")
+	data, err := program.Encode()
+	if err != nil {
+		t.Fatalf("encode error: %s", err)
+	}
+
+	// the same builtins defined in another order
+	registry := object.NewRegistry()
+	registry.Define("extra", &object.Null{})
+	mathBuiltin, _ := object.Builtins.Lookup("Math")
+	registry.Define("Math", mathBuiltin)
+
+	decoded, err := Decode(data, registry)
+	if err != nil {
+		t.Fatalf("decode error: %s", err)
+	}
+	want := compileProgram(t, "Math.max(1, 2);", registry).Instructions
+	if !reflect.DeepEqual(decoded.Instructions, want) {
+		t.Errorf("builtin not resolved by name.\nwant=%s\ngot=%s", want, decoded.Instructions)
+	}
+
+	if _, err := Decode(data, object.NewRegistry()); err == nil {
+		t.Errorf("expected an error for an undefined builtin")
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	data, err := compileProgram(t, encodeInput).Encode()
+	if err != nil {
+		t.Fatalf("encode error: %s", err)
+	}
+
+	if _, err := Decode([]byte("var a = 1;"), nil); !errors.Is(err, ErrFormat) {
+		t.Errorf("expected ErrFormat for a script. got=%v", err)
+	}
+
+	newer := append([]byte(magic), byte(FormatVersion+1))
+	newer = append(newer, data[len(magic)+1:]...)
+	if _, err := Decode(newer, nil); !errors.Is(err, ErrVersion) {
+		t.Errorf("expected ErrVersion. got=%v", err)
+	}
+
+	for n := 0; n < len(data); n++ {
+		if _, err := Decode(data[:n], nil); !errors.Is(err, ErrFormat) {
+			t.Fatalf("expected ErrFormat for a file cut at %d of %d. got=%v", n, len(data), err)
+		}
+	}
+	if _, err := Decode(append(data, 0), nil); !errors.Is(err, ErrFormat) {
+		t.Errorf("expected ErrFormat for trailing bytes. got=%v", err)
+	}
+}
+
+func TestDecodeChecksInstructions(t *testing.T) {
+	function := func(numLocals int, ins ...bytecode.Instructions) *object.BytecodeFunction {
+		return &object.BytecodeFunction{Instructions: mergeInstructions(ins), NumLocals: numLocals, DebugInfo: object.DebugInfo{Name: "f"}}
+	}
+	tests := []struct {
+		name      string
+		main      []bytecode.Instructions
+		constants []object.Object
+		expected  string
+	}{
+		{
+			name:     "jump into an instruction",
+			main:     []bytecode.Instructions{bytecode.Make(bytecode.OpNull), bytecode.Make(bytecode.OpJump, 2)},
+			expected: "OpJump at 1 jumps to 2 which is not an instruction",
+		},
+		{
+			name:     "conditional jump back",
+			main:     []bytecode.Instructions{bytecode.Make(bytecode.OpFalse), bytecode.Make(bytecode.OpJumpNotTrue, 0)},
+			expected: "OpJumpNotTrue at 1 jumps back to 0",
+		},
+		{
+			name:     "global out of range",
+			main:     []bytecode.Instructions{bytecode.Make(bytecode.OpGetGlobal, 1), bytecode.Make(bytecode.OpPop)},
+			expected: "OpGetGlobal at 0 uses global 1 of 1",
+		},
+		{
+			name:      "local out of range",
+			main:      []bytecode.Instructions{bytecode.Make(bytecode.OpClosure, 0, 0), bytecode.Make(bytecode.OpPop)},
+			constants: []object.Object{function(1, bytecode.Make(bytecode.OpGetLocal, 1), bytecode.Make(bytecode.OpReturnValue))},
+			expected:  "function f: not a valid jsgo bytecode file: OpGetLocal at 0 uses local 1 of 1",
+		},
+		{
+			name:     "local in the main program",
+			main:     []bytecode.Instructions{bytecode.Make(bytecode.OpSetLocal, 0)},
+			expected: "OpSetLocal at 0 uses local 0 of 0",
+		},
+		{
+			name:     "call with more arguments than the stack holds",
+			main:     []bytecode.Instructions{bytecode.Make(bytecode.OpGetBuiltIn, 0), bytecode.Make(bytecode.OpCall, 3), bytecode.Make(bytecode.OpPop)},
+			expected: "OpCall at 3 takes 4 values from a stack of 1",
+		},
+		{
+			name: "call on a path leaving no function",
+			main: []bytecode.Instructions{
+				bytecode.Make(bytecode.OpTrue), bytecode.Make(bytecode.OpJumpNotTrue, 5),
+				bytecode.Make(bytecode.OpNull), bytecode.Make(bytecode.OpCall, 0),
+			},
+			expected: "OpCall at 5 takes 1 values from a stack of 0",
+		},
+		{
+			name:      "function running past its end",
+			main:      []bytecode.Instructions{bytecode.Make(bytecode.OpClosure, 0, 0), bytecode.Make(bytecode.OpPop)},
+			constants: []object.Object{function(0, bytecode.Make(bytecode.OpNull), bytecode.Make(bytecode.OpPop))},
+			expected:  "function f: not a valid jsgo bytecode file: OpPop at 1 runs past the end of the function",
+		},
+		{
+			name: "closure without the free variables of its function",
+			main: []bytecode.Instructions{bytecode.Make(bytecode.OpNull), bytecode.Make(bytecode.OpClosure, 0, 1), bytecode.Make(bytecode.OpPop)},
+			constants: []object.Object{function(0,
+				bytecode.Make(bytecode.OpGetFree, 0), bytecode.Make(bytecode.OpGetFree, 1), bytecode.Make(bytecode.OpAdd), bytecode.Make(bytecode.OpReturnValue)),
+			},
+			expected: "OpClosure at 1 captures 1 free variables, the function reads 2",
+		},
+		{
+			name:     "free variable in the main program",
+			main:     []bytecode.Instructions{bytecode.Make(bytecode.OpGetFree, 0)},
+			expected: "OpGetFree at 0 outside of a function",
+		},
+		{
+			name:     "dictionary without a value for its last key",
+			main:     []bytecode.Instructions{bytecode.Make(bytecode.OpNull), bytecode.Make(bytecode.OpDic, 1)},
+			expected: "OpDic at 1 with 1 values, not key and value pairs",
+		},
+	}
+
+	for _, tt := range tests {
+		program := &Bytecode{Instructions: mergeInstructions(tt.main), Constants: tt.constants, NumGlobals: 1, Globals: []string{"a"}}
+		data, err := program.Encode()
+		if err != nil {
+			t.Fatalf("%s: encode error: %s", tt.name, err)
+		}
+		_, err = Decode(data, nil)
+		if !errors.Is(err, ErrFormat) {
+			t.Errorf("%s: expected ErrFormat. got=%v", tt.name, err)
+			continue
+		}
+		if !strings.HasSuffix(err.Error(), tt.expected) {
+			t.Errorf("%s: wrong error. want suffix=%q got=%q", tt.name, tt.expected, err)
+		}
+	}
+}
+
+func compileProgram(t *testing.T, input string, builtins ...*object.Registry) *Bytecode {
+	t.Helper()
+
+	compiler := New()
+	if len(builtins) > 0 {
+		compiler = NewWithBuiltins(builtins[0])
+	}
+	if err := compiler.Compile(parser.Parse("main.js", []byte(input))); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	return compiler.ByteCode()
+}
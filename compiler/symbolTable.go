@@ -61,6 +61,18 @@ func (st *SymbolTable) Resolve(s string) (Symbol, bool) {
 	return sy, ok
 }
 
+// names returns the names of the variables defined in st by index, a name defined again
+// leaves its earlier index without one.
+func (st *SymbolTable) names() []string {
+	names := make([]string, st.numberDefinitions)
+	for name, sym := range st.store {
+		if sym.Scope == GlobalScope || sym.Scope == LocalScope {
+			names[sym.Index] = name
+		}
+	}
+	return names
+}
+
 func (s *SymbolTable) DefineBuiltIn(index int, name string) Symbol {
 	symbol := Symbol{Name: name, Index: index, Scope: BuiltInScope}
 	s.store[name] = symbol
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jf550-kent/jsgo/compiler"
+	"github.com/jf550-kent/jsgo/parser"
+)
+
+// disasm prints the bytecode of a script or of a file written by build: jsgo disasm <filename>.
+// The source of a bytecode file is read from the file it was built from when it still exists.
+func disasm(args []string) (err error) {
+	if len(args) != 1 {
+		return errors.New("usage ./jsgo disasm <filename>")
+	}
+	fileName := args[0]
+
+	if strings.HasSuffix(fileName, BYTECODE_EXT) {
+		program, err := loadBytecode(fileName)
+		if err != nil {
+			return err
+		}
+		source, _ := os.ReadFile(program.File)
+		fmt.Print(program.Disassemble(source))
+		return nil
+	}
+
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	com := compiler.New()
+	if err := com.Compile(parser.Parse(fileName, content)); err != nil {
+		return fmt.Errorf("compiler error: %w", err)
+	}
+	fmt.Print(com.ByteCode().Disassemble(content))
+	return nil
+}
@@ -0,0 +1,201 @@
+package engine
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"sort"
+
+	"github.com/jf550-kent/jsgo/object"
+)
+
+var errCycle = errors.New("engine: cannot convert a value that contains itself")
+
+// ToObject converts a Go value to the object a program sees. nil is null, booleans, strings
+// and numbers become their JS value, integers are a *object.Number unless they do not fit
+// in an int64. Slices become arrays and maps dictionaries, converted element by element with
+// the keys of a map in sorted order. A struct or a pointer to one is an object whose exported
+// fields and methods are its properties, and a func is a builtin converting its arguments to
+// the types of its parameters. An object.Object is returned as is. A map, slice or pointer
+// that contains itself cannot be converted.
+func ToObject(v any) (object.Object, error) {
+	return toObject(v, nil)
+}
+
+func toObject(v any, visiting visited) (object.Object, error) {
+	switch v := v.(type) {
+	case nil:
+		return &object.Null{}, nil
+	case object.Object:
+		return v, nil
+	case bool:
+		return &object.Boolean{Value: v}, nil
+	case string:
+		return &object.String{Value: v}, nil
+	case int:
+		return &object.Number{Value: int64(v)}, nil
+	case int8:
+		return &object.Number{Value: int64(v)}, nil
+	case int16:
+		return &object.Number{Value: int64(v)}, nil
+	case int32:
+		return &object.Number{Value: int64(v)}, nil
+	case int64:
+		return &object.Number{Value: v}, nil
+	case uint:
+		return unsignedObject(uint64(v)), nil
+	case uint8:
+		return &object.Number{Value: int64(v)}, nil
+	case uint16:
+		return &object.Number{Value: int64(v)}, nil
+	case uint32:
+		return &object.Number{Value: int64(v)}, nil
+	case uint64:
+		return unsignedObject(v), nil
+	case float32:
+		return &object.Float{Value: float64(v)}, nil
+	case float64:
+		return &object.Float{Value: v}, nil
+	case []any:
+		visiting, err := visiting.enter(reflect.ValueOf(v))
+		if err != nil {
+			return nil, err
+		}
+		defer visiting.leave(reflect.ValueOf(v))
+		arr := &object.Array{Body: make([]object.Object, len(v))}
+		for i, el := range v {
+			obj, err := toObject(el, visiting)
+			if err != nil {
+				return nil, err
+			}
+			arr.Body[i] = obj
+		}
+		return arr, nil
+	case map[string]any:
+		visiting, err := visiting.enter(reflect.ValueOf(v))
+		if err != nil {
+			return nil, err
+		}
+		defer visiting.leave(reflect.ValueOf(v))
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		dic := object.NewDictionary()
+		for _, key := range keys {
+			obj, err := toObject(v[key], visiting)
+			if err != nil {
+				return nil, err
+			}
+			dic.Set(&object.String{Value: key}, obj)
+		}
+		return dic, nil
+	}
+	return reflectObject(reflect.ValueOf(v), visiting)
+}
+
+// visited holds the maps, slices and pointers being converted by [ToObject], converting one
+// again inside itself is a cycle. A slice is identified by its length too so a shorter slice
+// of the same array is not mistaken for it.
+type visited map[visit]struct{}
+
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+func visitOf(v reflect.Value) visit {
+	key := visit{ptr: v.Pointer(), typ: v.Type()}
+	if v.Kind() == reflect.Slice {
+		key.len = v.Len()
+	}
+	return key
+}
+
+// enter records that v is being converted, it returns the set to pass on to the values in v.
+func (s visited) enter(v reflect.Value) (visited, error) {
+	key := visitOf(v)
+	if _, ok := s[key]; ok {
+		return nil, errCycle
+	}
+	if s == nil {
+		s = visited{}
+	}
+	s[key] = struct{}{}
+	return s, nil
+}
+
+func (s visited) leave(v reflect.Value) {
+	delete(s, visitOf(v))
+}
+
+func unsignedObject(v uint64) object.Object {
+	if v > math.MaxInt64 {
+		return &object.Float{Value: float64(v)}
+	}
+	return &object.Number{Value: int64(v)}
+}
+
+// FromObject converts obj to a Go value. null is nil, a *object.Number an int64, a *object.Float
+// a float64, an array a []any and a dictionary a map[string]any with its keys converted to strings.
+// A struct exposed by [ToObject] is its pointer, functions and the other objects are returned as is.
+func FromObject(obj object.Object) (any, error) {
+	return fromObject(obj, map[object.Object]struct{}{})
+}
+
+func fromObject(obj object.Object, visiting map[object.Object]struct{}) (any, error) {
+	switch obj := obj.(type) {
+	case nil, *object.Null:
+		return nil, nil
+	case *object.Boolean:
+		return obj.Value, nil
+	case *object.Number:
+		return obj.Value, nil
+	case *object.Float:
+		return obj.Value, nil
+	case *object.String:
+		return obj.Value, nil
+	case *object.Array:
+		if err := enter(visiting, obj); err != nil {
+			return nil, err
+		}
+		defer delete(visiting, obj)
+		result := make([]any, len(obj.Body))
+		for i, el := range obj.Body {
+			var err error
+			if result[i], err = fromObject(el, visiting); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	case *object.Dictionary:
+		if err := enter(visiting, obj); err != nil {
+			return nil, err
+		}
+		defer delete(visiting, obj)
+		result := make(map[string]any, obj.Len())
+		for _, pair := range obj.Pairs() {
+			val, err := fromObject(pair.Value, visiting)
+			if err != nil {
+				return nil, err
+			}
+			result[object.ToString(pair.Key)] = val
+		}
+		return result, nil
+	case *hostObject:
+		return obj.Value(), nil
+	}
+	return obj, nil
+}
+
+// enter records that obj is being converted, converting it again inside itself is a cycle.
+func enter(visiting map[object.Object]struct{}, obj object.Object) error {
+	if _, ok := visiting[obj]; ok {
+		return errCycle
+	}
+	visiting[obj] = struct{}{}
+	return nil
+}
@@ -0,0 +1,299 @@
+// Package engine embeds jsgo in a Go program. A [Runtime] compiles and runs programs with
+// either interpreter and keeps its globals between them, so a host can set values, run a
+// script and call the functions it defined.
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+
+	"github.com/jf550-kent/jsgo/ast"
+	"github.com/jf550-kent/jsgo/compiler"
+	"github.com/jf550-kent/jsgo/evaluator"
+	"github.com/jf550-kent/jsgo/object"
+	"github.com/jf550-kent/jsgo/parser"
+	"github.com/jf550-kent/jsgo/vm"
+)
+
+// Engine selects the interpreter of a [Runtime].
+type Engine int
+
+const (
+	Tree     Engine = iota // the tree walking evaluator
+	Bytecode               // the compiler and the VM
+)
+
+func (e Engine) String() string {
+	switch e {
+	case Tree:
+		return "tree"
+	case Bytecode:
+		return "bytecode"
+	}
+	return fmt.Sprintf("Engine(%d)", int(e))
+}
+
+var ErrNotCompiled = errors.New("engine: no program compiled")
+
+// ErrInterrupted, ErrBudgetExceeded, ErrMemoryLimit and ErrStackOverflow are wrapped by the error
+// of a program stopped by its context, its step budget, its memory limit or its call depth.
+var (
+	ErrInterrupted    = object.ErrInterrupted
+	ErrBudgetExceeded = object.ErrBudgetExceeded
+	ErrMemoryLimit    = object.ErrMemoryLimit
+	ErrStackOverflow  = object.ErrStackOverflow
+)
+
+// Runtime runs programs with one engine. Every program compiled by a runtime shares its
+// globals, a function defined by one program can be called after another has run.
+// A Runtime must not be used by more than one goroutine at a time.
+type Runtime struct {
+	engine   Engine
+	builtins *object.Registry
+	budget   int64
+	memory   int64
+	depth    int
+	random   *rand.Rand
+
+	// tree engine
+	main *ast.Main
+	env  *object.Environment
+
+	// bytecode engine
+	program   *compiler.Bytecode
+	symbols   *compiler.SymbolTable
+	constants []object.Object
+	globals   []object.Object
+	machine   *vm.VM
+}
+
+// New returns a runtime using engine with the builtins of object.Builtins.
+func New(engine Engine) *Runtime {
+	return NewWithBuiltins(engine, object.Builtins)
+}
+
+// NewWithBuiltins returns a runtime using engine with the globals registered in builtins.
+func NewWithBuiltins(engine Engine, builtins *object.Registry) *Runtime {
+	rt := &Runtime{engine: engine, builtins: builtins}
+	switch engine {
+	case Tree:
+		rt.env = object.NewEnvironmentWithBuiltins(builtins)
+	case Bytecode:
+		rt.symbols = compiler.NewWithBuiltins(builtins).SymbolTable()
+		rt.constants = []object.Object{}
+	default:
+		panic("engine: unknown engine " + engine.String())
+	}
+	return rt
+}
+
+func (rt *Runtime) Engine() Engine { return rt.engine }
+
+// SetBudget limits every later Run and Call to steps loop iterations and function calls,
+// 0 removes the limit.
+func (rt *Runtime) SetBudget(steps int64) {
+	rt.budget = steps
+}
+
+// SetMemoryLimit stops every later Run and Call with a RangeError once it allocated more than
+// bytes in arrays, dictionaries and strings, 0 removes the limit.
+func (rt *Runtime) SetMemoryLimit(bytes int64) {
+	rt.memory = bytes
+}
+
+// SetMaxDepth makes a call nested deeper than depth calls a RangeError in every later Run and Call,
+// 0 restores object.DefaultMaxDepth.
+func (rt *Runtime) SetMaxDepth(depth int) {
+	rt.depth = depth
+}
+
+// SetRandomSeed makes Math.random of every later Run and Call draw from a generator seeded with seed,
+// the sequence continues across runs and is independent of other runtimes.
+func (rt *Runtime) SetRandomSeed(seed int64) {
+	rt.random = rand.New(rand.NewSource(seed))
+}
+
+// Compile parses src and, for the bytecode engine, compiles it. The program is run by [Runtime.Run].
+func (rt *Runtime) Compile(src string) (err error) {
+	defer recoverError(&err)
+
+	main := parser.Parse("", []byte(src))
+	if rt.engine == Tree {
+		rt.main = main
+		return nil
+	}
+
+	com := compiler.NewWithState(rt.symbols, rt.constants, rt.builtins)
+	if err := com.Compile(main); err != nil {
+		return err
+	}
+	rt.program = com.ByteCode()
+	rt.constants = rt.program.Constants
+	return nil
+}
+
+// Run runs the last compiled program and returns the value of its last statement.
+// A program still running when ctx is done is stopped with an error wrapping ErrInterrupted.
+func (rt *Runtime) Run(ctx context.Context) (result object.Object, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	defer recoverError(&err)
+	limits := rt.limits(ctx)
+
+	switch rt.engine {
+	case Tree:
+		if rt.main == nil {
+			return nil, ErrNotCompiled
+		}
+		rt.env.SetLimits(limits)
+		result = evaluator.EvalIn(rt.main, rt.env)
+	case Bytecode:
+		if rt.program == nil {
+			return nil, ErrNotCompiled
+		}
+		rt.growGlobals(rt.program.NumGlobals)
+		rt.machine = vm.NewWithGlobals(rt.program, rt.globals)
+		rt.machine.SetLimits(limits)
+		if err := rt.machine.Run(); err != nil {
+			// a failed run leaves frames on the machine, Call starts from a new one
+			rt.machine = nil
+			return nil, err
+		}
+		result = rt.machine.LastPopStack()
+	}
+	return valueOf(result)
+}
+
+// Get returns the global or builtin bound to name.
+func (rt *Runtime) Get(name string) (object.Object, bool) {
+	if rt.engine == Tree {
+		if val, ok := rt.env.Get(name); ok {
+			return val, true
+		}
+		return rt.env.Builtin(name)
+	}
+
+	sym, ok := rt.symbols.Resolve(name)
+	if !ok {
+		return nil, false
+	}
+	switch sym.Scope {
+	case compiler.GlobalScope:
+		if sym.Index < len(rt.globals) && rt.globals[sym.Index] != nil {
+			return rt.globals[sym.Index], true
+		}
+		return &object.Null{}, true
+	case compiler.BuiltInScope:
+		return rt.builtins.At(sym.Index), true
+	}
+	return nil, false
+}
+
+// Set binds the global name to value converted with [ToObject], a builtin of the same name is shadowed.
+// With the bytecode engine the global is seen by the programs compiled after Set.
+// A Go func is named after the global in the errors it raises.
+func (rt *Runtime) Set(name string, value any) error {
+	obj, err := ToObject(value)
+	if err != nil {
+		return err
+	}
+	if fn := reflect.ValueOf(value); fn.Kind() == reflect.Func && !fn.IsNil() {
+		obj = hostFunction(name, fn)
+	}
+	if rt.engine == Tree {
+		rt.env.Set(name, obj)
+		return nil
+	}
+
+	sym, ok := rt.symbols.Resolve(name)
+	if !ok || sym.Scope != compiler.GlobalScope {
+		sym = rt.symbols.Define(name)
+	}
+	if sym.Index >= vm.MAX_GLOBAL_VARIABLES {
+		return fmt.Errorf("engine: too many globals to set %s", name)
+	}
+	rt.growGlobals(sym.Index + 1)
+	rt.globals[sym.Index] = obj
+	return nil
+}
+
+// Call calls the function bound to the global name with args converted with [ToObject].
+// An error thrown by the function is returned as an *object.Error.
+func (rt *Runtime) Call(name string, args ...any) (object.Object, error) {
+	return rt.CallContext(context.Background(), name, args...)
+}
+
+// CallContext is Call stopping the function when ctx is done.
+func (rt *Runtime) CallContext(ctx context.Context, name string, args ...any) (result object.Object, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	fn, ok := rt.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("engine: %s is not defined", name)
+	}
+	objs := make([]object.Object, len(args))
+	for i, arg := range args {
+		if objs[i], err = ToObject(arg); err != nil {
+			return nil, err
+		}
+	}
+	defer recoverError(&err)
+	limits := rt.limits(ctx)
+
+	if rt.engine == Tree {
+		rt.env.SetLimits(limits)
+		return valueOf(evaluator.Call(rt.env, fn, objs...))
+	}
+	if rt.machine == nil {
+		program := &compiler.Bytecode{Constants: rt.constants, Builtins: rt.builtins}
+		rt.machine = vm.NewWithGlobals(program, rt.globals)
+	}
+	rt.machine.SetLimits(limits)
+	return valueOf(rt.machine.Call(fn, objs...))
+}
+
+// growGlobals makes room for n globals. The VM of the last run still holds the old slice,
+// Call starts from a new one sharing the grown globals.
+func (rt *Runtime) growGlobals(n int) {
+	if n > len(rt.globals) {
+		rt.globals = append(rt.globals, make([]object.Object, n-len(rt.globals))...)
+		rt.machine = nil
+	}
+}
+
+func (rt *Runtime) limits(ctx context.Context) *object.Limits {
+	limits := object.NewLimits(ctx, rt.budget)
+	limits.SetMemoryLimit(rt.memory)
+	limits.SetMaxDepth(rt.depth)
+	limits.SetRandom(rt.random)
+	return limits
+}
+
+// valueOf returns the error of a failed program, nil is returned as null.
+func valueOf(obj object.Object) (object.Object, error) {
+	switch obj := obj.(type) {
+	case nil:
+		return &object.Null{}, nil
+	case *object.Error:
+		return nil, obj
+	}
+	return obj, nil
+}
+
+// recoverError turns the panic of a syntax error or an engine failure into *err.
+func recoverError(err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if e, ok := r.(error); ok {
+		*err = e
+		return
+	}
+	*err = fmt.Errorf("%v", r)
+}
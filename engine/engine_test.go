@@ -0,0 +1,397 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jf550-kent/jsgo/object"
+)
+
+var engines = []Engine{Tree, Bytecode}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"1 + 2;", int64(3)},
+		{"1.5 * 2;", 3.0},
+		{`"a" + "b";`, "ab"},
+		{"[1, [2, 3]];", []any{int64(1), []any{int64(2), int64(3)}}},
+		{`var d = {"a": 1, "b": null}; d;`, map[string]any{"a": int64(1), "b": nil}},
+		{"Math.max(1, 5);", int64(5)},
+	}
+
+	for _, engine := range engines {
+		for _, tt := range tests {
+			rt := New(engine)
+			result := run(t, rt, tt.input)
+			got, err := FromObject(result)
+			if err != nil {
+				t.Fatalf("%s: %s", engine, err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("%s: %q wrong result. want=%#v got=%#v", engine, tt.input, tt.expected, got)
+			}
+		}
+	}
+}
+
+func TestGlobals(t *testing.T) {
+	for _, engine := range engines {
+		rt := New(engine)
+		if err := rt.Set("limit", 10); err != nil {
+			t.Fatalf("%s: %s", engine, err)
+		}
+		if err := rt.Set("names", []any{"a", "b"}); err != nil {
+			t.Fatalf("%s: %s", engine, err)
+		}
+		run(t, rt, `var total = limit * 2; var first = names[0];`)
+
+		total, ok := rt.Get("total")
+		if !ok {
+			t.Fatalf("%s: total not defined", engine)
+		}
+		if got, _ := FromObject(total); got != int64(20) {
+			t.Errorf("%s: wrong total. got=%v", engine, got)
+		}
+		first, _ := rt.Get("first")
+		if got, _ := FromObject(first); got != "a" {
+			t.Errorf("%s: wrong first. got=%v", engine, got)
+		}
+
+		// a later program sees the globals of the earlier one
+		result := run(t, rt, "total + limit;")
+		if got, _ := FromObject(result); got != int64(30) {
+			t.Errorf("%s: wrong result of second program. got=%v", engine, got)
+		}
+
+		// a global set after a run is seen by the functions it defined
+		run(t, rt, "var factor = 1; var scaled = function() { return limit * factor; };")
+		for i := 0; i < 100; i++ {
+			if err := rt.Set(fmt.Sprintf("extra%d", i), i); err != nil {
+				t.Fatalf("%s: %s", engine, err)
+			}
+		}
+		if err := rt.Set("factor", 3); err != nil {
+			t.Fatalf("%s: %s", engine, err)
+		}
+		scaled, err := rt.Call("scaled")
+		if err != nil {
+			t.Fatalf("%s: %s", engine, err)
+		}
+		if got, _ := FromObject(scaled); got != int64(30) {
+			t.Errorf("%s: wrong scaled. got=%v", engine, got)
+		}
+
+		if _, ok := rt.Get("missing"); ok {
+			t.Errorf("%s: missing should not be defined", engine)
+		}
+		if _, ok := rt.Get("console"); !ok {
+			t.Errorf("%s: builtins should be visible through Get", engine)
+		}
+	}
+}
+
+func TestCall(t *testing.T) {
+	for _, engine := range engines {
+		rt := New(engine)
+		run(t, rt, `
+		var scale = 3;
+		var mul = function(a, b) { return a * b * scale; };
+		var fail = function() { return [].reduce(function(a, b) { return a; }); };
+		`)
+
+		result, err := rt.Call("mul", 2, 5)
+		if err != nil {
+			t.Fatalf("%s: %s", engine, err)
+		}
+		if got, _ := FromObject(result); got != int64(30) {
+			t.Errorf("%s: wrong result. got=%v", engine, got)
+		}
+
+		if err := rt.Set("scale", 1); err != nil {
+			t.Fatalf("%s: %s", engine, err)
+		}
+		result, _ = rt.Call("mul", 2, 5)
+		if got, _ := FromObject(result); got != int64(10) {
+			t.Errorf("%s: Set not seen by the function. got=%v", engine, got)
+		}
+
+		result, err = rt.Call("Math.floor", 1.5)
+		if err == nil {
+			t.Errorf("%s: expected an error calling an undefined global. got=%v", engine, result)
+		}
+
+		_, err = rt.Call("fail")
+		var errObj *object.Error
+		if !errors.As(err, &errObj) {
+			t.Fatalf("%s: expected *object.Error. got=%T(%v)", engine, err, err)
+		}
+		if errObj.Message != "TypeError: Reduce of empty array with no initial value" {
+			t.Errorf("%s: wrong error message. got=%q", engine, errObj.Message)
+		}
+
+		// the runtime is still usable after the failed call
+		result, err = rt.Call("mul", 1, 1)
+		if err != nil {
+			t.Fatalf("%s: %s", engine, err)
+		}
+		if got, _ := FromObject(result); got != int64(1) {
+			t.Errorf("%s: wrong result after failed call. got=%v", engine, got)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	for _, engine := range engines {
+		rt := New(engine)
+		if _, err := rt.Run(context.Background()); !errors.Is(err, ErrNotCompiled) {
+			t.Errorf("%s: expected ErrNotCompiled. got=%v", engine, err)
+		}
+		if err := rt.Compile("var = ;"); err == nil {
+			t.Errorf("%s: expected a syntax error", engine)
+		}
+
+		if err := rt.Compile("[].reduce(function(a, b) { return a; });"); err != nil {
+			t.Fatalf("%s: %s", engine, err)
+		}
+		if _, err := rt.Run(context.Background()); err == nil {
+			t.Errorf("%s: expected a runtime error", engine)
+		}
+
+		// a function called by the host has no caller in the program
+		run(t, rt, "var first = function(a) { return a[0].name; };")
+		_, err := rt.Call("first", []any{nil})
+		var errObj *object.Error
+		if !errors.As(err, &errObj) {
+			t.Fatalf("%s: expected an *object.Error. got=%v", engine, err)
+		}
+		if want := "\n    at first (1:35)"; !strings.HasSuffix(errObj.StackTrace(), want) {
+			t.Errorf("%s: wrong stack trace. want suffix=%q got=%q", engine, want, errObj.StackTrace())
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := rt.Run(ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: expected context.Canceled. got=%v", engine, err)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	values := []any{
+		nil, true, "s", int64(-4), 2.5,
+		[]any{int64(1), "two", []any{}},
+		map[string]any{"k": []any{nil, false}},
+	}
+	for _, v := range values {
+		obj, err := ToObject(v)
+		if err != nil {
+			t.Fatalf("ToObject(%#v): %s", v, err)
+		}
+		got, err := FromObject(obj)
+		if err != nil {
+			t.Fatalf("FromObject(%s): %s", obj, err)
+		}
+		if !reflect.DeepEqual(got, v) {
+			t.Errorf("round trip changed value. want=%#v got=%#v", v, got)
+		}
+	}
+
+	obj, _ := ToObject(uint64(1) << 63)
+	if _, ok := obj.(*object.Float); !ok {
+		t.Errorf("uint64 past int64 should be a float. got=%T", obj)
+	}
+	if _, err := ToObject(make(chan int)); err == nil {
+		t.Errorf("expected an error converting a chan")
+	}
+
+	arr := &object.Array{}
+	arr.Body = []object.Object{arr}
+	if _, err := FromObject(arr); !errors.Is(err, errCycle) {
+		t.Errorf("expected a cycle error. got=%v", err)
+	}
+
+	m := map[string]any{}
+	m["m"] = m
+	s := []any{nil}
+	s[0] = s
+	type node struct{ Next []*node }
+	n := &node{}
+	n.Next = []*node{n}
+	type list []list
+	l := list{nil}
+	l[0] = l
+	var p any
+	p = &p
+	for _, v := range []any{m, s, map[string]any{"a": []any{m}}, l, p} {
+		if _, err := ToObject(v); !errors.Is(err, errCycle) {
+			t.Errorf("ToObject(%T) expected a cycle error. got=%v", v, err)
+		}
+	}
+	if _, err := ToObject(n); err != nil {
+		t.Errorf("a struct pointer is converted lazily. got=%v", err)
+	}
+
+	// a value shared without a cycle is converted each time it appears
+	shared := []any{int64(1)}
+	obj, err := ToObject(map[string]any{"a": shared, "b": []any{shared, shared}})
+	if err != nil {
+		t.Fatalf("a shared value is not a cycle. got=%v", err)
+	}
+	if got := obj.String(); got != "{a: [1], b: [[1], [1]]}" {
+		t.Errorf("wrong shared conversion. got=%s", got)
+	}
+}
+
+func run(t *testing.T, rt *Runtime, input string) object.Object {
+	t.Helper()
+
+	if err := rt.Compile(input); err != nil {
+		t.Fatalf("%s: compile error: %s", rt.Engine(), err)
+	}
+	result, err := rt.Run(context.Background())
+	if err != nil {
+		t.Fatalf("%s: run error: %s", rt.Engine(), err)
+	}
+	return result
+}
+
+func TestLimits(t *testing.T) {
+	for _, engine := range engines {
+		rt := New(engine)
+		rt.SetBudget(1000)
+		if err := rt.Compile("var spin = function() { for (;true;) { 1; } }; spin();"); err != nil {
+			t.Fatalf("%s: %s", engine, err)
+		}
+		if _, err := rt.Run(context.Background()); !errors.Is(err, ErrBudgetExceeded) {
+			t.Errorf("%s: expected ErrBudgetExceeded. got=%v", engine, err)
+		}
+
+		if err := rt.Compile("var a = []; a[0] = spin();"); err != nil {
+			t.Fatalf("%s: %s", engine, err)
+		}
+		if _, err := rt.Run(context.Background()); !errors.Is(err, ErrBudgetExceeded) {
+			t.Errorf("%s: expected ErrBudgetExceeded from an array assignment. got=%v", engine, err)
+		}
+
+		// the budget is per run, a new run starts from zero
+		result := run(t, rt, "var n = 0; for (var i = 0; i < 900; i = i + 1) { n = n + 1; } n;")
+		if got, _ := FromObject(result); got != int64(900) {
+			t.Errorf("%s: wrong result. got=%v", engine, got)
+		}
+
+		rt.SetBudget(0)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		_, err := rt.CallContext(ctx, "spin")
+		cancel()
+		if !errors.Is(err, ErrInterrupted) || !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%s: expected ErrInterrupted wrapping the deadline. got=%v", engine, err)
+		}
+	}
+}
+
+func TestMemoryLimit(t *testing.T) {
+	for _, engine := range engines {
+		rt := New(engine)
+		rt.SetMemoryLimit(1 << 20)
+		if err := rt.Compile("var grow = function(n) { var a = []; a[n] = 1; return a.length; };"); err != nil {
+			t.Fatalf("%s: %s", engine, err)
+		}
+		if _, err := rt.Run(context.Background()); err != nil {
+			t.Fatalf("%s: %s", engine, err)
+		}
+
+		_, err := rt.Call("grow", 1000000000)
+		var errObj *object.Error
+		if !errors.As(err, &errObj) || !errors.Is(err, ErrMemoryLimit) {
+			t.Fatalf("%s: expected a RangeError. got=%v", engine, err)
+		}
+		if !strings.HasPrefix(errObj.Message, "RangeError:") {
+			t.Errorf("%s: wrong error message. got=%q", engine, errObj.Message)
+		}
+
+		// the limit is per call
+		result, err := rt.Call("grow", 1000)
+		if err != nil {
+			t.Fatalf("%s: %s", engine, err)
+		}
+		if got, _ := FromObject(result); got != int64(1001) {
+			t.Errorf("%s: wrong length. got=%v", engine, got)
+		}
+
+		// builtins growing arrays and strings are accounted too
+		for _, input := range []string{
+			"var a = []; for (var i = 0; i < 2000000; i = i + 1) { a.push(i); }",
+			"var a = [1]; for (var i = 0; i < 21; i = i + 1) { a = [a, a]; } JSON.stringify(a);",
+		} {
+			if err := rt.Compile(input); err != nil {
+				t.Fatalf("%s: %s", engine, err)
+			}
+			if _, err := rt.Run(context.Background()); !errors.Is(err, ErrMemoryLimit) {
+				t.Errorf("%s: %q expected a RangeError. got=%v", engine, input, err)
+			}
+		}
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	for _, engine := range engines {
+		rt := New(engine)
+		rt.SetMaxDepth(20)
+		run(t, rt, "var count = function(n) { if (n == 0) { return 0; } return 1 + count(n - 1); };")
+
+		_, err := rt.Call("count", 20)
+		if !errors.Is(err, ErrStackOverflow) {
+			t.Fatalf("%s: expected a RangeError. got=%v", engine, err)
+		}
+		if want := "RangeError: Maximum call stack size exceeded in count 1:13"; err.Error() != want {
+			t.Errorf("%s: wrong error message. want=%q got=%q", engine, want, err.Error())
+		}
+
+		// the runtime is still usable after the overflow
+		result, err := rt.Call("count", 19)
+		if err != nil {
+			t.Fatalf("%s: %s", engine, err)
+		}
+		if got, _ := FromObject(result); got != int64(19) {
+			t.Errorf("%s: wrong result. got=%v", engine, got)
+		}
+	}
+}
+
+func TestRandomSeed(t *testing.T) {
+	for _, engine := range engines {
+		draw := func(rt *Runtime) float64 {
+			t.Helper()
+			got, _ := FromObject(run(t, rt, "Math.random();"))
+			return got.(float64)
+		}
+
+		a, b := New(engine), New(engine)
+		a.SetRandomSeed(7)
+		b.SetRandomSeed(7)
+		first := draw(a)
+		if got := draw(b); got != first {
+			t.Errorf("%s: same seed gave another number. want=%v got=%v", engine, first, got)
+		}
+
+		// the sequence continues across runs, seeding another runtime does not restart it
+		second := draw(a)
+		b.SetRandomSeed(7)
+		draw(b)
+		if got := draw(b); got != second {
+			t.Errorf("%s: wrong second number. want=%v got=%v", engine, second, got)
+		}
+		New(engine).SetRandomSeed(1)
+		if got, want := draw(a), draw(b); got != want {
+			t.Errorf("%s: seeding another runtime changed the sequence. want=%v got=%v", engine, want, got)
+		}
+	}
+}
@@ -0,0 +1,441 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jf550-kent/jsgo/object"
+)
+
+var (
+	callerType = reflect.TypeOf((*object.Caller)(nil)).Elem()
+	errorType  = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// reflectObject converts the values [ToObject] does not handle directly. A struct is exposed as a
+// host object, a struct value is copied first so its pointer methods can be called. Slices and
+// arrays are copied into an array and maps into a dictionary with their keys sorted, a nil
+// pointer, slice, map or func is null. visiting holds the values being converted around v.
+func reflectObject(v reflect.Value, visiting visited) (object.Object, error) {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return &object.Null{}, nil
+	case reflect.Bool:
+		return &object.Boolean{Value: v.Bool()}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &object.Number{Value: v.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return unsignedObject(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return &object.Float{Value: v.Float()}, nil
+	case reflect.String:
+		return &object.String{Value: v.String()}, nil
+	case reflect.Interface:
+		if v.IsNil() {
+			return &object.Null{}, nil
+		}
+		return toObject(v.Elem().Interface(), visiting)
+	case reflect.Pointer:
+		if v.IsNil() {
+			return &object.Null{}, nil
+		}
+		if v.Elem().Kind() == reflect.Struct {
+			return &hostObject{ptr: v}, nil
+		}
+		visiting, err := visiting.enter(v)
+		if err != nil {
+			return nil, err
+		}
+		defer visiting.leave(v)
+		return reflectObject(v.Elem(), visiting)
+	case reflect.Struct:
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		return &hostObject{ptr: ptr}, nil
+	case reflect.Func:
+		if v.IsNil() {
+			return &object.Null{}, nil
+		}
+		return hostFunction("func", v), nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return &object.Null{}, nil
+		}
+		if v.Kind() == reflect.Slice {
+			var err error
+			if visiting, err = visiting.enter(v); err != nil {
+				return nil, err
+			}
+			defer visiting.leave(v)
+		}
+		arr := &object.Array{Body: make([]object.Object, v.Len())}
+		for i := range arr.Body {
+			el, err := reflectObject(v.Index(i), visiting)
+			if err != nil {
+				return nil, err
+			}
+			arr.Body[i] = el
+		}
+		return arr, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return &object.Null{}, nil
+		}
+		visiting, err := visiting.enter(v)
+		if err != nil {
+			return nil, err
+		}
+		defer visiting.leave(v)
+		keys := v.MapKeys()
+		slices.SortFunc(keys, compareKeys)
+
+		dic := object.NewDictionary()
+		for _, key := range keys {
+			k, err := reflectObject(key, visiting)
+			if err != nil {
+				return nil, err
+			}
+			hash, ok := k.(object.Hasher)
+			if !ok {
+				return nil, fmt.Errorf("engine: cannot use %s as a dictionary key", key.Type())
+			}
+			val, err := reflectObject(v.MapIndex(key), visiting)
+			if err != nil {
+				return nil, err
+			}
+			dic.Set(hash, val)
+		}
+		return dic, nil
+	}
+	return nil, fmt.Errorf("engine: cannot convert %s to an object", v.Type())
+}
+
+// compareKeys orders map keys by value, numbers numerically and everything else by its text.
+func compareKeys(a, b reflect.Value) int {
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmpOrdered(a.Int(), b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return cmpOrdered(a.Uint(), b.Uint())
+	case reflect.Float32, reflect.Float64:
+		return cmpOrdered(a.Float(), b.Float())
+	case reflect.String:
+		return strings.Compare(a.String(), b.String())
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func cmpOrdered[T int64 | uint64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// hostObject exposes a pointer to a Go struct. Its exported fields and methods are its properties,
+// a field holding a struct is exposed in place so assigning to its fields changes the original.
+type hostObject struct {
+	ptr reflect.Value
+}
+
+func (h *hostObject) Type() object.ObjectType { return object.HOST_OBJECT }
+func (h *hostObject) String() string          { return fmt.Sprintf("%+v", h.ptr.Elem().Interface()) }
+
+func (h *hostObject) Property(name string) (object.Object, bool) {
+	if method := h.ptr.MethodByName(name); method.IsValid() {
+		return hostFunction(name, method), true
+	}
+	field, ok := h.field(name)
+	if !ok {
+		return nil, false
+	}
+	if field.Kind() == reflect.Struct {
+		return &hostObject{ptr: field.Addr()}, true
+	}
+	val, err := reflectObject(field, nil)
+	if err != nil {
+		return nil, false
+	}
+	return val, true
+}
+
+// PropertyNames returns the exported fields in the order they are declared, an embedded struct
+// is replaced by its fields.
+func (h *hostObject) PropertyNames() []string {
+	var names []string
+	for _, sf := range reflect.VisibleFields(h.ptr.Elem().Type()) {
+		if sf.Anonymous || !sf.IsExported() {
+			continue
+		}
+		names = append(names, sf.Name)
+	}
+	return names
+}
+
+func (h *hostObject) Value() any { return h.ptr.Interface() }
+
+func (h *hostObject) SetProperty(name string, val object.Object) *object.Error {
+	field, ok := h.field(name)
+	if !ok || !field.CanSet() {
+		return typeError("Cannot set property %s of %s", name, h.ptr.Type())
+	}
+	v, err := toValue(val, field.Type())
+	if err != nil {
+		return typeError("%s.%s: %s", h.ptr.Elem().Type().Name(), name, err)
+	}
+	field.Set(v)
+	return nil
+}
+
+// field returns the exported field name, ok is false when it does not exist or sits behind a nil embedded pointer.
+func (h *hostObject) field(name string) (reflect.Value, bool) {
+	sf, ok := h.ptr.Elem().Type().FieldByName(name)
+	if !ok || !sf.IsExported() {
+		return reflect.Value{}, false
+	}
+	field, err := h.ptr.Elem().FieldByIndexErr(sf.Index)
+	return field, err == nil
+}
+
+// hostFunction wraps a Go func into a builtin. The arguments are converted to the parameter types,
+// a parameter of type object.Object takes the argument as is and a first parameter of type
+// object.Caller takes the engine calling it. An argument that cannot be converted is a TypeError.
+// A func returning a non nil error as its last result throws it, a func with more than one
+// other result returns them as an array.
+func hostFunction(name string, fn reflect.Value) *object.BuiltIn {
+	t := fn.Type()
+	takesCaller := t.NumIn() > 0 && t.In(0) == callerType
+	arity := t.NumIn()
+	if takesCaller {
+		arity--
+	}
+	if t.IsVariadic() {
+		arity--
+	}
+
+	return &object.BuiltIn{
+		Name:  name,
+		Arity: arity,
+		Function: func(caller object.Caller, args ...object.Object) (result object.Object) {
+			in := make([]reflect.Value, 0, t.NumIn())
+			first := 0
+			if takesCaller {
+				in = append(in, reflect.ValueOf(caller))
+				first = 1
+			}
+			fixed := t.NumIn()
+			if t.IsVariadic() {
+				fixed--
+			}
+			for p := first; p < fixed; p++ {
+				v, err := toValue(argOrNull(args, p-first), t.In(p))
+				if err != nil {
+					return typeError("%s: argument %d: %s", name, p-first+1, err)
+				}
+				in = append(in, v)
+			}
+			if t.IsVariadic() {
+				elem := t.In(fixed).Elem()
+				for i := fixed - first; i < len(args); i++ {
+					v, err := toValue(args[i], elem)
+					if err != nil {
+						return typeError("%s: argument %d: %s", name, i+1, err)
+					}
+					in = append(in, v)
+				}
+			}
+
+			defer func() {
+				if r := recover(); r != nil {
+					result = &object.Error{Message: fmt.Sprintf("Error: %s: %v", name, r)}
+				}
+			}()
+			return callResult(name, fn.Call(in))
+		},
+	}
+}
+
+func callResult(name string, out []reflect.Value) object.Object {
+	if len(out) > 0 && out[len(out)-1].Type() == errorType {
+		if err, _ := out[len(out)-1].Interface().(error); err != nil {
+			return &object.Error{Message: "Error: " + err.Error(), Err: err}
+		}
+		out = out[:len(out)-1]
+	}
+
+	results := make([]object.Object, len(out))
+	for i, v := range out {
+		obj, err := reflectObject(v, nil)
+		if err != nil {
+			return typeError("%s: result %d: %s", name, i+1, err)
+		}
+		results[i] = obj
+	}
+	switch len(results) {
+	case 0:
+		return nil
+	case 1:
+		return results[0]
+	}
+	return &object.Array{Body: results}
+}
+
+// toValue converts obj to a value of type t, the reverse of reflectObject. An integer
+// parameter takes a float with no fraction, null is the zero value of a nilable type.
+func toValue(obj object.Object, t reflect.Type) (reflect.Value, error) {
+	if obj == nil {
+		obj = &object.Null{}
+	}
+	if t.Kind() == reflect.Interface && t.NumMethod() == 0 {
+		goValue, err := FromObject(obj)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if goValue == nil {
+			return reflect.Zero(t), nil
+		}
+		return reflect.ValueOf(goValue), nil
+	}
+	if reflect.TypeOf(obj).AssignableTo(t) {
+		return reflect.ValueOf(obj), nil
+	}
+	if host, ok := obj.(*hostObject); ok {
+		switch {
+		case host.ptr.Type().AssignableTo(t):
+			return host.ptr, nil
+		case host.ptr.Elem().Type().AssignableTo(t):
+			return host.ptr.Elem(), nil
+		}
+	}
+
+	v := reflect.New(t).Elem()
+	switch obj := obj.(type) {
+	case *object.Null:
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map, reflect.Func:
+			return v, nil
+		}
+	case *object.Boolean:
+		if t.Kind() == reflect.Bool {
+			v.SetBool(obj.Value)
+			return v, nil
+		}
+	case *object.String:
+		if t.Kind() == reflect.String {
+			v.SetString(obj.Value)
+			return v, nil
+		}
+	case *object.Number, *object.Float:
+		if setNumber(v, obj) {
+			return v, nil
+		}
+	case *object.Array:
+		if t.Kind() == reflect.Slice {
+			v = reflect.MakeSlice(t, len(obj.Body), len(obj.Body))
+			for i, el := range obj.Body {
+				el, err := toValue(el, t.Elem())
+				if err != nil {
+					return reflect.Value{}, fmt.Errorf("element %d: %w", i, err)
+				}
+				v.Index(i).Set(el)
+			}
+			return v, nil
+		}
+	case *object.Dictionary:
+		switch t.Kind() {
+		case reflect.Map:
+			v = reflect.MakeMapWithSize(t, obj.Len())
+			for _, pair := range obj.Pairs() {
+				key, err := toValue(pair.Key, t.Key())
+				if err != nil {
+					return reflect.Value{}, fmt.Errorf("key %s: %w", pair.Key, err)
+				}
+				val, err := toValue(pair.Value, t.Elem())
+				if err != nil {
+					return reflect.Value{}, fmt.Errorf("key %s: %w", pair.Key, err)
+				}
+				v.SetMapIndex(key, val)
+			}
+			return v, nil
+		case reflect.Struct:
+			host := &hostObject{ptr: v.Addr()}
+			for _, pair := range obj.Pairs() {
+				key := object.ToString(pair.Key)
+				field, ok := host.field(key)
+				if !ok || !field.CanSet() {
+					continue
+				}
+				val, err := toValue(pair.Value, field.Type())
+				if err != nil {
+					return reflect.Value{}, fmt.Errorf("field %s: %w", key, err)
+				}
+				field.Set(val)
+			}
+			return v, nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("cannot use %s as %s", describe(obj), t)
+}
+
+// setNumber stores num in v when v is numeric and holds it exactly, an integer kind only takes integers.
+func setNumber(v reflect.Value, num object.Object) bool {
+	var f float64
+	switch num := num.(type) {
+	case *object.Number:
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if v.OverflowInt(num.Value) {
+				return false
+			}
+			v.SetInt(num.Value)
+			return true
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if num.Value < 0 || v.OverflowUint(uint64(num.Value)) {
+				return false
+			}
+			v.SetUint(uint64(num.Value))
+			return true
+		}
+		f = float64(num.Value)
+	case *object.Float:
+		f = num.Value
+	}
+
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(f)
+		return true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if f != math.Trunc(f) || math.IsInf(f, 0) || math.Abs(f) >= 1<<63 {
+			return false
+		}
+		return setNumber(v, &object.Number{Value: int64(f)})
+	}
+	return false
+}
+
+func describe(obj object.Object) string {
+	if str, ok := obj.(*object.String); ok {
+		return strconv.Quote(str.Value)
+	}
+	return object.ToString(obj)
+}
+
+func typeError(format string, args ...any) *object.Error {
+	return &object.Error{Message: "TypeError: " + fmt.Sprintf(format, args...)}
+}
+
+func argOrNull(args []object.Object, i int) object.Object {
+	if i >= len(args) || args[i] == nil {
+		return &object.Null{}
+	}
+	return args[i]
+}
@@ -0,0 +1,202 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jf550-kent/jsgo/object"
+)
+
+type point struct {
+	X, Y   int
+	Label  string
+	hidden int
+}
+
+func (p point) Sum() int { return p.X + p.Y }
+
+func (p *point) Move(dx, dy int) { p.X += dx; p.Y += dy }
+
+type shape struct {
+	Name   string
+	Origin point
+	Tags   []string
+	Sizes  map[string]float64
+}
+
+type node struct {
+	Name string
+	Next *node
+}
+
+var errNegative = errors.New("negative input")
+
+func TestReflectBridge(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"p.X + p.Y;", int64(3)},
+		{"p.Label;", "start"},
+		{"p.hidden;", nil},
+		{"p.Sum();", int64(3)},
+		{"p.Move(10, 20); p.X;", int64(11)},
+		{"p.X = 5; p.Sum();", int64(7)},
+		{"s.Origin.X;", int64(1)},
+		{"s.Origin.X = 9; s.Origin.Sum();", int64(11)},
+		{"s.Tags.length;", int64(2)},
+		{"s.Tags.join();", "a,b"},
+		{`s.Sizes["w"];`, 2.5},
+		{"add(1, 2);", int64(3)},
+		{"add(1.0, 2);", int64(3)},
+		{"scale([1, 2], 1.5);", []any{1.5, 3.0}},
+		{`join("-", "a", "b", "c");`, "a-b-c"},
+		{`join("-");`, ""},
+		{"sqrt(16);", 4.0},
+		{"divmod(7, 2);", []any{int64(3), int64(1)}},
+		{"twice(function(x) { return x * 3; }, 2);", int64(18)},
+		{`area({"X": 2, "Y": 3});`, int64(6)},
+		{"origin(p);", int64(1)},
+		{"JSON.stringify(p);", `{"X":1,"Y":2,"Label":"start"}`},
+		{"JSON.stringify(s);", `{"Name":"square","Origin":{"X":1,"Y":2,"Label":""},"Tags":["a","b"],"Sizes":{"w":2.5}}`},
+		{"JSON.stringify([s.Origin, s.Origin]);", `[{"X":1,"Y":2,"Label":""},{"X":1,"Y":2,"Label":""}]`},
+	}
+
+	for _, engine := range engines {
+		for _, tt := range tests {
+			rt := newBridgeRuntime(t, engine)
+			got, err := FromObject(run(t, rt, tt.input))
+			if err != nil {
+				t.Fatalf("%s: %s", engine, err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("%s: %q wrong result. want=%#v got=%#v", engine, tt.input, tt.expected, got)
+			}
+		}
+	}
+}
+
+func TestReflectBridgeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`add("a", 1);`, `TypeError: add: argument 1: cannot use "a" as int`},
+		{"add(1.5, 1);", "TypeError: add: argument 1: cannot use 1.5 as int"},
+		{"add(1);", "TypeError: add: argument 2: cannot use null as int"},
+		{`scale([1, "x"], 2);`, `TypeError: scale: argument 1: element 1: cannot use "x" as float64`},
+		{`join("-", 1);`, "TypeError: join: argument 2: cannot use 1 as string"},
+		{"sqrt(-1);", "Error: negative input"},
+		{"boom();", "Error: boom: runtime failure"},
+		{`p.X = "a";`, `TypeError: point.X: cannot use "a" as int`},
+		{"p.Missing = 1;", "TypeError: Cannot set property Missing of *engine.point"},
+		{`p.Move("a", 1);`, `TypeError: Move: argument 1: cannot use "a" as int`},
+		{"JSON.stringify(n);", "TypeError: Converting circular structure to JSON"},
+		{"loop();", "TypeError: loop: result 1: engine: cannot convert a value that contains itself"},
+	}
+
+	for _, engine := range engines {
+		for _, tt := range tests {
+			rt := newBridgeRuntime(t, engine)
+			if err := rt.Compile(tt.input); err != nil {
+				t.Fatalf("%s: compile error: %s", engine, err)
+			}
+			_, err := rt.Run(context.Background())
+			if err == nil {
+				t.Errorf("%s: %q expected an error", engine, tt.input)
+				continue
+			}
+			if err.Error() != tt.expected {
+				t.Errorf("%s: %q wrong error. want=%q got=%q", engine, tt.input, tt.expected, err.Error())
+			}
+		}
+	}
+
+	rt := newBridgeRuntime(t, Tree)
+	_, err := rt.Call("sqrt", -1)
+	if !errors.Is(err, errNegative) {
+		t.Errorf("the error of the go func should be kept. got=%v", err)
+	}
+}
+
+func TestReflectSharesValues(t *testing.T) {
+	for _, engine := range engines {
+		p := &point{X: 1}
+		rt := New(engine)
+		if err := rt.Set("p", p); err != nil {
+			t.Fatal(err)
+		}
+		run(t, rt, "p.Move(1, 1); p.Label = \"moved\";")
+		if p.X != 2 || p.Y != 1 || p.Label != "moved" {
+			t.Errorf("%s: the script did not change the go value. got=%+v", engine, *p)
+		}
+
+		result, _ := rt.Get("p")
+		got, _ := FromObject(result)
+		if got != p {
+			t.Errorf("%s: FromObject should return the go pointer. got=%#v", engine, got)
+		}
+
+		m := map[int]string{10: "b", 2: "a"}
+		obj, err := ToObject(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := obj.String(); !strings.Contains(s, "2") || strings.Index(s, "2") > strings.Index(s, "10") {
+			t.Errorf("map keys should be sorted numerically. got=%s", s)
+		}
+	}
+}
+
+func newBridgeRuntime(t *testing.T, engine Engine) *Runtime {
+	t.Helper()
+
+	rt := New(engine)
+	globals := map[string]any{
+		"p": &point{X: 1, Y: 2, Label: "start"},
+		"s": shape{
+			Name:   "square",
+			Origin: point{X: 1, Y: 2},
+			Tags:   []string{"a", "b"},
+			Sizes:  map[string]float64{"w": 2.5},
+		},
+		"add": func(a, b int) int { return a + b },
+		"scale": func(xs []float64, by float64) []float64 {
+			for i := range xs {
+				xs[i] *= by
+			}
+			return xs
+		},
+		"join": func(sep string, parts ...string) string { return strings.Join(parts, sep) },
+		"sqrt": func(f float64) (float64, error) {
+			if f < 0 {
+				return 0, errNegative
+			}
+			return f / 4, nil
+		},
+		"divmod": func(a, b int) (int, int) { return a / b, a % b },
+		"twice": func(caller object.Caller, fn object.Object, x int) object.Object {
+			return caller.Call(fn, caller.Call(fn, &object.Number{Value: int64(x)}))
+		},
+		"area":   func(p point) int { return p.X * p.Y },
+		"origin": func(p *point) int { return p.X },
+		"boom":   func() { panic("runtime failure") },
+		"loop": func() map[string]any {
+			m := map[string]any{}
+			m["m"] = m
+			return m
+		},
+	}
+	n := &node{Name: "a"}
+	n.Next = &node{Name: "b", Next: n}
+	globals["n"] = n
+	for name, value := range globals {
+		if err := rt.Set(name, value); err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+	}
+	return rt
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/jf550-kent/jsgo/ast"
 	"github.com/jf550-kent/jsgo/object"
+	"github.com/jf550-kent/jsgo/token"
 )
 
 var (
@@ -14,21 +15,50 @@ var (
 )
 
 func Eval(main *ast.Main, debug bool) object.Object {
+	return EvalWithBuiltins(main, object.Builtins, debug)
+}
+
+// EvalWithBuiltins evaluates main with the globals of builtins instead of object.Builtins.
+func EvalWithBuiltins(main *ast.Main, builtins *object.Registry, debug bool) object.Object {
 	if debug {
 		main = Partial(main)
 	}
-	obj := eval(main, object.NewEnvironment())
+	obj := eval(main, object.NewEnvironmentWithBuiltins(builtins))
 	err, ok := obj.(*object.Error)
 	if ok {
-		panic(err.Error())
+		panic(err.StackTrace())
 	}
 	return obj
 }
 
+// EvalIn evaluates main in env and returns the *object.Error of a failing program instead of
+// panicking, so a host can keep env between programs.
+func EvalIn(main *ast.Main, env *object.Environment) object.Object {
+	return eval(main, env)
+}
+
+// Call calls a function or builtin of the tree engine with args, bounded by the limits of env.
+func Call(env *object.Environment, fn object.Object, args ...object.Object) object.Object {
+	return callFunction(fn, args, env.Limits(), token.Pos{})
+}
+
+// eval evaluates node, an error raised by node itself is located at its start.
 func eval(node ast.Node, env *object.Environment) object.Object {
+	obj := evalNode(node, env)
+	if err, ok := obj.(*object.Error); ok {
+		err.At(node.Start())
+	}
+	return obj
+}
+
+func evalNode(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	case *ast.Main:
-		return evalMain(node.Statements, env)
+		result := evalMain(node.Statements, env)
+		if err, ok := result.(*object.Error); ok && err.File == "" {
+			err.File = node.Name
+		}
+		return result
 	case *ast.VarStatement:
 		val := eval(node.Expression, env)
 		if isError(val) {
@@ -81,32 +111,26 @@ func eval(node ast.Node, env *object.Environment) object.Object {
 		if isError(right) {
 			return right
 		}
-		return evalBinaryExpression(left, right, node.Operator)
+		return evalBinaryExpression(left, right, node.Operator, env.Limits())
 
 	case *ast.FunctionDeclaration:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Body: body, Env: env}
+		return &object.Function{Parameters: params, Body: body, Env: env, Name: node.Name, Pos: node.Token.Start}
 
 	case *ast.CallExpression:
 		args := evalExpressions(node.Arguments, env)
 		if len(args) == 1 && isError(args[0]) {
 			return args[0]
 		}
-		if index, ok := node.Function.(*ast.Index); ok {
-			right := eval(index.Index, env)
-			if isError(right) {
-				return right
-			}
-			if method, ok := right.(*object.String); ok {
-				return evalArrayMethodEpression(index, method, args, env)
-			}
-		}
 		function := eval(node.Function, env)
 		if isError(function) {
 			return function
 		}
-		return callFunction(function, args)
+		if ident, ok := node.Function.(*ast.Identifier); ok && !object.IsCallable(function) {
+			return object.NotFunctionError(function, ident.Literal)
+		}
+		return callFunction(function, args, env.Limits(), node.Start())
 	case *ast.ForStatement:
 		return evalForStatement(node, env)
 	case *ast.BlockStatement:
@@ -114,6 +138,9 @@ func eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.IFExpression:
 		return evalIfExpression(node, env)
 	case *ast.ReturnStatement:
+		if node.ReturnExpression == nil {
+			return &object.ReturnValue{Value: NULL}
+		}
 		val := eval(node.ReturnExpression, env)
 		if isError(val) {
 			return val
@@ -122,6 +149,9 @@ func eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.String:
 		return &object.String{Value: node.Value}
 	case *ast.Array:
+		if err := env.Limits().AllocValues(len(node.Body)); err != nil {
+			return err
+		}
 		body := evalExpressions(node.Body, env)
 		if len(body) == 1 && isError(body[0]) {
 			return body[0]
@@ -137,12 +167,22 @@ func eval(node ast.Node, env *object.Environment) object.Object {
 			return index
 		}
 		return evalIndexExpression(ident, index)
+	case *ast.Member:
+		ident := eval(node.Identifier, env)
+		if isError(ident) {
+			return ident
+		}
+		return evalMemberExpression(ident, node.Property.Literal)
 	case *ast.Null:
 		return NULL
 	case *ast.Dictionary:
 		return evalDictionary(node, env)
 	case *ast.BracketDeclaration:
 		return evalDictionaryDeclaration(node, env)
+	case *ast.DeleteExpression:
+		return evalDeleteExpression(node, env)
+	case *ast.NewExpression:
+		return eval(node.Call, env)
 	}
 	return nil
 }
@@ -176,23 +216,70 @@ func evalExpressions(epxs []ast.Expression, env *object.Environment) []object.Ob
 	return result
 }
 
-func callFunction(fn object.Object, args []object.Object) object.Object {
+// callFunction calls fn with args, limits are the limits of the caller passed on to builtins.
+// call is where fn is called, an error leaving fn records it in its stack.
+func callFunction(fn object.Object, args []object.Object, limits *object.Limits, call token.Pos) object.Object {
 
 	switch fn := fn.(type) {
 	case *object.Function:
+		limits := fn.Env.Limits()
+		if err := limits.Step(); err != nil {
+			return object.NewError(err)
+		}
+		if !fn.Env.EnterCall(limits.MaxDepth()) {
+			return object.StackOverflowError(fn.Name, fn.Pos)
+		}
+		defer fn.Env.LeaveCall()
+
 		extendedEnv := extendFunctionEnv(fn, args)
-		evaluated := eval(fn.Body, extendedEnv)
-		return unwrapReturnValue(evaluated)
+		evaluated := unwrapReturnValue(eval(fn.Body, extendedEnv))
+		if err, ok := evaluated.(*object.Error); ok {
+			err.Called(fn.Name, call)
+		}
+		return evaluated
 	case *object.BuiltIn:
-		return fn.Function(args...)
+		return builtinResult(fn.Name, fn.Function(evalCaller{limits}, args...), call)
+	case *object.BoundMethod:
+		return builtinResult(fn.Method.Name, fn.Call(evalCaller{limits}, args...), call)
 	}
-	return newError("not a function: %s", fn.Type())
+	return object.NotFunctionError(fn, "")
+}
+
+// evalCaller lets builtins call back into the functions of the program.
+type evalCaller struct {
+	limits *object.Limits
+}
+
+func (c evalCaller) Call(fn object.Object, args ...object.Object) object.Object {
+	return callFunction(fn, args, c.limits, token.Pos{})
+}
+
+func (c evalCaller) Limits() *object.Limits {
+	return c.limits
+}
+
+// builtinResult is NULL for a builtin that returns nothing. An error raised by a function
+// the builtin called back records the builtin, called at call, in its stack.
+func builtinResult(name string, result object.Object, call token.Pos) object.Object {
+	switch result := result.(type) {
+	case nil:
+		return NULL
+	case *object.Error:
+		if result.Pos.Line != 0 {
+			result.Called(name, call)
+		}
+	}
+	return result
 }
 
 func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
 	env := object.NewEnclosedEnvironment(fn.Env)
 
 	for paramIdx, param := range fn.Parameters {
+		if paramIdx >= len(args) {
+			env.Set(param.Literal, NULL)
+			continue
+		}
 		env.Set(param.Literal, args[paramIdx])
 	}
 
@@ -236,7 +323,44 @@ func evalIfExpression(ie *ast.IFExpression, env *object.Environment) object.Obje
 	}
 }
 
-func evalBinaryExpression(left, right object.Object, op string) object.Object {
+// evalBinaryExpression applies op to left and right, a concatenated string is accounted in limits.
+func evalBinaryExpression(left, right object.Object, op string, limits *object.Limits) object.Object {
+	switch op {
+	case "==":
+		return nativeBoolean(object.LooseEqual(left, right))
+	case "!=":
+		return nativeBoolean(!object.LooseEqual(left, right))
+	case "===":
+		return nativeBoolean(object.StrictEqual(left, right))
+	case "!==":
+		return nativeBoolean(!object.StrictEqual(left, right))
+	}
+
+	if op == "+" {
+		left, right = object.ToPrimitive(left), object.ToPrimitive(right)
+		_, lString := left.(*object.String)
+		_, rString := right.(*object.String)
+		if lString || rString {
+			l, r := object.ToString(left), object.ToString(right)
+			if err := limits.AllocBytes(len(l) + len(r)); err != nil {
+				return err
+			}
+			return &object.String{Value: l + r}
+		}
+	}
+	if op == "<" || op == ">" {
+		left, right = object.ToPrimitive(left), object.ToPrimitive(right)
+		l, lString := left.(*object.String)
+		r, rString := right.(*object.String)
+		if lString && rString {
+			if op == "<" {
+				return nativeBoolean(object.CompareStrings(l.Value, r.Value) < 0)
+			}
+			return nativeBoolean(object.CompareStrings(l.Value, r.Value) > 0)
+		}
+	}
+	left, right = object.ToNumber(left), object.ToNumber(right)
+
 	lType := left.Type()
 	rType := right.Type()
 	switch {
@@ -248,12 +372,6 @@ func evalBinaryExpression(left, right object.Object, op string) object.Object {
 		l := object.ConvertFloat(left)
 		r := object.ConvertFloat(right)
 		return evalFloatExpression(l, r, op)
-	case op == "!=":
-		return nativeBoolean(left != right)
-	case op == "==":
-		return nativeBoolean(left == right)
-	case left.Type() != right.Type():
-		return newError("type mismatch: %s %s %s", left.Type(), op, right.Type())
 	}
 
 	return newError("unknown operator: %s %s %s", left.Type(), op, right.Type())
@@ -280,6 +398,9 @@ func evalForStatement(forStmt *ast.ForStatement, env *object.Environment) object
 			panic("for loop does not contain condition : is the middle bit :)")
 		}
 
+		if err := env.Limits().Step(); err != nil {
+			return object.NewError(err)
+		}
 		condition := eval(forStmt.Condition, env)
 		if isError(condition) {
 			return condition
@@ -322,23 +443,16 @@ func evalNumberExpression(left, right object.Object, op string) object.Object {
 	case "+":
 		return &object.Number{Value: leftValue.Value + rightValue.Value}
 	case "/":
-		reminder := leftValue.Value % rightValue.Value
-		if reminder != 0 {
+		if rightValue.Value == 0 || leftValue.Value%rightValue.Value != 0 {
 			return &object.Float{Value: float64(leftValue.Value) / float64(rightValue.Value)}
 		}
 		return &object.Number{Value: leftValue.Value / rightValue.Value}
-	case "<<":
-		return &object.Number{Value: leftValue.Value << rightValue.Value}
-	case "^":
-		return &object.Number{Value: leftValue.Value ^ rightValue.Value}
+	case "<<", "^":
+		return evalBitwiseExpression(left, right, op)
 	case "<":
 		return nativeBoolean(leftValue.Value < rightValue.Value)
 	case ">":
 		return nativeBoolean(leftValue.Value > rightValue.Value)
-	case "==":
-		return nativeBoolean(leftValue.Value == rightValue.Value)
-	case "!=":
-		return nativeBoolean(leftValue.Value != rightValue.Value)
 	}
 	return newError("unknown operator: %s %s %s", left.Type(), op, right.Type())
 }
@@ -361,32 +475,34 @@ func evalFloatExpression(left, right object.Object, op string) object.Object {
 		return &object.Float{Value: leftValue.Value + rightValue.Value}
 	case "/":
 		return &object.Float{Value: leftValue.Value / rightValue.Value}
+	case "<<", "^":
+		return evalBitwiseExpression(left, right, op)
 	case "<":
 		return nativeBoolean(leftValue.Value < rightValue.Value)
 	case ">":
 		return nativeBoolean(leftValue.Value > rightValue.Value)
-	case "==":
-		return nativeBoolean(leftValue.Value == rightValue.Value)
-	case "!=":
-		return nativeBoolean(leftValue.Value != rightValue.Value)
 	}
 	return newError("unknown operator: %s %s %s", left.Type(), op, right.Type())
 }
 
-func evalBangOperatorExpression(right object.Object) object.Object {
-	switch right {
-	case TRUE:
-		return FALSE
-	case FALSE:
-		return TRUE
-	case NULL:
-		return TRUE
+// evalBitwiseExpression applies a bitwise operator on the operands wrapped to 32 bit integers.
+func evalBitwiseExpression(left, right object.Object, op string) object.Object {
+	l, r := object.ToInt32(left), object.ToInt32(right)
+	switch op {
+	case "<<":
+		return &object.Number{Value: int64(l << (uint32(r) & 31))}
+	case "^":
+		return &object.Number{Value: int64(l ^ r)}
 	}
-	return FALSE
+	return newError("unknown operator: %s %s %s", left.Type(), op, right.Type())
+}
+
+func evalBangOperatorExpression(right object.Object) object.Object {
+	return nativeBoolean(!object.ToBoolean(right))
 }
 
 func evalNegativeOperatorExpression(exp object.Object) object.Object {
-	switch r := exp.(type) {
+	switch r := object.ToNumber(exp).(type) {
 	case *object.Number:
 		return &object.Number{Value: -r.Value}
 	case *object.Float:
@@ -396,16 +512,7 @@ func evalNegativeOperatorExpression(exp object.Object) object.Object {
 }
 
 func isTruthy(obj object.Object) bool {
-	switch obj {
-	case NULL:
-		return false
-	case TRUE:
-		return true
-	case FALSE:
-		return false
-	default:
-		return true
-	}
+	return object.ToBoolean(obj)
 }
 
 func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
@@ -413,7 +520,7 @@ func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object
 		return val
 	}
 
-	if val, ok := builtin[node.Literal]; ok {
+	if val, ok := env.Builtin(node.Literal); ok {
 		return val
 	}
 	return newError("identifier not found: " + node.Literal)
@@ -425,51 +532,69 @@ func evalIndexExpression(left, index object.Object) object.Object {
 		return evalArrayIndexExpression(left, index)
 	case *object.Dictionary:
 		return evalDictionaryExpression(left, index)
+	case *object.String:
+		return evalStringIndexExpression(left, index)
+	}
+	if name, ok := index.(*object.String); ok {
+		return evalMemberExpression(left, name.Value)
 	}
 	return newError("index operator not supported: %s", left.Type())
 }
 
+// evalMemberExpression evaluates obj.name, a missing property evaluates to NULL.
+func evalMemberExpression(obj object.Object, name string) object.Object {
+	if dic, ok := obj.(*object.Dictionary); ok {
+		return evalDictionaryExpression(dic, &object.String{Value: name})
+	}
+	if _, ok := obj.(*object.Null); ok {
+		return newError("cannot read property %s of null", name)
+	}
+	if val, ok := object.Member(obj, name); ok {
+		return val
+	}
+	return NULL
+}
+
 func evalArrayIndexExpression(arr *object.Array, index object.Object) object.Object {
 	size := int64(len(arr.Body))
 
 	switch right := index.(type) {
-	case *object.Number:
-		idx := right.Value
+	case *object.Number, *object.Float:
+		idx, ok := object.IntegerIndex(right)
+		if !ok {
+			break
+		}
 		if idx < 0 || idx >= size {
 			return NULL
 		}
 		return arr.Body[idx]
 	case *object.String:
-		if right.Value == "length" {
-			return &object.Number{Value: size}
-		}
-		return newError("array index unsupported for " + right.Value)
+		return evalMemberExpression(arr, right.Value)
 	}
 	return newError("array index unsupported for type: " + index.String())
 }
 
-func evalArrayMethodEpression(index *ast.Index, method *object.String, args []object.Object, env *object.Environment) object.Object {
-	ident := eval(index.Identifier, env)
-	if isError(ident) {
-		return ident
-	}
-	arr, ok := ident.(*object.Array)
-	if !ok {
-		return newError("unsupported array call")
-	}
-	switch method.Value {
-	case "push":
-		arr.Body = append(arr.Body, args...)
+// evalStringIndexExpression evaluates s[i] to the UTF-16 code unit at i as a one unit string.
+func evalStringIndexExpression(str *object.String, index object.Object) object.Object {
+	switch right := index.(type) {
+	case *object.Number:
+		unit, ok := str.CodeUnitAt(int(right.Value))
+		if !ok {
+			return NULL
+		}
+		return &object.String{Value: object.StringFromCodeUnits([]uint16{unit})}
+	case *object.String:
+		return evalMemberExpression(str, right.Value)
 	}
-	return arr
+	return newError("string index unsupported for type: " + index.String())
 }
 
 func evalDictionary(dic *ast.Dictionary, env *object.Environment) object.Object {
 
-	dicry := &object.Dictionary{Value: make(map[object.Hash]object.KeyValue)}
+	dicry := object.NewDictionary()
 
-	for key, val := range dic.Object {
-		if _, err := assignDictionaryKey(dicry, key, val, env); err != nil {
+	for _, key := range dic.Keys {
+		if _, err := assignDictionaryKey(dicry, key, dic.Object[key], env); err != nil {
 			return err
 		}
 	}
@@ -490,9 +615,13 @@ func assignDictionaryKey(dic *object.Dictionary, key, value ast.Node, env *objec
 	if isError(v) {
 		return nil, v.(*object.Error)
 	}
+	if _, ok := dic.Get(h); !ok {
+		if err := env.Limits().AllocEntries(1); err != nil {
+			return nil, err
+		}
+	}
 
-	keyHash := h.Hash()
-	dic.Value[keyHash] = object.KeyValue{Key: k, Value: v}
+	dic.Set(h, v)
 
 	return dic, nil
 }
@@ -512,23 +641,43 @@ func evalDictionaryDeclaration(decl *ast.BracketDeclaration, env *object.Environ
 	case *object.Array:
 		in := eval(decl.Key, env)
 		if isError(in) {
-			newError("unable to evaluate array index")
+			return in
 		}
-		num, ok := in.(*object.Number)
-		if !ok {
-			return newError("wrong type for array index")
+		v := eval(decl.Value, env)
+		if isError(v) {
+			return v
+		}
+		index, err := object.ArrayIndex(in)
+		if err != nil {
+			return err
 		}
-		if int(num.Value) >= len(left.Body) {
+		if index >= len(left.Body) {
+			if err := env.Limits().AllocValues(index + 1 - len(left.Body)); err != nil {
+				return err
+			}
 			// a more efficient assignment can be done here
-			newArr := make([]object.Object, int(num.Value)+1)
+			newArr := make([]object.Object, index+1)
 			copy(newArr, left.Body)
+			// the elements between the old end and index are null
+			for i := len(left.Body); i < index; i++ {
+				newArr[i] = NULL
+			}
 			left.Body = newArr
 		}
+		left.Body[index] = v
+		return left
+	case object.HostObject:
+		key := eval(decl.Key, env)
+		if isError(key) {
+			return key
+		}
 		v := eval(decl.Value, env)
 		if isError(v) {
-			return newError("unable to eval array assigment")
+			return v
+		}
+		if err := left.SetProperty(object.ToString(key), v); err != nil {
+			return err
 		}
-		left.Body[int(num.Value)] = v
 		return left
 	}
 
@@ -542,12 +691,51 @@ func evalDictionaryExpression(dic *object.Dictionary, right object.Object) objec
 		return newError("cannot use " + right.String() + "as dictionary index")
 	}
 
-	hashKey := key.Hash()
-	keyValue, ok := dic.Value[hashKey]
+	val, ok := dic.Get(key)
 	if !ok {
 		return NULL
 	}
-	return keyValue.Value
+	return val
+}
+
+// evalDeleteExpression removes the property from a dictionary, deleting an array element leaves NULL in its place.
+func evalDeleteExpression(del *ast.DeleteExpression, env *object.Environment) object.Object {
+	var ident, key object.Object
+	switch target := del.Expression.(type) {
+	case *ast.Index:
+		ident = eval(target.Identifier, env)
+		if isError(ident) {
+			return ident
+		}
+		key = eval(target.Index, env)
+		if isError(key) {
+			return key
+		}
+	case *ast.Member:
+		ident = eval(target.Identifier, env)
+		if isError(ident) {
+			return ident
+		}
+		key = &object.String{Value: target.Property.Literal}
+	default:
+		return newError("delete of unsupported expression %s", del.Expression)
+	}
+
+	switch left := ident.(type) {
+	case *object.Dictionary:
+		h, ok := key.(object.Hasher)
+		if !ok {
+			return newError("cannot use " + key.String() + " as dictionary key")
+		}
+		left.Delete(h)
+		return TRUE
+	case *object.Array:
+		if idx, ok := key.(*object.Number); ok && idx.Value >= 0 && idx.Value < int64(len(left.Body)) {
+			left.Body[idx.Value] = NULL
+		}
+		return TRUE
+	}
+	return newError("cannot delete property of %s", ident.Type())
 }
 
 func newError(format string, a ...interface{}) *object.Error {
@@ -1,7 +1,12 @@
 package evaluator
 
 import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/jf550-kent/jsgo/benchmark"
@@ -136,6 +141,45 @@ func TestEvalBooleanExpression(t *testing.T) {
 	}
 }
 
+func TestEquality(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{`var a = "x"; var b = "x"; a == b;`, true},
+		{`"1" == 1`, true},
+		{`"1" === 1`, false},
+		{`1 === 1.0`, true},
+		{`1 !== 2`, true},
+		{`true == 1`, true},
+		{`true === 1`, false},
+		{`false == "0"`, true},
+		{`false == ""`, true},
+		{`null == null`, true},
+		{`null === null`, true},
+		{`null == 0`, false},
+		{`null == false`, false},
+		{`"" == 0`, true},
+		{`" 12 " == 12`, true},
+		{`"abc" == 0`, false},
+		{`[1, 2] == "1,2"`, true},
+		{`[1] == 1`, true},
+		{`[1] == [1]`, false},
+		{`var a = [1]; a === a;`, true},
+		{`{"a": 1} == "[object Object]"`, true},
+		{`0.1 + 0.2 == 0.3`, false},
+		{`"b" != "a"`, true},
+	}
+
+	for _, tt := range tests {
+		evaluated := evalSetup(tt.input)
+		testValue(t, evaluated, tt.expected)
+
+		main := Partial(parser.Parse("", []byte(tt.input)))
+		testValue(t, eval(main, object.NewEnvironment()), tt.expected)
+	}
+}
+
 func TestBangOperator(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -206,24 +250,348 @@ func TestReturnStatements(t *testing.T) {
 	}
 }
 
+func TestEmptyReturn(t *testing.T) {
+	tests := []string{
+		"var a = function() { return; }; a();",
+		"var a = function() { return }; a();",
+		"var a = function() { return\n 3; }; a();",
+	}
+
+	for _, input := range tests {
+		evaluated := evalSetup(input)
+		testNullObject(t, evaluated)
+	}
+}
+
+func TestCoercion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"5 + true;", 6},
+		{"-true;", -1},
+		{"true + false;", 1},
+		{"true - false;", 1},
+		{"5 + null;", 5},
+		{`"5" * "2";`, 10},
+		{`"6" / 4;`, 1.5},
+		{`"3" - 1;`, 2},
+		{`1 + "2";`, "12"},
+		{`"a" + 1.5;`, "a1.5"},
+		{`"a" + null;`, "anull"},
+		{`"a" + true;`, "atrue"},
+		{`[1, 2] + "";`, "1,2"},
+		{`[1, 2] + [3];`, "1,23"},
+		{`{} + "";`, "[object Object]"},
+		{`1 + 2 + "3";`, "33"},
+		{`"1" + 2 + 3;`, "123"},
+		{`"10" > 9;`, true},
+		{`1 / 0 > 1000000;`, true},
+		{`0 / 0 == 0 / 0;`, false},
+		{"1 << 32;", 1},
+		{"1.5 ^ 3;", 2},
+		{"if (0) { 1 } else { 2 };", 2},
+		{`if ("") { 1 } else { 2 };`, 2},
+		{`if ("0") { 1 } else { 2 };`, 1},
+		{"if ([]) { 1 } else { 2 };", 1},
+		{"if (0.0) { 1 } else { 2 };", 2},
+		{"!0;", true},
+		{`!"";`, true},
+		{"!{};", false},
+		{"!null;", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := evalSetup(tt.input)
+		testValue(t, evaluated, tt.expected)
+
+		main := Partial(parser.Parse("", []byte(tt.input)))
+		testValue(t, eval(main, object.NewEnvironment()), tt.expected)
+	}
+}
+
+func TestStringOperations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`"foo" + "bar";`, "foobar"},
+		{`"n" + 1 + 2;`, "n12"},
+		{`"a" < "b";`, true},
+		{`"B" < "a";`, true},
+		{`"abc" > "ab";`, true},
+		{`"abc" < "abc";`, false},
+		{`"10" < "9";`, true},
+		{`"\uFFFF" < "😀";`, false},
+		{`"abc".length;`, 3},
+		{`"héllo".length;`, 5},
+		{`"😀".length;`, 2},
+		{`"".length;`, 0},
+		{`var s = "xyz"; s.length;`, 3},
+		{`"abc"[1];`, "b"},
+		{`"héllo"[1];`, "é"},
+		{`"abc"["length"];`, 3},
+		{`var s = "abc"; s[s.length - 1];`, "c"},
+		{`"abc"[5];`, nil},
+		{`"abc"[-1];`, nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := evalSetup(tt.input)
+		testValue(t, evaluated, tt.expected)
+
+		main := Partial(parser.Parse("", []byte(tt.input)))
+		testValue(t, eval(main, object.NewEnvironment()), tt.expected)
+	}
+}
+
+func TestStringMethods(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`"hello world".slice(6);`, "world"},
+		{`"hello world".slice(-5, -2);`, "wor"},
+		{`"hello".slice(3, 1);`, ""},
+		{`"hello".substring(4, 1);`, "ell"},
+		{`"hello".substring(-3);`, "hello"},
+		{`"a😀b".slice(1, 3);`, "😀"},
+		{`"banana".indexOf("an");`, 1},
+		{`"banana".indexOf("an", 2);`, 3},
+		{`"banana".indexOf("x");`, -1},
+		{`"banana".lastIndexOf("an");`, 3},
+		{`"banana".lastIndexOf("an", 2);`, 1},
+		{`"😀banana".indexOf("b");`, 2},
+		{`"banana".includes("nan");`, true},
+		{`"banana".includes("nab");`, false},
+		{`"banana".startsWith("ban");`, true},
+		{`"banana".startsWith("nan", 2);`, true},
+		{`"banana".endsWith("ana");`, true},
+		{`"banana".endsWith("ban", 3);`, true},
+		{`"a,b,,c".split(",").length;`, 4},
+		{`"a,b,,c".split(",")[3];`, "c"},
+		{`"a,b,c".split(",", 2).length;`, 2},
+		{`"abc".split("")[1];`, "b"},
+		{`"abc".split().length;`, 1},
+		{`"".split(",").length;`, 1},
+		{`"  hi \n".trim();`, "hi"},
+		{`"  hi ".trimStart();`, "hi "},
+		{`"  hi ".trimEnd();`, "  hi"},
+		{`"Hello".toUpperCase();`, "HELLO"},
+		{`"Hello".toLowerCase();`, "hello"},
+		{`"a-b-c".replace("-", "+");`, "a+b-c"},
+		{`"a-b-c".replaceAll("-", "+");`, "a+b+c"},
+		{`"abc".replace("b", "[$&]");`, "a[b]c"},
+		{`"abc".replace("b", "$$");`, "a$c"},
+		{`"ab".replaceAll("", "-");`, "-a-b-"},
+		{`"5".padStart(3, "0");`, "005"},
+		{`"abc".padStart(6, "12");`, "121abc"},
+		{`"abc".padEnd(5);`, "abc  "},
+		{`"abc".padEnd(2, "x");`, "abc"},
+		{`"ab".repeat(3);`, "ababab"},
+		{`"ab".repeat(0);`, ""},
+		{`"abc".charCodeAt(1);`, 98},
+		{`"😀".charCodeAt(0);`, 55357},
+		{`String.fromCharCode(104, 105);`, "hi"},
+		{`String.fromCharCode(55357, 56832);`, "😀"},
+		{`var s = "x"; s.padStart(2, "-") + s.repeat(2);`, "-xxx"},
+	}
+
+	for _, tt := range tests {
+		evaluated := evalSetup(tt.input)
+		testValue(t, evaluated, tt.expected)
+	}
+}
+
+func TestCollections(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`var m = new Map(); m.set(1, "a"); m.get(1);`, "a"},
+		{`var m = new Map(); m.set(1, "a").set(1.0, "b"); m.size;`, 1},
+		{`var m = new Map(); m.set("1", "a"); m.get(1);`, nil},
+		{`var k = [1]; var m = new Map(); m.set(k, 5); m.get(k);`, 5},
+		{`var m = new Map(); m.set([1], 5); m.get([1]);`, nil},
+		{`var f = function() {}; var m = new Map([[f, 1], [{}, 2]]); m.get(f);`, 1},
+		{`var m = new Map([[0 / 0, 1]]); m.get(0 / 0);`, 1},
+		{`var m = new Map([[null, 1]]); m.has(null);`, true},
+		{`var m = new Map([["b", 1], ["a", 2], ["c", 3]]); m.delete("a"); m.set("a", 4); m.keys().join();`, "b,c,a"},
+		{`var m = new Map([["x", 1]]); m.delete("x") + m.delete("x");`, 1},
+		{`var m = new Map([["x", 1], ["y", 2]]); m.clear(); m.size;`, 0},
+		{`new Map([["x", 1], ["y", 2]]).values().join();`, "1,2"},
+		{`new Map([["x", 1]]).entries()[0].join();`, "x,1"},
+		{`var m = new Map([["x", 1], ["y", 2]]); var out = []; m.forEach(function(v, k) { out.push(k + v); }); out.join();`, "x1,y2"},
+		{`var m = new Map([[1, 1]]); m.forEach(function(v, k) { if (k < 4) { m.set(k + 1, v) }; }); m.size;`, 4},
+		{`var m = new Map([[1, 1]]); new Map(m).get(1);`, 1},
+		{`var s = new Set([1, 2, 2, 3]); s.size;`, 3},
+		{`var s = new Set(); s.add(1).add(1.0).add("1"); s.size;`, 2},
+		{`var a = []; var s = new Set([a]); s.has(a) === !s.has([]);`, true},
+		{`var s = new Set([3, 1, 2]); s.delete(1); s.add(1); s.values().join();`, "3,2,1"},
+		{`new Set("hello").size;`, 4},
+		{`var s = new Set([1, 2]); var total = [0]; s.forEach(function(v, k) { total[0] = total[0] + v + k; }); total[0];`, 6},
+		{`new Set([1, 2]).entries()[1].join();`, "2,2"},
+		{`var s = new Set([1]); s.clear(); s.has(1);`, false},
+		{`var w = new WeakMap(); var k = {}; w.set(k, 1); w.get(k);`, 1},
+		{`var w = new WeakMap(); var k = {}; w.set(k, 1); w.delete(k); w.has(k);`, false},
+		{`new WeakMap().get({});`, nil},
+		{`var m = new Map(); m.set("a", 1); m["size"];`, 1},
+		{`new Map() + "";`, "[object Map]"},
+		{`JSON.stringify([new Set([1])]);`, "[{}]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := evalSetup(tt.input)
+		testValue(t, evaluated, tt.expected)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`JSON.parse("42");`, 42},
+		{`JSON.parse("1.5e2");`, 150.0},
+		{`JSON.parse("\"hi\"");`, "hi"},
+		{`JSON.parse("[1, [2, true], null]")[1][1];`, true},
+		{`JSON.parse("{\"b\": 1, \"a\": {\"c\": [3]}}").a.c[0];`, 3},
+		{`JSON.stringify(JSON.parse("{\"b\": 1, \"a\": 2, \"c\": 3}"));`, `{"b":1,"a":2,"c":3}`},
+		{`JSON.parse("{\"a\": 1, \"b\": 2}", function(k, v) { if (k == "a") { return v * 10; }; return v; }).a;`, 10},
+		{`JSON.parse("[1, 2]", function(k, v) { if (k == "") { return v.length; }; return v; });`, 2},
+		{`JSON.stringify(1);`, "1"},
+		{`JSON.stringify(1.5);`, "1.5"},
+		{`JSON.stringify(0 / 0);`, "null"},
+		{`JSON.stringify("a\"b\n");`, `"a\"b\n"`},
+		{`JSON.stringify(null);`, "null"},
+		{`JSON.stringify([1, "a", false, null]);`, `[1,"a",false,null]`},
+		{`JSON.stringify({"z": 1, "a": [2], "m": {}});`, `{"z":1,"a":[2],"m":{}}`},
+		{`var d = {"x": 1}; d["y"] = 2; delete d["x"]; d["x"] = 3; JSON.stringify(d);`, `{"y":2,"x":3}`},
+		{`JSON.stringify({"f": function() {}, "a": [function() {}]});`, `{"a":[null]}`},
+		{`JSON.stringify([1, [2]], null, 2);`, "[\n  1,\n  [\n    2\n  ]\n]"},
+		{`JSON.stringify({"a": 1, "b": []}, null, "--");`, "{\n--\"a\": 1,\n--\"b\": []\n}"},
+		{`JSON.stringify({"a": 1, "b": 2, "c": 3}, ["c", "a"]);`, `{"a":1,"c":3}`},
+		{`JSON.stringify({"a": 1, "b": "x"}, function(k, v) { if (k == "a") { return v + 1; }; return v; });`, `{"a":2,"b":"x"}`},
+		{`var shared = [1]; JSON.stringify([shared, shared]);`, "[[1],[1]]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := evalSetup(tt.input)
+		testValue(t, evaluated, tt.expected)
+	}
+}
+
+func TestMath(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"Math.floor(2.7);", 2},
+		{"Math.floor(-2.5);", -3},
+		{"Math.floor(5);", 5},
+		{"Math.ceil(2.1);", 3},
+		{"Math.round(2.5);", 3},
+		{"Math.round(-2.5);", -2},
+		{"Math.round(0.49999999999999994);", 0},
+		{"Math.trunc(-2.7);", -2},
+		{"Math.floor(1 / 0) > 1000;", true},
+		{`Math.floor("3.5");`, 3},
+		{"Math.abs(-4);", 4},
+		{"Math.abs(-4.5);", 4.5},
+		{"Math.sqrt(16);", 4.0},
+		{"Math.pow(2, 10);", 1024},
+		{"Math.pow(2, 0.5) == Math.sqrt(2);", true},
+		{"Math.pow(2, -1);", 0.5},
+		{"Math.pow(2.5, 2);", 6.25},
+		{"Math.min(3, 1.5, 2);", 1.5},
+		{"Math.max(3, 1.5, 2);", 3},
+		{"Math.max() < 0;", true},
+		{"Math.min(1, 0 / 0) == Math.min(1, 0 / 0);", false},
+		{"Math.sin(0);", 0.0},
+		{"Math.cos(0);", 1.0},
+		{"Math.tan(0);", 0.0},
+		{"Math.atan2(0, 1);", 0.0},
+		{"Math.log(1);", 0.0},
+		{"Math.exp(0);", 1.0},
+		{"Math.PI > 3.14 === Math.PI < 3.15;", true},
+		{"Math.E;", math.E},
+		{"var r = Math.random(); if (r > -1) { r < 1 } else { false };", true},
+		{"var floor = Math.floor; floor(9.9);", 9},
+	}
+
+	for _, tt := range tests {
+		evaluated := evalSetup(tt.input)
+		testValue(t, evaluated, tt.expected)
+	}
+
+	seeded := func() object.Object {
+		env := object.NewEnvironment()
+		limits := object.NewLimits(context.Background(), 0)
+		limits.SetRandom(rand.New(rand.NewSource(7)))
+		env.SetLimits(limits)
+		return eval(parser.Parse("", []byte("Math.random();")), env)
+	}
+	first := seeded()
+	testValue(t, seeded(), first.(*object.Float).Value)
+}
+
+func TestBuiltinCallback(t *testing.T) {
+	env := object.NewEnvironment()
+	env.Set("apply", &object.BuiltIn{
+		Name: "apply",
+		Function: func(caller object.Caller, args ...object.Object) object.Object {
+			return caller.Call(args[0], args[1:]...)
+		},
+	})
+
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"apply(function(x) { return x * 2; }, 21);", 42},
+		{"apply(function(a, b) { return b; }, 1);", nil},
+		{"apply(apply, function() { return 7; });", 7},
+		{"var n = 3; apply(function() { return [1, 2].map(function(x) { return x * n; }).join(); });", "3,6"},
+	}
+
+	for _, tt := range tests {
+		evaluated := eval(parser.Parse("", []byte(tt.input)), env)
+		testValue(t, evaluated, tt.expected)
+	}
+
+	evaluated := eval(parser.Parse("", []byte("apply(function() { return x; });")), env)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "identifier not found: x" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
 func TestErrorHandling(t *testing.T) {
 	tests := []struct {
 		input           string
 		expectedMessage string
 	}{
-		{"5 + true;", "type mismatch: NUMBER + BOOLEAN"},
-		{"5 + true; 5;", "type mismatch: NUMBER + BOOLEAN"},
-		{"-true;", "unable to minus value: -true for type: BOOLEAN"},
-		{"true + false;", "unknown operator: BOOLEAN + BOOLEAN"},
-		{"5; true + false; 5;", "unknown operator: BOOLEAN + BOOLEAN"},
-		{"if (10 > 1) { true + false; };", "unknown operator: BOOLEAN + BOOLEAN"},
+		{"var a = 5; a(); 5;", "not a function: a (NUMBER)"},
+		{"if (10 > 1) { true(); };", "not a function: BOOLEAN"},
 		{`if (10 > 1) {
 			if (10 > 1) {
-				return true - false;
+				return foobar;
 			};
 			return 1;
-		};`, "unknown operator: BOOLEAN - BOOLEAN"},
+		};`, "identifier not found: foobar"},
 		{"foobar;", "identifier not found: foobar"},
+		{`"ab".repeat(-1);`, "RangeError: Invalid count value: -1"},
+		{"[1, 2].map(function(x) { return x(); });", "not a function: x (NUMBER)"},
+		{"[].reduce(function(a, b) { return a; });", "TypeError: Reduce of empty array with no initial value"},
+		{`var a = [1]; a.push(a); JSON.stringify(a);`, "TypeError: Converting circular structure to JSON"},
+		{`var d = {}; d["self"] = {"d": d}; JSON.stringify(d);`, "TypeError: Converting circular structure to JSON"},
+		{`JSON.parse("");`, "SyntaxError: JSON.parse: unexpected EOF"},
+		{`JSON.parse("[1] 2");`, "SyntaxError: JSON.parse: unexpected data after the JSON value"},
+		{"new WeakMap().set(1, 2);", "TypeError: Invalid value used as weak map key"},
+		{"new Map([1]);", "TypeError: Iterator value 1 is not an entry object"},
 	}
 
 	for _, tt := range tests {
@@ -406,6 +774,88 @@ func TestArrayPush(t *testing.T) {
 	testValue(t, evaluated, 4)
 }
 
+func TestArrayMethod(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`var arr = [1, 3, 4]; arr.length;`, 3},
+		{`var arr = []; arr.push(9, 8);`, 2},
+		{`var arr = [1]; arr.push(2); arr.push(3); arr.length;`, 3},
+		{`var arr = [1]; arr.missing;`, nil},
+		{`var arr = [1, 2, 3]; arr.pop() + arr.length;`, 5},
+		{`var arr = []; arr.pop();`, nil},
+		{`var arr = [1, 2, 3]; arr.shift() + arr[0];`, 3},
+		{`var arr = [3]; arr.unshift(1, 2); arr.join("");`, "123"},
+		{`[1, 2, 3, 4].slice(1, -1).join();`, "2,3"},
+		{`var arr = [1, 2, 3, 4]; arr.splice(1, 2, "a").join() + ";" + arr.join();`, "2,3;1,a,4"},
+		{`var arr = [1, 2, 3]; arr.splice(1).length + arr.length;`, 3},
+		{`[1].concat([2, 3], 4).join();`, "1,2,3,4"},
+		{`[1, 2, 1].indexOf(1, 1);`, 2},
+		{`[1, 2].indexOf("1");`, -1},
+		{`[1, 0 / 0].includes(0 / 0);`, true},
+		{`[1, 2].includes(3);`, false},
+		{`[1, null, "a"].join("-");`, "1--a"},
+		{`[1, 2, 3].reverse().join();`, "3,2,1"},
+		{`[1, 2, 3].map(function(x) { return x * 2; }).join();`, "2,4,6"},
+		{`[5, 6].map(function(x, i) { return i; }).join();`, "0,1"},
+		{`[1, 2, 3, 4].filter(function(x) { return x > 2; }).join();`, "3,4"},
+		{`[1, 2, 3].reduce(function(acc, x) { return acc + x; });`, 6},
+		{`[1, 2, 3].reduce(function(acc, x) { return acc + x; }, "");`, "123"},
+		{`var sum = [0]; [1, 2, 3].forEach(function(x) { sum[0] = sum[0] + x; }); sum[0];`, 6},
+		{`[1, 2, 3].find(function(x) { return x > 1; });`, 2},
+		{`[1, 2, 3].find(function(x) { return x > 5; });`, nil},
+		{`[1, 2, 3].some(function(x) { return x == 2; });`, true},
+		{`[1, 2, 3].every(function(x) { return x > 1; });`, false},
+		{`[10, 9, 1].sort().join();`, "1,10,9"},
+		{`[3, 1, 2].sort(function(a, b) { return a - b; }).join();`, "1,2,3"},
+		{`var pairs = [[1, "a"], [0, "b"], [1, "c"], [0, "d"]];
+		pairs.sort(function(x, y) { return x[0] - y[0]; });
+		pairs.map(function(p) { return p[1]; }).join("");`, "bdac"},
+		{`var offset = 10; [1, 2].map(function(x) { return x + offset; }).join();`, "11,12"},
+		{`[[1, 2], [3]].map(function(row) { return row.map(function(x) { return x * 10; }).join(" "); }).join();`, "10 20,30"},
+		{`function() { var n = 2; return [1, 2].map(function(x) { return x * n; }).join(); }();`, "2,4"},
+	}
+
+	for _, tt := range tests {
+		evaluated := evalSetup(tt.input)
+		testValue(t, evaluated, tt.expected)
+	}
+}
+
+func TestMember(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`var node = {"next": {"value": 3}}; node.next.value;`, 3},
+		{`var node = {"value": 1}; node.value = 7; node.value;`, 7},
+		{`var node = {"value": 1}; node.missing;`, nil},
+		{`var list = {"items": [1, 2]}; list.items.push(3); list.items.length;`, 3},
+		{`var node = null; node.next;`, "cannot read property next of null"},
+		{`var x = 5; x.foo;`, nil},
+		{`var x = 1.5; x["foo"];`, nil},
+		{`var x = true; x.foo;`, nil},
+		{`[1].map(function(v) { return v.q; })[0];`, nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := evalSetup(tt.input)
+		if msg, ok := tt.expected.(string); ok {
+			err, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("expected error object. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if err.Message != msg {
+				t.Errorf("wrong error message. expected=%q, got=%q", msg, err.Message)
+			}
+			continue
+		}
+		testValue(t, evaluated, tt.expected)
+	}
+}
+
 func TestArrayFunctionCall(t *testing.T) {
 	input := `var add = function (a) { return a + a; }; var arr = [1, 3, 4, add]; arr[3](9);`
 	evaluated := evalSetup(input)
@@ -438,6 +888,50 @@ func TestDictionaryDeclaration(t *testing.T) {
 		expected any
 	}{
 		{`var apple = {"color": "red"}; apple["taste"] = "red"; apple["taste"];`, "red"},
+		{"var arr = [10]; arr[3] = 90; arr[1] == null;", true},
+		{"var arr = []; arr[Math.sqrt(4)] = 7; arr[2];", 7},
+		{"var arr = [1, 2, 3]; arr[4 / 2.0];", 3},
+	}
+
+	for _, tt := range tests {
+		evaluated := evalSetup(tt.input)
+		testValue(t, evaluated, tt.expected)
+	}
+}
+
+func TestDictionaryOrderAndKeys(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"b": 1, "a": 2, "c": 3}`, "{b: 1, a: 2, c: 3}"},
+		{`var d = {"b": 1}; d["a"] = 2; d["b"] = 3; d;`, "{b: 3, a: 2}"},
+		{`{1.2: 1, 1.7: 2}`, "{1.2: 1, 1.7: 2}"},
+		{`var d = {"a": 1, "b": 2, "c": 3}; delete d.b; d;`, "{a: 1, c: 3}"},
+		{`var d = {"a": 1, "b": 2}; delete d["a"]; d["a"] = 3; d;`, "{b: 2, a: 3}"},
+		{`var d = {}; d[1] = "a"; d[1.0] = "b"; d;`, "{1: b}"},
+		{`var d = {}; d[4 / 2] = "a"; d[2] = "b"; d[2.5] = "c"; delete d[2.0]; d;`, "{2.5: c}"},
+	}
+
+	for _, tt := range tests {
+		evaluated := evalSetup(tt.input)
+		dic := checkObject[*object.Dictionary](t, evaluated)
+		if dic.String() != tt.expected {
+			t.Errorf("wrong dictionary. expected=%s, got=%s", tt.expected, dic.String())
+		}
+	}
+}
+
+func TestDelete(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`var d = {1.2: 1, 1.7: 2}; d[1.7];`, 2},
+		{`var d = {"a": 1}; delete d.a;`, true},
+		{`var d = {"a": 1}; delete d.a; d.a;`, nil},
+		{`var d = {"a": 1}; delete d.missing; d.a;`, 1},
+		{`var arr = [1, 2]; delete arr[0]; arr[0];`, nil},
 	}
 
 	for _, tt := range tests {
@@ -522,3 +1016,234 @@ func testNullObject(t *testing.T, obj object.Object) bool {
 	}
 	return true
 }
+
+func TestHostBuiltins(t *testing.T) {
+	registry := object.NewStandardRegistry()
+	registry.Function("double", 1, func(_ object.Caller, args ...object.Object) object.Object {
+		return &object.Number{Value: args[0].(*object.Number).Value * 2}
+	})
+	registry.Function("second", 2, func(_ object.Caller, args ...object.Object) object.Object {
+		return args[1]
+	})
+	registry.Function("console.count", 0, func(_ object.Caller, args ...object.Object) object.Object {
+		return &object.Number{Value: int64(len(args))}
+	})
+	registry.Function("units.length.km", 1, func(_ object.Caller, args ...object.Object) object.Object {
+		return &object.Number{Value: args[0].(*object.Number).Value * 1000}
+	})
+	registry.Function("units.length.cm", 1, func(_ object.Caller, args ...object.Object) object.Object {
+		return &object.Number{Value: args[0].(*object.Number).Value / 100}
+	})
+
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"double(21);", 42},
+		{"second(1);", nil},
+		{"console.count(1, 2, 3);", 3},
+		{"var f = function() { return double(2); }; f();", 4},
+		{"var double = 1; double;", 1},
+		{"Math.floor(1.5);", 1},
+		{"units.length.km(2) + units.length.cm(300);", 2003},
+	}
+
+	for _, tt := range tests {
+		env := object.NewEnvironmentWithBuiltins(registry)
+		testValue(t, eval(parser.Parse("", []byte(tt.input)), env), tt.expected)
+	}
+
+	evaluated := evalSetup("double(1);")
+	if _, ok := evaluated.(*object.Error); !ok {
+		t.Errorf("expected double to be undefined with the default builtins. got=%T(%+v)", evaluated, evaluated)
+	}
+
+	// a namespace must be a builtin object and every part of the name is needed
+	for _, name := range []string{"Map.of", "console.log.twice", "a..b", "units."} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%q expected a panic", name)
+				}
+			}()
+			registry.Function(name, 0, func(_ object.Caller, args ...object.Object) object.Object { return nil })
+		}()
+	}
+	if m, _ := registry.Lookup("Map"); m != object.MapBuiltIn {
+		t.Errorf("Map was replaced by the failed registration. got=%v", m)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		input  string
+		budget int64
+		steps  int64
+		err    error
+	}{
+		{"for (var i = 0; i < 10; i = i + 1) { i; }", 0, 11, nil},
+		{"var f = function(n) { return n; }; f(1); f(2);", 0, 2, nil},
+		{"var f = function(n) { return n; }; [1, 2, 3].map(f);", 0, 3, nil},
+		{"for (;true;) { 1; }", 500, 501, object.ErrBudgetExceeded},
+		{"var f = function() { return f(); }; f();", 500, 501, object.ErrBudgetExceeded},
+		{"[1].map(function() { for (;true;) { 1; } });", 500, 501, object.ErrBudgetExceeded},
+		{"var f = function() { for (;true;) { 1; } }; var a = []; a[0] = f();", 500, 501, object.ErrBudgetExceeded},
+	}
+
+	for _, tt := range tests {
+		env := object.NewEnvironment()
+		limits := object.NewLimits(context.Background(), tt.budget)
+		env.SetLimits(limits)
+		evaluated := eval(parser.Parse("", []byte(tt.input)), env)
+		if limits.Steps() != tt.steps {
+			t.Errorf("%q wrong number of steps. want=%d got=%d", tt.input, tt.steps, limits.Steps())
+		}
+		if tt.err == nil {
+			continue
+		}
+		err, ok := evaluated.(*object.Error)
+		if !ok || !errors.Is(err, tt.err) {
+			t.Errorf("%q wrong error. want=%v got=%v", tt.input, tt.err, evaluated)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	env := object.NewEnvironment()
+	env.SetLimits(object.NewLimits(ctx, 0))
+	evaluated := eval(parser.Parse("", []byte("for (;true;) { 1; }")), env)
+	err, ok := evaluated.(*object.Error)
+	if !ok || !errors.Is(err, object.ErrInterrupted) || !errors.Is(err, context.Canceled) {
+		t.Errorf("expected an interrupted error wrapping context.Canceled. got=%v", evaluated)
+	}
+
+	// JSON.stringify checks the context while it writes a deep value
+	env = object.NewEnvironment()
+	eval(parser.Parse("", []byte("var a = []; for (var i = 0; i < 10000; i = i + 1) { a = [a]; }")), env)
+	env.SetLimits(object.NewLimits(ctx, 0))
+	evaluated = eval(parser.Parse("", []byte("JSON.stringify(a);")), env)
+	err, ok = evaluated.(*object.Error)
+	if !ok || !errors.Is(err, object.ErrInterrupted) {
+		t.Errorf("expected JSON.stringify to be interrupted. got=%v", evaluated)
+	}
+}
+
+func TestMemoryLimit(t *testing.T) {
+	tests := []struct {
+		input string
+		fails bool
+	}{
+		{"var a = []; a[100000000] = 1;", true},
+		{`var s = "ab"; s.repeat(100000000);`, true},
+		{`"a".padEnd(100000000);`, true},
+		{`var s = "abcdefgh"; for (var i = 0; i < 20; i = i + 1) { s = s + s; }`, true},
+		{"var d = {}; for (var i = 0; i < 100000; i = i + 1) { d[i] = i; }", true},
+		{"var s = new Set(); for (var i = 0; i < 100000; i = i + 1) { s.add(i); }", true},
+		{"var a = [1, 2]; [1].map(function() { for (var i = 0; i < 20; i = i + 1) { a = a.concat(a); } });", true},
+		{"var a = []; for (var i = 0; i < 2000000; i = i + 1) { a.push(i); }", true},
+		{"var a = [1]; for (var i = 0; i < 21; i = i + 1) { a = [a, a]; } JSON.stringify(a);", true},
+		{`JSON.parse("[" + "1,".repeat(100000) + "1]");`, true},
+		{`"a".repeat(100000).split("");`, true},
+		{"var a = []; a[30000] = 1; new Set(a);", true},
+		{"var a = []; a[40000] = 1; a.map(function(x) { return x; }).map(function(x) { return x; });", true},
+		{"var m = new Map(); for (var i = 0; i < 15000; i = i + 1) { m.set(i, i); } m.entries();", true},
+		{`var a = [1, 2, 3]; a[10] = 1; "x".repeat(100) + [1, 2].join("-");`, false},
+	}
+
+	for _, tt := range tests {
+		env := object.NewEnvironment()
+		limits := object.NewLimits(context.Background(), 0)
+		limits.SetMemoryLimit(1 << 20)
+		env.SetLimits(limits)
+		evaluated := eval(parser.Parse("", []byte(tt.input)), env)
+		err, isErr := evaluated.(*object.Error)
+		if !tt.fails {
+			if isErr {
+				t.Errorf("%q unexpected error: %s", tt.input, err)
+			}
+			continue
+		}
+		if !isErr || !errors.Is(err, object.ErrMemoryLimit) {
+			t.Errorf("%q expected the memory limit error. got=%v", tt.input, evaluated)
+			continue
+		}
+		if err.Message != "RangeError: memory limit of 1048576 bytes exceeded" {
+			t.Errorf("%q wrong error message. got=%q", tt.input, err.Message)
+		}
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	tests := []struct {
+		input    string
+		depth    int
+		expected string
+	}{
+		{"var f = function() { return f(); }; f();", 0, "RangeError: Maximum call stack size exceeded in f 1:9"},
+		{"var count = function(n) { if (n == 0) { return 0; } return 1 + count(n - 1); }; count(100);", 50, "RangeError: Maximum call stack size exceeded in count 1:13"},
+		{"[1].map(function(x) { var g = function() { return g(); }; return g(); });", 10, "RangeError: Maximum call stack size exceeded in g 1:31"},
+		{"var f = function() { return f(); }; var a = []; a[0] = f();", 0, "RangeError: Maximum call stack size exceeded in f 1:9"},
+	}
+
+	for _, tt := range tests {
+		env := object.NewEnvironment()
+		limits := object.NewLimits(context.Background(), 0)
+		limits.SetMaxDepth(tt.depth)
+		env.SetLimits(limits)
+		evaluated := eval(parser.Parse("", []byte(tt.input)), env)
+		err, ok := evaluated.(*object.Error)
+		if !ok || !errors.Is(err, object.ErrStackOverflow) {
+			t.Errorf("%q expected the stack overflow error. got=%v", tt.input, evaluated)
+			continue
+		}
+		if err.Message != tt.expected {
+			t.Errorf("%q wrong error message. want=%q got=%q", tt.input, tt.expected, err.Message)
+		}
+	}
+
+	input := "var count = function(n) { if (n == 0) { return 0; } return 1 + count(n - 1); }; count(50);"
+	testValue(t, evalSetup(input), 50)
+}
+
+func TestErrorStackTrace(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // the end of the stack trace
+	}{
+		{
+			"var inner = function(x) {\n  return x.y.z;\n};\nvar outer = function() {\n  return inner(null);\n};\nouter();",
+			"\n    at inner (main.js:2:10)\n    at outer (main.js:5:10)\n    at <main> (main.js:7:1)",
+		},
+		{
+			"var f = function(n) { return n.a.b; };\n[1, 2].map(function(x) {\n  return f(x);\n});",
+			"\n    at f (main.js:1:30)\n    at <anonymous> (main.js:3:10)\n    at map (native)\n    at <main> (main.js:2:1)",
+		},
+		{
+			"var a = 1;\n\"a\".repeat(-1);",
+			"RangeError: Invalid count value: -1\n    at <main> (main.js:2:1)",
+		},
+		{
+			"var a = 1;\na();",
+			"\n    at <main> (main.js:2:1)",
+		},
+		{"var x = x;", "identifier not found: x\n    at <main> (main.js:1:9)"},
+		{"var a = null;\na.foo;", "cannot read property foo of null\n    at <main> (main.js:2:1)"},
+		{"var a = 5;\nvar f = function() {\n  return a(1);\n};\nf();", "not a function: a (NUMBER)\n    at f (main.js:3:10)\n    at <main> (main.js:5:1)"},
+		{"var d = {\"x\": true};\nd.x();", "not a function: BOOLEAN\n    at <main> (main.js:2:1)"},
+		{"var a = 1;\n\"abc\".repeat(1000000000000000000);", "RangeError: Invalid string length\n    at <main> (main.js:2:1)"},
+		{"var a = 1;\n\"abc\".padEnd(10000000000);", "RangeError: Invalid string length\n    at <main> (main.js:2:1)"},
+		{"var a = [];\na[-1] = 5;", "RangeError: Invalid array index -1\n    at <main> (main.js:2:2)"},
+		{"var a = [];\na[1.5] = 5;", "RangeError: Invalid array index 1.5\n    at <main> (main.js:2:2)"},
+	}
+
+	for _, tt := range tests {
+		evaluated := eval(parser.Parse("main.js", []byte(tt.input)), object.NewEnvironment())
+		err, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Fatalf("%q expected an *object.Error. got=%T (%+v)", tt.input, evaluated, evaluated)
+		}
+		if trace := err.StackTrace(); !strings.HasSuffix(trace, tt.expected) {
+			t.Errorf("%q wrong stack trace. want suffix=%q got=%q", tt.input, tt.expected, trace)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/jf550-kent/jsgo/ast"
+	"github.com/jf550-kent/jsgo/object"
 )
 
 func Partial(main *ast.Main) *ast.Main {
@@ -33,6 +34,9 @@ func partialEvalStatement(stmt ast.Statement) ast.Statement {
 		s.Expression = e
 		return s
 	case *ast.ReturnStatement:
+		if s.ReturnExpression == nil {
+			return s
+		}
 		e := partialEvalExpression(s.ReturnExpression)
 		s.ReturnExpression = e
 		return s
@@ -70,6 +74,9 @@ func partialEvalExpression(exp ast.Expression) ast.Expression {
 	case *ast.Index:
 		e.Index = partialEvalExpression(e.Index)
 		return e
+	case *ast.NewExpression:
+		partialEvalExpression(e.Call)
+		return e
 	}
 
 	return exp
@@ -79,6 +86,16 @@ func partialEvalBinaryOperation(b *ast.BinaryExpression) ast.Expression {
 	left := partialEvalExpression(b.Left)
 	right := partialEvalExpression(b.Right)
 
+	switch b.Operator {
+	case "==", "!=", "===", "!==":
+		if folded, ok := partialEquality(left, right, b.Operator); ok {
+			return folded
+		}
+		b.Left = left
+		b.Right = right
+		return b
+	}
+
 	switch left := left.(type) {
 	case *ast.Number:
 		if right, ok := right.(*ast.Number); ok {
@@ -112,23 +129,19 @@ func partialNumber(left, right *ast.Number, b *ast.BinaryExpression) ast.Express
 	case "+":
 		return &ast.Number{Value: left.Value + right.Value}
 	case "/":
-		reminder := left.Value % right.Value
-		if reminder != 0 {
+		if right.Value == 0 || left.Value%right.Value != 0 {
 			return &ast.Float{Value: float64(left.Value) / float64(right.Value)}
 		}
 		return &ast.Number{Value: left.Value / right.Value}
 	case "<<":
-		return &ast.Number{Value: left.Value << right.Value}
+		l, r := int32(left.Value), uint32(right.Value)&31
+		return &ast.Number{Value: int64(l << r)}
 	case "^":
-		return &ast.Number{Value: left.Value ^ right.Value}
+		return &ast.Number{Value: int64(int32(left.Value) ^ int32(right.Value))}
 	case "<":
 		return &ast.Boolean{Value: left.Value < right.Value}
 	case ">":
 		return &ast.Boolean{Value: left.Value > right.Value}
-	case "==":
-		return &ast.Boolean{Value: left.Value == right.Value}
-	case "!=":
-		return &ast.Boolean{Value: left.Value != right.Value}
 	}
 	return b
 }
@@ -147,12 +160,49 @@ func partialFloat(left, right *ast.Float, b *ast.BinaryExpression) ast.Expressio
 		return &ast.Boolean{Value: left.Value < right.Value}
 	case ">":
 		return &ast.Boolean{Value: left.Value > right.Value}
+	}
+	return b
+}
+
+// partialEquality folds an equality between two literals with the same rules as the evaluator.
+func partialEquality(left, right ast.Expression, op string) (ast.Expression, bool) {
+	l, ok := literalObject(left)
+	if !ok {
+		return nil, false
+	}
+	r, ok := literalObject(right)
+	if !ok {
+		return nil, false
+	}
+
+	switch op {
 	case "==":
-		return &ast.Boolean{Value: left.Value == right.Value}
+		return &ast.Boolean{Value: object.LooseEqual(l, r)}, true
 	case "!=":
-		return &ast.Boolean{Value: left.Value != right.Value}
+		return &ast.Boolean{Value: !object.LooseEqual(l, r)}, true
+	case "===":
+		return &ast.Boolean{Value: object.StrictEqual(l, r)}, true
+	case "!==":
+		return &ast.Boolean{Value: !object.StrictEqual(l, r)}, true
 	}
-	return b
+	return nil, false
+}
+
+// literalObject returns the value of a primitive literal.
+func literalObject(exp ast.Expression) (object.Object, bool) {
+	switch exp := exp.(type) {
+	case *ast.Number:
+		return &object.Number{Value: exp.Value}, true
+	case *ast.Float:
+		return &object.Float{Value: exp.Value}, true
+	case *ast.String:
+		return &object.String{Value: exp.Value}, true
+	case *ast.Boolean:
+		return nativeBoolean(exp.Value), true
+	case *ast.Null:
+		return NULL, true
+	}
+	return nil, false
 }
 
 func partialEvalUnaryOperation(e *ast.UnaryExpression) ast.Expression {
@@ -168,31 +218,14 @@ func partialEvalUnaryOperation(e *ast.UnaryExpression) ast.Expression {
 }
 
 func buildBang(expr ast.Expression, e *ast.UnaryExpression) ast.Expression {
-	switch expr := expr.(type) {
-	case *ast.Boolean:
-		return &ast.Boolean{Value: !expr.Value}
-	case *ast.Number:
-		if expr.Value == 0 {
-			return &ast.Boolean{Value: true}
-		}
-		return &ast.Boolean{Value: false}
-	case *ast.Float:
-		if expr.Value == 0 {
-			return &ast.Boolean{Value: true}
-		}
-		return &ast.Boolean{Value: false}
-	case *ast.Null:
-		return &ast.Boolean{Value: true}
-	case *ast.Dictionary:
-		return &ast.Boolean{Value: false}
-	case *ast.Array:
+	if obj, ok := literalObject(expr); ok {
+		return &ast.Boolean{Value: !object.ToBoolean(obj)}
+	}
+	switch expr.(type) {
+	case *ast.Dictionary, *ast.Array:
 		return &ast.Boolean{Value: false}
-	case *ast.String:
-		if len(expr.Value) == 0 {
-			return &ast.Boolean{Value: true}
-		}
-		return &ast.Boolean{Value: true}
 	}
+	e.Expression = expr
 	return e
 }
 
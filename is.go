@@ -21,7 +21,7 @@ func check(node ast.Node) bool {
 		return true
 	case *ast.BinaryExpression:
 		switch node.Operator {
-		case "+", "-", "*", "/", "<<", "^", "<", ">", "==", "!=":
+		case "+", "-", "*", "/", "<<", "^", "<", ">", "==", "!=", "===", "!==":
 			return check(node.Left) && check(node.Right)
 		default:
 			return false
@@ -42,6 +42,12 @@ func check(node ast.Node) bool {
 		return checkBlockStatements(node.Body)
 	case *ast.Index:
 		return check(node.Identifier) && check(node.Index)
+	case *ast.Member:
+		return check(node.Identifier)
+	case *ast.DeleteExpression:
+		return check(node.Expression)
+	case *ast.NewExpression:
+		return check(node.Call)
 	case *ast.CallExpression:
 		if !check(node.Function) {
 			return false
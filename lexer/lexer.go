@@ -5,33 +5,41 @@ import (
 	"errors"
 	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/jf550-kent/jsgo/token"
 )
 
 // Lexer tokenization the source text for the language
 type Lexer struct {
-	src          []byte // source text for tokenization
+	src          string // source text for tokenization, held as a string so literals are sliced without copying
 	position     int    // current position at [Lexer.src]
 	nextPosition int    // next position to be lex at [Lexer.src]
 
 	line int // the current line at the source text
-	col  int // the current column at the source text
+	col  int // the current column at the source text, counted in runes
 
-	ch byte // the current byte at [Lexer.src]
+	ch rune // the current rune at [Lexer.src]
 }
 
 // New return a *Lexer
 func New(byt []byte) *Lexer {
-	l := &Lexer{src: byt, line: 1}
+	l := &Lexer{src: string(byt), line: 1}
 	l.next()
 	return l
 }
 
 // Lex return the next token in the [*Lexer]
 func (l *Lexer) Lex() (token.Token, error) {
+	newLine := l.skipWhitespace()
+	tok, err := l.lex()
+	tok.NewLine = newLine
+	return tok, err
+}
+
+func (l *Lexer) lex() (token.Token, error) {
 	var tok token.Token
-	l.skipWhitespace()
 	switch l.ch {
 	case '+':
 		pos := l.currentPos()
@@ -95,6 +103,11 @@ func (l *Lexer) Lex() (token.Token, error) {
 		start := l.currentPos()
 		if l.peekByte() == '=' {
 			l.next()
+			if l.peekByte() == '=' {
+				l.next()
+				tok = newToken(token.STRICT_NOT_EQUAL, "!==", start, l.currentPos())
+				break
+			}
 			end := l.currentPos()
 			tok = newToken(token.NOT_EQUAL, "!=", start, end)
 			break
@@ -104,6 +117,11 @@ func (l *Lexer) Lex() (token.Token, error) {
 		start := l.currentPos()
 		if l.peekByte() == '=' {
 			l.next()
+			if l.peekByte() == '=' {
+				l.next()
+				tok = newToken(token.STRICT_EQUAL, "===", start, l.currentPos())
+				break
+			}
 			end := l.currentPos()
 			tok = newToken(token.EQUAL, "==", start, end)
 			break
@@ -132,14 +150,12 @@ func (l *Lexer) Lex() (token.Token, error) {
 // getDigitToken returns either [token.Token.NUMBER] or [token.Token.FLOAT]
 // with its corresponding literal
 func (l *Lexer) getDigitToken() (token.Token, error) {
-	var digit strings.Builder
 	start := l.currentPos()
 	var end token.Pos
 	var err error
 	hasDot := false
 	for {
 		if l.isDigit() {
-			digit.WriteByte(l.ch)
 			end = l.currentPos()
 			l.next()
 			continue
@@ -149,29 +165,28 @@ func (l *Lexer) getDigitToken() (token.Token, error) {
 				err = errors.New("digit formatted incorrect at " + strconv.Itoa(l.position))
 			}
 			hasDot = true
-			digit.WriteByte(l.ch)
 			end = l.currentPos()
 			l.next()
 			continue
 		}
 		break
 	}
+	digit := l.src[start.Offset:l.position]
 	if hasDot {
-		return newToken(token.FLOAT, digit.String(), start, end), err
+		return newToken(token.FLOAT, digit, start, end), err
 	}
-	return newToken(token.NUMBER, digit.String(), start, end), err
+	return newToken(token.NUMBER, digit, start, end), err
 }
 
 // getLetter return the whole letter with the position
 func (l *Lexer) getLetter() (string, token.Pos) {
-	var letter strings.Builder
+	start := l.position
 	var end token.Pos
-	for l.isLetter() {
-		letter.WriteByte(l.ch)
+	for l.isLetter() || l.isDigit() || l.isIdentifierPart() {
 		end = l.currentPos()
 		l.next()
 	}
-	return letter.String(), end
+	return l.src[start:l.position], end
 }
 
 func (l *Lexer) readString() (token.Token, error) {
@@ -180,11 +195,12 @@ func (l *Lexer) readString() (token.Token, error) {
 	l.next()
 	start := l.position
 	// when is an empty string ""
-	if l.src[l.position] == '"' {
+	if l.ch == '"' {
 		endPos := l.currentPos()
 		lit := convertString(l.src[start:l.position])
 
 		tok := newToken(token.STRING, lit, starttPos, endPos)
+		l.next()
 		return tok, nil
 	}
 
@@ -208,14 +224,19 @@ func (l *Lexer) readString() (token.Token, error) {
 
 // next moves the current position of the char in [Lexer.data] to the next one
 // it will the [Lexer.ch] to 0 when [Lexer.position] is at the last byte of the [Lexer.src]
+// ASCII is read directly from [Lexer.src], only multi-byte characters are decoded as UTF-8.
 func (l *Lexer) next() {
-	if l.nextPosition == len(l.src) {
+	if l.nextPosition >= len(l.src) {
 		l.ch = 0
-		l.position = l.nextPosition
+		l.position = len(l.src)
 		return
 	}
 	l.position = l.nextPosition
-	l.ch = l.src[l.position]
+	ch, width := rune(l.src[l.position]), 1
+	if ch >= utf8.RuneSelf {
+		ch, width = utf8.DecodeRuneInString(l.src[l.position:])
+	}
+	l.ch = ch
 	switch l.ch {
 	case '\n':
 		l.col = 0
@@ -223,7 +244,7 @@ func (l *Lexer) next() {
 	default:
 		l.col++
 	}
-	l.nextPosition++
+	l.nextPosition += width
 }
 
 // peekByte returns the next byte in [Lexer.src] without
@@ -236,30 +257,58 @@ func (l *Lexer) peekByte() byte {
 	return l.src[l.nextPosition]
 }
 
-// skipWhitespace skips all the current whitespace in [Lexer.src]
-func (l *Lexer) skipWhitespace() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
+// skipWhitespace skips all the current whitespace in [Lexer.src] and
+// reports whether a line terminator was skipped.
+func (l *Lexer) skipWhitespace() bool {
+	newLine := false
+	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' || l.ch >= utf8.RuneSelf && unicode.IsSpace(l.ch) {
+		if isLineTerminator(l.ch) {
+			newLine = true
+		}
 		l.next()
 	}
+	return newLine
+}
+
+// isLineTerminator reports whether ch ends a line, which decides where a semicolon may be inserted.
+func isLineTerminator(ch rune) bool {
+	return ch == '\n' || ch == '\r' || ch == '\u2028' || ch == '\u2029'
 }
 
 func (l *Lexer) currentPos() token.Pos {
-	return token.Pos{Line: l.line, Col: l.col}
+	return token.Pos{Line: l.line, Col: l.col, Offset: l.position}
 }
 
 func newToken(typ token.TokenType, literal string, start, end token.Pos) token.Token {
 	return token.Token{TokenType: typ, Literal: literal, Start: start, End: end}
 }
 
+// isLetter reports whether [Lexer.ch] can start an identifier, which is
+// the Unicode ID_Start property plus _ and $.
 func (l *Lexer) isLetter() bool {
-	return 'a' <= l.ch && l.ch <= 'z' || l.ch == '_' || 'A' <= l.ch && l.ch <= 'Z' || l.ch == '.'
+	if l.ch < utf8.RuneSelf {
+		return 'a' <= l.ch && l.ch <= 'z' || l.ch == '_' || 'A' <= l.ch && l.ch <= 'Z' || l.ch == '$'
+	}
+	return unicode.In(l.ch, unicode.L, unicode.Nl, unicode.Other_ID_Start)
+}
+
+// isIdentifierPart reports whether the non ASCII [Lexer.ch] can continue an identifier,
+// which is the Unicode ID_Continue property minus the ID_Start already checked by isLetter.
+func (l *Lexer) isIdentifierPart() bool {
+	if l.ch < utf8.RuneSelf {
+		return false
+	}
+	return unicode.In(l.ch, unicode.Mn, unicode.Mc, unicode.Nd, unicode.Pc, unicode.Other_ID_Continue) || l.ch == '\u200c' || l.ch == '\u200d'
 }
 
 func (l *Lexer) isDigit() bool {
 	return '0' <= l.ch && l.ch <= '9'
 }
 
-func convertString(b []byte) string {
+func convertString(b string) string {
+	if strings.IndexByte(b, '\\') < 0 {
+		return b
+	}
 	var result strings.Builder
 
 	for i := 0; i < len(b); i++ {
@@ -286,7 +335,7 @@ func convertString(b []byte) string {
 			i++
 		case 'u':
 			if i+5 < len(b) {
-				hex := string(b[i+2 : i+6])
+				hex := b[i+2 : i+6]
 				codePoint, err := strconv.ParseInt(hex, 16, 32)
 				if err == nil {
 					result.WriteRune(rune(codePoint))
@@ -297,7 +346,7 @@ func convertString(b []byte) string {
 			}
 		case 'U':
 			if i+9 < len(b) {
-				hex := string(b[i+2 : i+10])
+				hex := b[i+2 : i+10]
 				codePoint, err := strconv.ParseInt(hex, 16, 32)
 				if err == nil {
 					result.WriteRune(rune(codePoint))
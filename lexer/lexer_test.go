@@ -31,7 +31,7 @@ func TestLexerLineAndCol(t *testing.T) {
 	
 	hh`
 	expected := []struct {
-		char byte
+		char rune
 		line int
 		col  int
 	}{
@@ -78,35 +78,37 @@ func TestLexSingleToken(t *testing.T) {
 		input         string
 		expectedToken token.Token
 	}{
-		{"+", token.Token{TokenType: token.ADD, Literal: "+", Start: token.Pos{Line: 1, Col: 1}, End: token.Pos{Line: 1, Col: 1}}},
-		{"-", token.Token{TokenType: token.MINUS, Literal: "-", Start: token.Pos{Line: 1, Col: 1}, End: token.Pos{Line: 1, Col: 1}}},
-		{"*", token.Token{TokenType: token.MUL, Literal: "*", Start: token.Pos{Line: 1, Col: 1}, End: token.Pos{Line: 1, Col: 1}}},
-		{"/", token.Token{TokenType: token.DIVIDE, Literal: "/", Start: token.Pos{Line: 1, Col: 1}, End: token.Pos{Line: 1, Col: 1}}},
-		{",", token.Token{TokenType: token.COMMA, Literal: ",", Start: token.Pos{Line: 1, Col: 1}, End: token.Pos{Line: 1, Col: 1}}},
-		{".", token.Token{TokenType: token.DOT, Literal: ".", Start: token.Pos{Line: 1, Col: 1}, End: token.Pos{Line: 1, Col: 1}}},
-		{":", token.Token{TokenType: token.COLON, Literal: ":", Start: token.Pos{Line: 1, Col: 1}, End: token.Pos{Line: 1, Col: 1}}},
-		{";", token.Token{TokenType: token.SEMICOLON, Literal: ";", Start: token.Pos{Line: 1, Col: 1}, End: token.Pos{Line: 1, Col: 1}}},
-		{"(", token.Token{TokenType: token.LPAREN, Literal: "(", Start: token.Pos{Line: 1, Col: 1}, End: token.Pos{Line: 1, Col: 1}}},
-		{")", token.Token{TokenType: token.RPAREN, Literal: ")", Start: token.Pos{Line: 1, Col: 1}, End: token.Pos{Line: 1, Col: 1}}},
-		{"{", token.Token{TokenType: token.LBRACE, Literal: "{", Start: token.Pos{Line: 1, Col: 1}, End: token.Pos{Line: 1, Col: 1}}},
-		{"}", token.Token{TokenType: token.RBRACE, Literal: "}", Start: token.Pos{Line: 1, Col: 1}, End: token.Pos{Line: 1, Col: 1}}},
-		{"=", token.Token{TokenType: token.ASSIGN, Literal: "=", Start: token.Pos{Line: 1, Col: 1}, End: token.Pos{Line: 1, Col: 1}}},
-		{"!", token.Token{TokenType: token.BANG, Literal: "!", Start: token.Pos{Line: 1, Col: 1}, End: token.Pos{Line: 1, Col: 1}}},
-		{"!=", token.Token{TokenType: token.NOT_EQUAL, Literal: "!=", Start: token.Pos{Line: 1, Col: 1}, End: token.Pos{Line: 1, Col: 2}}},
-		{"==", token.Token{TokenType: token.EQUAL, Literal: "==", Start: token.Pos{Line: 1, Col: 1}, End: token.Pos{Line: 1, Col: 2}}},
-		{"", token.Token{TokenType: token.EOF, Literal: "EOF", Start: token.Pos{Line: 1, Col: 0}, End: token.Pos{Line: 1, Col: 0}}},
-		{"89", token.Token{TokenType: token.NUMBER, Literal: "89", Start: token.Pos{Line: 1, Col: 1}, End: token.Pos{Line: 1, Col: 2}}},
-		{"hello", token.Token{TokenType: token.IDENT, Literal: "hello", Start: token.Pos{Line: 1, Col: 1}, End: token.Pos{Line: 1, Col: 5}}},
-		{"89.2", token.Token{TokenType: token.FLOAT, Literal: "89.2", Start: token.Pos{Line: 1, Col: 1}, End: token.Pos{Line: 1, Col: 4}}},
-		{"var", token.Token{TokenType: token.VAR, Literal: "var", Start: token.Pos{Line: 1, Col: 1}, End: token.Pos{Line: 1, Col: 3}}},
-		{"function", token.Token{TokenType: token.FUNCTION, Literal: "function", Start: token.Pos{Line: 1, Col: 1}, End: token.Pos{Line: 1, Col: 8}}},
-		{"if", token.Token{TokenType: token.IF, Literal: "if", Start: token.Pos{Line: 1, Col: 1}, End: token.Pos{Line: 1, Col: 2}}},
-		{"else", token.Token{TokenType: token.ELSE, Literal: "else", Start: token.Pos{Line: 1, Col: 1}, End: token.Pos{Line: 1, Col: 4}}},
-		{"elseif", token.Token{TokenType: token.ELSEIF, Literal: "elseif", Start: token.Pos{Line: 1, Col: 1}, End: token.Pos{Line: 1, Col: 6}}},
-		{"return", token.Token{TokenType: token.RETURN, Literal: "return", Start: token.Pos{Line: 1, Col: 1}, End: token.Pos{Line: 1, Col: 6}}},
-		{"false", token.Token{TokenType: token.FALSE, Literal: "false", Start: token.Pos{Line: 1, Col: 1}, End: token.Pos{Line: 1, Col: 5}}},
-		{"true", token.Token{TokenType: token.TRUE, Literal: "true", Start: token.Pos{Line: 1, Col: 1}, End: token.Pos{Line: 1, Col: 4}}},
-		{`"hello"`, token.Token{TokenType: token.STRING, Literal: "hello", Start: token.Pos{Line: 1, Col: 1}, End: token.Pos{Line: 1, Col: 7}}},
+		{"+", token.Token{TokenType: token.ADD, Literal: "+", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 1, Offset: 0}}},
+		{"-", token.Token{TokenType: token.MINUS, Literal: "-", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 1, Offset: 0}}},
+		{"*", token.Token{TokenType: token.MUL, Literal: "*", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 1, Offset: 0}}},
+		{"/", token.Token{TokenType: token.DIVIDE, Literal: "/", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 1, Offset: 0}}},
+		{",", token.Token{TokenType: token.COMMA, Literal: ",", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 1, Offset: 0}}},
+		{".", token.Token{TokenType: token.DOT, Literal: ".", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 1, Offset: 0}}},
+		{":", token.Token{TokenType: token.COLON, Literal: ":", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 1, Offset: 0}}},
+		{";", token.Token{TokenType: token.SEMICOLON, Literal: ";", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 1, Offset: 0}}},
+		{"(", token.Token{TokenType: token.LPAREN, Literal: "(", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 1, Offset: 0}}},
+		{")", token.Token{TokenType: token.RPAREN, Literal: ")", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 1, Offset: 0}}},
+		{"{", token.Token{TokenType: token.LBRACE, Literal: "{", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 1, Offset: 0}}},
+		{"}", token.Token{TokenType: token.RBRACE, Literal: "}", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 1, Offset: 0}}},
+		{"=", token.Token{TokenType: token.ASSIGN, Literal: "=", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 1, Offset: 0}}},
+		{"!", token.Token{TokenType: token.BANG, Literal: "!", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 1, Offset: 0}}},
+		{"!=", token.Token{TokenType: token.NOT_EQUAL, Literal: "!=", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 2, Offset: 1}}},
+		{"==", token.Token{TokenType: token.EQUAL, Literal: "==", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 2, Offset: 1}}},
+		{"!==", token.Token{TokenType: token.STRICT_NOT_EQUAL, Literal: "!==", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 3, Offset: 2}}},
+		{"===", token.Token{TokenType: token.STRICT_EQUAL, Literal: "===", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 3, Offset: 2}}},
+		{"", token.Token{TokenType: token.EOF, Literal: "EOF", Start: token.Pos{Line: 1, Col: 0, Offset: 0}, End: token.Pos{Line: 1, Col: 0, Offset: 0}}},
+		{"89", token.Token{TokenType: token.NUMBER, Literal: "89", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 2, Offset: 1}}},
+		{"hello", token.Token{TokenType: token.IDENT, Literal: "hello", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 5, Offset: 4}}},
+		{"89.2", token.Token{TokenType: token.FLOAT, Literal: "89.2", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 4, Offset: 3}}},
+		{"var", token.Token{TokenType: token.VAR, Literal: "var", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 3, Offset: 2}}},
+		{"function", token.Token{TokenType: token.FUNCTION, Literal: "function", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 8, Offset: 7}}},
+		{"if", token.Token{TokenType: token.IF, Literal: "if", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 2, Offset: 1}}},
+		{"else", token.Token{TokenType: token.ELSE, Literal: "else", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 4, Offset: 3}}},
+		{"elseif", token.Token{TokenType: token.ELSEIF, Literal: "elseif", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 6, Offset: 5}}},
+		{"return", token.Token{TokenType: token.RETURN, Literal: "return", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 6, Offset: 5}}},
+		{"false", token.Token{TokenType: token.FALSE, Literal: "false", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 5, Offset: 4}}},
+		{"true", token.Token{TokenType: token.TRUE, Literal: "true", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 4, Offset: 3}}},
+		{`"hello"`, token.Token{TokenType: token.STRING, Literal: "hello", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 7, Offset: 6}}},
 	}
 
 	for _, test := range tests {
@@ -184,93 +186,93 @@ func TestLexSourceFile(t *testing.T) {
 
 	tests := []token.Token{
 		// Line 1
-		{TokenType: token.VAR, Literal: "var", Start: token.Pos{Line: 1, Col: 1}, End: token.Pos{Line: 1, Col: 3}},
-		{TokenType: token.IDENT, Literal: "num", Start: token.Pos{Line: 1, Col: 5}, End: token.Pos{Line: 1, Col: 7}},
-		{TokenType: token.ASSIGN, Literal: "=", Start: token.Pos{Line: 1, Col: 9}, End: token.Pos{Line: 1, Col: 9}},
-		{TokenType: token.NUMBER, Literal: "89", Start: token.Pos{Line: 1, Col: 11}, End: token.Pos{Line: 1, Col: 12}},
-		{TokenType: token.SEMICOLON, Literal: ";", Start: token.Pos{Line: 1, Col: 13}, End: token.Pos{Line: 1, Col: 13}},
+		{TokenType: token.VAR, Literal: "var", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 3, Offset: 2}},
+		{TokenType: token.IDENT, Literal: "num", Start: token.Pos{Line: 1, Col: 5, Offset: 4}, End: token.Pos{Line: 1, Col: 7, Offset: 6}},
+		{TokenType: token.ASSIGN, Literal: "=", Start: token.Pos{Line: 1, Col: 9, Offset: 8}, End: token.Pos{Line: 1, Col: 9, Offset: 8}},
+		{TokenType: token.NUMBER, Literal: "89", Start: token.Pos{Line: 1, Col: 11, Offset: 10}, End: token.Pos{Line: 1, Col: 12, Offset: 11}},
+		{TokenType: token.SEMICOLON, Literal: ";", Start: token.Pos{Line: 1, Col: 13, Offset: 12}, End: token.Pos{Line: 1, Col: 13, Offset: 12}},
 
 		// Line 2
-		{TokenType: token.VAR, Literal: "var", Start: token.Pos{Line: 2, Col: 1}, End: token.Pos{Line: 2, Col: 3}},
-		{TokenType: token.IDENT, Literal: "add", Start: token.Pos{Line: 2, Col: 5}, End: token.Pos{Line: 2, Col: 7}},
-		{TokenType: token.ASSIGN, Literal: "=", Start: token.Pos{Line: 2, Col: 9}, End: token.Pos{Line: 2, Col: 9}},
-		{TokenType: token.FUNCTION, Literal: "function", Start: token.Pos{Line: 2, Col: 11}, End: token.Pos{Line: 2, Col: 18}},
-		{TokenType: token.LPAREN, Literal: "(", Start: token.Pos{Line: 2, Col: 19}, End: token.Pos{Line: 2, Col: 19}},
-		{TokenType: token.IDENT, Literal: "a", Start: token.Pos{Line: 2, Col: 20}, End: token.Pos{Line: 2, Col: 20}},
-		{TokenType: token.COMMA, Literal: ",", Start: token.Pos{Line: 2, Col: 21}, End: token.Pos{Line: 2, Col: 21}},
-		{TokenType: token.IDENT, Literal: "b", Start: token.Pos{Line: 2, Col: 23}, End: token.Pos{Line: 2, Col: 23}},
-		{TokenType: token.RPAREN, Literal: ")", Start: token.Pos{Line: 2, Col: 24}, End: token.Pos{Line: 2, Col: 24}},
-		{TokenType: token.LBRACE, Literal: "{", Start: token.Pos{Line: 2, Col: 26}, End: token.Pos{Line: 2, Col: 26}},
+		{TokenType: token.VAR, Literal: "var", Start: token.Pos{Line: 2, Col: 1, Offset: 14}, End: token.Pos{Line: 2, Col: 3, Offset: 16}, NewLine: true},
+		{TokenType: token.IDENT, Literal: "add", Start: token.Pos{Line: 2, Col: 5, Offset: 18}, End: token.Pos{Line: 2, Col: 7, Offset: 20}},
+		{TokenType: token.ASSIGN, Literal: "=", Start: token.Pos{Line: 2, Col: 9, Offset: 22}, End: token.Pos{Line: 2, Col: 9, Offset: 22}},
+		{TokenType: token.FUNCTION, Literal: "function", Start: token.Pos{Line: 2, Col: 11, Offset: 24}, End: token.Pos{Line: 2, Col: 18, Offset: 31}},
+		{TokenType: token.LPAREN, Literal: "(", Start: token.Pos{Line: 2, Col: 19, Offset: 32}, End: token.Pos{Line: 2, Col: 19, Offset: 32}},
+		{TokenType: token.IDENT, Literal: "a", Start: token.Pos{Line: 2, Col: 20, Offset: 33}, End: token.Pos{Line: 2, Col: 20, Offset: 33}},
+		{TokenType: token.COMMA, Literal: ",", Start: token.Pos{Line: 2, Col: 21, Offset: 34}, End: token.Pos{Line: 2, Col: 21, Offset: 34}},
+		{TokenType: token.IDENT, Literal: "b", Start: token.Pos{Line: 2, Col: 23, Offset: 36}, End: token.Pos{Line: 2, Col: 23, Offset: 36}},
+		{TokenType: token.RPAREN, Literal: ")", Start: token.Pos{Line: 2, Col: 24, Offset: 37}, End: token.Pos{Line: 2, Col: 24, Offset: 37}},
+		{TokenType: token.LBRACE, Literal: "{", Start: token.Pos{Line: 2, Col: 26, Offset: 39}, End: token.Pos{Line: 2, Col: 26, Offset: 39}},
 
 		// Line 3
-		{TokenType: token.RETURN, Literal: "return", Start: token.Pos{Line: 3, Col: 3}, End: token.Pos{Line: 3, Col: 8}},
-		{TokenType: token.IDENT, Literal: "a", Start: token.Pos{Line: 3, Col: 10}, End: token.Pos{Line: 3, Col: 10}},
-		{TokenType: token.ADD, Literal: "+", Start: token.Pos{Line: 3, Col: 12}, End: token.Pos{Line: 3, Col: 12}},
-		{TokenType: token.IDENT, Literal: "b", Start: token.Pos{Line: 3, Col: 14}, End: token.Pos{Line: 3, Col: 14}},
-		{TokenType: token.SEMICOLON, Literal: ";", Start: token.Pos{Line: 3, Col: 15}, End: token.Pos{Line: 3, Col: 15}},
+		{TokenType: token.RETURN, Literal: "return", Start: token.Pos{Line: 3, Col: 3, Offset: 43}, End: token.Pos{Line: 3, Col: 8, Offset: 48}, NewLine: true},
+		{TokenType: token.IDENT, Literal: "a", Start: token.Pos{Line: 3, Col: 10, Offset: 50}, End: token.Pos{Line: 3, Col: 10, Offset: 50}},
+		{TokenType: token.ADD, Literal: "+", Start: token.Pos{Line: 3, Col: 12, Offset: 52}, End: token.Pos{Line: 3, Col: 12, Offset: 52}},
+		{TokenType: token.IDENT, Literal: "b", Start: token.Pos{Line: 3, Col: 14, Offset: 54}, End: token.Pos{Line: 3, Col: 14, Offset: 54}},
+		{TokenType: token.SEMICOLON, Literal: ";", Start: token.Pos{Line: 3, Col: 15, Offset: 55}, End: token.Pos{Line: 3, Col: 15, Offset: 55}},
 
 		// Line 4
-		{TokenType: token.RBRACE, Literal: "}", Start: token.Pos{Line: 4, Col: 1}, End: token.Pos{Line: 4, Col: 1}},
-		{TokenType: token.SEMICOLON, Literal: ";", Start: token.Pos{Line: 4, Col: 2}, End: token.Pos{Line: 4, Col: 2}},
+		{TokenType: token.RBRACE, Literal: "}", Start: token.Pos{Line: 4, Col: 1, Offset: 57}, End: token.Pos{Line: 4, Col: 1, Offset: 57}, NewLine: true},
+		{TokenType: token.SEMICOLON, Literal: ";", Start: token.Pos{Line: 4, Col: 2, Offset: 58}, End: token.Pos{Line: 4, Col: 2, Offset: 58}},
 
 		// Line 6
-		{TokenType: token.VAR, Literal: "var", Start: token.Pos{Line: 6, Col: 1}, End: token.Pos{Line: 6, Col: 3}},
-		{TokenType: token.IDENT, Literal: "foo", Start: token.Pos{Line: 6, Col: 5}, End: token.Pos{Line: 6, Col: 7}},
-		{TokenType: token.ASSIGN, Literal: "=", Start: token.Pos{Line: 6, Col: 9}, End: token.Pos{Line: 6, Col: 9}},
-		{TokenType: token.FUNCTION, Literal: "function", Start: token.Pos{Line: 6, Col: 11}, End: token.Pos{Line: 6, Col: 18}},
-		{TokenType: token.LPAREN, Literal: "(", Start: token.Pos{Line: 6, Col: 19}, End: token.Pos{Line: 6, Col: 19}},
-		{TokenType: token.IDENT, Literal: "a", Start: token.Pos{Line: 6, Col: 20}, End: token.Pos{Line: 6, Col: 20}},
-		{TokenType: token.COMMA, Literal: ",", Start: token.Pos{Line: 6, Col: 21}, End: token.Pos{Line: 6, Col: 21}},
-		{TokenType: token.IDENT, Literal: "func", Start: token.Pos{Line: 6, Col: 23}, End: token.Pos{Line: 6, Col: 26}},
-		{TokenType: token.RPAREN, Literal: ")", Start: token.Pos{Line: 6, Col: 27}, End: token.Pos{Line: 6, Col: 27}},
-		{TokenType: token.LBRACE, Literal: "{", Start: token.Pos{Line: 6, Col: 29}, End: token.Pos{Line: 6, Col: 29}},
+		{TokenType: token.VAR, Literal: "var", Start: token.Pos{Line: 6, Col: 1, Offset: 61}, End: token.Pos{Line: 6, Col: 3, Offset: 63}, NewLine: true},
+		{TokenType: token.IDENT, Literal: "foo", Start: token.Pos{Line: 6, Col: 5, Offset: 65}, End: token.Pos{Line: 6, Col: 7, Offset: 67}},
+		{TokenType: token.ASSIGN, Literal: "=", Start: token.Pos{Line: 6, Col: 9, Offset: 69}, End: token.Pos{Line: 6, Col: 9, Offset: 69}},
+		{TokenType: token.FUNCTION, Literal: "function", Start: token.Pos{Line: 6, Col: 11, Offset: 71}, End: token.Pos{Line: 6, Col: 18, Offset: 78}},
+		{TokenType: token.LPAREN, Literal: "(", Start: token.Pos{Line: 6, Col: 19, Offset: 79}, End: token.Pos{Line: 6, Col: 19, Offset: 79}},
+		{TokenType: token.IDENT, Literal: "a", Start: token.Pos{Line: 6, Col: 20, Offset: 80}, End: token.Pos{Line: 6, Col: 20, Offset: 80}},
+		{TokenType: token.COMMA, Literal: ",", Start: token.Pos{Line: 6, Col: 21, Offset: 81}, End: token.Pos{Line: 6, Col: 21, Offset: 81}},
+		{TokenType: token.IDENT, Literal: "func", Start: token.Pos{Line: 6, Col: 23, Offset: 83}, End: token.Pos{Line: 6, Col: 26, Offset: 86}},
+		{TokenType: token.RPAREN, Literal: ")", Start: token.Pos{Line: 6, Col: 27, Offset: 87}, End: token.Pos{Line: 6, Col: 27, Offset: 87}},
+		{TokenType: token.LBRACE, Literal: "{", Start: token.Pos{Line: 6, Col: 29, Offset: 89}, End: token.Pos{Line: 6, Col: 29, Offset: 89}},
 
 		// Line 7
-		{TokenType: token.RETURN, Literal: "return", Start: token.Pos{Line: 7, Col: 3}, End: token.Pos{Line: 7, Col: 8}},
-		{TokenType: token.IDENT, Literal: "func", Start: token.Pos{Line: 7, Col: 10}, End: token.Pos{Line: 7, Col: 13}},
-		{TokenType: token.LPAREN, Literal: "(", Start: token.Pos{Line: 7, Col: 14}, End: token.Pos{Line: 7, Col: 14}},
-		{TokenType: token.IDENT, Literal: "a", Start: token.Pos{Line: 7, Col: 15}, End: token.Pos{Line: 7, Col: 15}},
-		{TokenType: token.COMMA, Literal: ",", Start: token.Pos{Line: 7, Col: 16}, End: token.Pos{Line: 7, Col: 16}},
-		{TokenType: token.IDENT, Literal: "a", Start: token.Pos{Line: 7, Col: 18}, End: token.Pos{Line: 7, Col: 18}},
-		{TokenType: token.RPAREN, Literal: ")", Start: token.Pos{Line: 7, Col: 19}, End: token.Pos{Line: 7, Col: 19}},
-		{TokenType: token.MINUS, Literal: "-", Start: token.Pos{Line: 7, Col: 21}, End: token.Pos{Line: 7, Col: 21}},
-		{TokenType: token.IDENT, Literal: "a", Start: token.Pos{Line: 7, Col: 23}, End: token.Pos{Line: 7, Col: 23}},
-		{TokenType: token.SEMICOLON, Literal: ";", Start: token.Pos{Line: 7, Col: 24}, End: token.Pos{Line: 7, Col: 24}},
+		{TokenType: token.RETURN, Literal: "return", Start: token.Pos{Line: 7, Col: 3, Offset: 93}, End: token.Pos{Line: 7, Col: 8, Offset: 98}, NewLine: true},
+		{TokenType: token.IDENT, Literal: "func", Start: token.Pos{Line: 7, Col: 10, Offset: 100}, End: token.Pos{Line: 7, Col: 13, Offset: 103}},
+		{TokenType: token.LPAREN, Literal: "(", Start: token.Pos{Line: 7, Col: 14, Offset: 104}, End: token.Pos{Line: 7, Col: 14, Offset: 104}},
+		{TokenType: token.IDENT, Literal: "a", Start: token.Pos{Line: 7, Col: 15, Offset: 105}, End: token.Pos{Line: 7, Col: 15, Offset: 105}},
+		{TokenType: token.COMMA, Literal: ",", Start: token.Pos{Line: 7, Col: 16, Offset: 106}, End: token.Pos{Line: 7, Col: 16, Offset: 106}},
+		{TokenType: token.IDENT, Literal: "a", Start: token.Pos{Line: 7, Col: 18, Offset: 108}, End: token.Pos{Line: 7, Col: 18, Offset: 108}},
+		{TokenType: token.RPAREN, Literal: ")", Start: token.Pos{Line: 7, Col: 19, Offset: 109}, End: token.Pos{Line: 7, Col: 19, Offset: 109}},
+		{TokenType: token.MINUS, Literal: "-", Start: token.Pos{Line: 7, Col: 21, Offset: 111}, End: token.Pos{Line: 7, Col: 21, Offset: 111}},
+		{TokenType: token.IDENT, Literal: "a", Start: token.Pos{Line: 7, Col: 23, Offset: 113}, End: token.Pos{Line: 7, Col: 23, Offset: 113}},
+		{TokenType: token.SEMICOLON, Literal: ";", Start: token.Pos{Line: 7, Col: 24, Offset: 114}, End: token.Pos{Line: 7, Col: 24, Offset: 114}},
 
 		// Line 8
-		{TokenType: token.RBRACE, Literal: "}", Start: token.Pos{Line: 8, Col: 1}, End: token.Pos{Line: 8, Col: 1}},
-		{TokenType: token.SEMICOLON, Literal: ";", Start: token.Pos{Line: 8, Col: 2}, End: token.Pos{Line: 8, Col: 2}},
+		{TokenType: token.RBRACE, Literal: "}", Start: token.Pos{Line: 8, Col: 1, Offset: 116}, End: token.Pos{Line: 8, Col: 1, Offset: 116}, NewLine: true},
+		{TokenType: token.SEMICOLON, Literal: ";", Start: token.Pos{Line: 8, Col: 2, Offset: 117}, End: token.Pos{Line: 8, Col: 2, Offset: 117}},
 
 		// Line 10
-		{TokenType: token.IDENT, Literal: "foo", Start: token.Pos{Line: 10, Col: 1}, End: token.Pos{Line: 10, Col: 3}},
-		{TokenType: token.LPAREN, Literal: "(", Start: token.Pos{Line: 10, Col: 4}, End: token.Pos{Line: 10, Col: 4}},
-		{TokenType: token.NUMBER, Literal: "4", Start: token.Pos{Line: 10, Col: 5}, End: token.Pos{Line: 10, Col: 5}},
-		{TokenType: token.COMMA, Literal: ",", Start: token.Pos{Line: 10, Col: 6}, End: token.Pos{Line: 10, Col: 6}},
-		{TokenType: token.IDENT, Literal: "add", Start: token.Pos{Line: 10, Col: 8}, End: token.Pos{Line: 10, Col: 10}},
-		{TokenType: token.RPAREN, Literal: ")", Start: token.Pos{Line: 10, Col: 11}, End: token.Pos{Line: 10, Col: 11}},
-		{TokenType: token.SEMICOLON, Literal: ";", Start: token.Pos{Line: 10, Col: 12}, End: token.Pos{Line: 10, Col: 12}},
+		{TokenType: token.IDENT, Literal: "foo", Start: token.Pos{Line: 10, Col: 1, Offset: 120}, End: token.Pos{Line: 10, Col: 3, Offset: 122}, NewLine: true},
+		{TokenType: token.LPAREN, Literal: "(", Start: token.Pos{Line: 10, Col: 4, Offset: 123}, End: token.Pos{Line: 10, Col: 4, Offset: 123}},
+		{TokenType: token.NUMBER, Literal: "4", Start: token.Pos{Line: 10, Col: 5, Offset: 124}, End: token.Pos{Line: 10, Col: 5, Offset: 124}},
+		{TokenType: token.COMMA, Literal: ",", Start: token.Pos{Line: 10, Col: 6, Offset: 125}, End: token.Pos{Line: 10, Col: 6, Offset: 125}},
+		{TokenType: token.IDENT, Literal: "add", Start: token.Pos{Line: 10, Col: 8, Offset: 127}, End: token.Pos{Line: 10, Col: 10, Offset: 129}},
+		{TokenType: token.RPAREN, Literal: ")", Start: token.Pos{Line: 10, Col: 11, Offset: 130}, End: token.Pos{Line: 10, Col: 11, Offset: 130}},
+		{TokenType: token.SEMICOLON, Literal: ";", Start: token.Pos{Line: 10, Col: 12, Offset: 131}, End: token.Pos{Line: 10, Col: 12, Offset: 131}},
 
 		// Line 12
-		{TokenType: token.VAR, Literal: "var", Start: token.Pos{Line: 12, Col: 1}, End: token.Pos{Line: 12, Col: 3}},
-		{TokenType: token.IDENT, Literal: "total", Start: token.Pos{Line: 12, Col: 5}, End: token.Pos{Line: 12, Col: 9}},
-		{TokenType: token.ASSIGN, Literal: "=", Start: token.Pos{Line: 12, Col: 11}, End: token.Pos{Line: 12, Col: 11}},
-		{TokenType: token.IDENT, Literal: "num", Start: token.Pos{Line: 12, Col: 13}, End: token.Pos{Line: 12, Col: 15}},
-		{TokenType: token.MUL, Literal: "*", Start: token.Pos{Line: 12, Col: 17}, End: token.Pos{Line: 12, Col: 17}},
-		{TokenType: token.NUMBER, Literal: "90", Start: token.Pos{Line: 12, Col: 19}, End: token.Pos{Line: 12, Col: 20}},
-		{TokenType: token.SEMICOLON, Literal: ";", Start: token.Pos{Line: 12, Col: 21}, End: token.Pos{Line: 12, Col: 21}},
+		{TokenType: token.VAR, Literal: "var", Start: token.Pos{Line: 12, Col: 1, Offset: 134}, End: token.Pos{Line: 12, Col: 3, Offset: 136}, NewLine: true},
+		{TokenType: token.IDENT, Literal: "total", Start: token.Pos{Line: 12, Col: 5, Offset: 138}, End: token.Pos{Line: 12, Col: 9, Offset: 142}},
+		{TokenType: token.ASSIGN, Literal: "=", Start: token.Pos{Line: 12, Col: 11, Offset: 144}, End: token.Pos{Line: 12, Col: 11, Offset: 144}},
+		{TokenType: token.IDENT, Literal: "num", Start: token.Pos{Line: 12, Col: 13, Offset: 146}, End: token.Pos{Line: 12, Col: 15, Offset: 148}},
+		{TokenType: token.MUL, Literal: "*", Start: token.Pos{Line: 12, Col: 17, Offset: 150}, End: token.Pos{Line: 12, Col: 17, Offset: 150}},
+		{TokenType: token.NUMBER, Literal: "90", Start: token.Pos{Line: 12, Col: 19, Offset: 152}, End: token.Pos{Line: 12, Col: 20, Offset: 153}},
+		{TokenType: token.SEMICOLON, Literal: ";", Start: token.Pos{Line: 12, Col: 21, Offset: 154}, End: token.Pos{Line: 12, Col: 21, Offset: 154}},
 
 		// Line 14
-		{TokenType: token.STRING, Literal: "hello", Start: token.Pos{Line: 14, Col: 1}, End: token.Pos{Line: 14, Col: 7}},
+		{TokenType: token.STRING, Literal: "hello", Start: token.Pos{Line: 14, Col: 1, Offset: 157}, End: token.Pos{Line: 14, Col: 7, Offset: 163}, NewLine: true},
 
 		// Line 16
-		{TokenType: token.LBRACKET, Literal: "[", Start: token.Pos{Line: 16, Col: 1}, End: token.Pos{Line: 16, Col: 1}},
-		{TokenType: token.NUMBER, Literal: "1", Start: token.Pos{Line: 16, Col: 2}, End: token.Pos{Line: 16, Col: 2}},
-		{TokenType: token.COMMA, Literal: ",", Start: token.Pos{Line: 16, Col: 3}, End: token.Pos{Line: 16, Col: 3}},
-		{TokenType: token.NUMBER, Literal: "3", Start: token.Pos{Line: 16, Col: 5}, End: token.Pos{Line: 16, Col: 5}},
-		{TokenType: token.COMMA, Literal: ",", Start: token.Pos{Line: 16, Col: 6}, End: token.Pos{Line: 16, Col: 6}},
-		{TokenType: token.NUMBER, Literal: "5", Start: token.Pos{Line: 16, Col: 8}, End: token.Pos{Line: 16, Col: 8}},
-		{TokenType: token.RBRACKET, Literal: "]", Start: token.Pos{Line: 16, Col: 9}, End: token.Pos{Line: 16, Col: 9}},
-		{TokenType: token.SEMICOLON, Literal: ";", Start: token.Pos{Line: 16, Col: 10}, End: token.Pos{Line: 16, Col: 10}},
+		{TokenType: token.LBRACKET, Literal: "[", Start: token.Pos{Line: 16, Col: 1, Offset: 166}, End: token.Pos{Line: 16, Col: 1, Offset: 166}, NewLine: true},
+		{TokenType: token.NUMBER, Literal: "1", Start: token.Pos{Line: 16, Col: 2, Offset: 167}, End: token.Pos{Line: 16, Col: 2, Offset: 167}},
+		{TokenType: token.COMMA, Literal: ",", Start: token.Pos{Line: 16, Col: 3, Offset: 168}, End: token.Pos{Line: 16, Col: 3, Offset: 168}},
+		{TokenType: token.NUMBER, Literal: "3", Start: token.Pos{Line: 16, Col: 5, Offset: 170}, End: token.Pos{Line: 16, Col: 5, Offset: 170}},
+		{TokenType: token.COMMA, Literal: ",", Start: token.Pos{Line: 16, Col: 6, Offset: 171}, End: token.Pos{Line: 16, Col: 6, Offset: 171}},
+		{TokenType: token.NUMBER, Literal: "5", Start: token.Pos{Line: 16, Col: 8, Offset: 173}, End: token.Pos{Line: 16, Col: 8, Offset: 173}},
+		{TokenType: token.RBRACKET, Literal: "]", Start: token.Pos{Line: 16, Col: 9, Offset: 174}, End: token.Pos{Line: 16, Col: 9, Offset: 174}},
+		{TokenType: token.SEMICOLON, Literal: ";", Start: token.Pos{Line: 16, Col: 10, Offset: 175}, End: token.Pos{Line: 16, Col: 10, Offset: 175}},
 	}
 
 	l := New(byt)
@@ -292,3 +294,108 @@ func TestLexSourceFile(t *testing.T) {
 		}
 	}
 }
+
+func TestLexUnicodeIdentifier(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected token.Token
+	}{
+		{"größe", token.Token{TokenType: token.IDENT, Literal: "größe", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 5, Offset: 6}}},
+		{"π", token.Token{TokenType: token.IDENT, Literal: "π", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 1, Offset: 0}}},
+		{"日本語", token.Token{TokenType: token.IDENT, Literal: "日本語", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 3, Offset: 6}}},
+		{"$apple1", token.Token{TokenType: token.IDENT, Literal: "$apple1", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 7, Offset: 6}}},
+		{"été", token.Token{TokenType: token.IDENT, Literal: "été", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 4, Offset: 4}}},
+	}
+
+	for _, test := range tests {
+		l := New([]byte(test.input))
+		tok, err := l.Lex()
+		if err != nil {
+			t.Fatalf("Lexer.Lex Lex error. %v", err)
+		}
+		if tok != test.expected {
+			t.Errorf("Lexer.Lex wrong token. got=%#v, expected=%#v", tok, test.expected)
+		}
+	}
+}
+
+func TestLexEmptyString(t *testing.T) {
+	input := `"" == a`
+
+	tests := []token.Token{
+		{TokenType: token.STRING, Literal: "", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 2, Offset: 1}},
+		{TokenType: token.EQUAL, Literal: "==", Start: token.Pos{Line: 1, Col: 4, Offset: 3}, End: token.Pos{Line: 1, Col: 5, Offset: 4}},
+		{TokenType: token.IDENT, Literal: "a", Start: token.Pos{Line: 1, Col: 7, Offset: 6}, End: token.Pos{Line: 1, Col: 7, Offset: 6}},
+	}
+
+	l := New([]byte(input))
+	for _, test := range tests {
+		tok, err := l.Lex()
+		if err != nil {
+			t.Fatal("Lexer.Lex: error in Lex", err)
+		}
+		if tok != test {
+			t.Errorf("Lexer.Lex wrong token, got=%+v, expected=%+v", tok, test)
+		}
+	}
+}
+
+func TestLexUnicodePosition(t *testing.T) {
+	input := `var s = "héllo 😀"; s;`
+
+	tests := []token.Token{
+		{TokenType: token.VAR, Literal: "var", Start: token.Pos{Line: 1, Col: 1, Offset: 0}, End: token.Pos{Line: 1, Col: 3, Offset: 2}},
+		{TokenType: token.IDENT, Literal: "s", Start: token.Pos{Line: 1, Col: 5, Offset: 4}, End: token.Pos{Line: 1, Col: 5, Offset: 4}},
+		{TokenType: token.ASSIGN, Literal: "=", Start: token.Pos{Line: 1, Col: 7, Offset: 6}, End: token.Pos{Line: 1, Col: 7, Offset: 6}},
+		{TokenType: token.STRING, Literal: "héllo 😀", Start: token.Pos{Line: 1, Col: 9, Offset: 8}, End: token.Pos{Line: 1, Col: 17, Offset: 20}},
+		{TokenType: token.SEMICOLON, Literal: ";", Start: token.Pos{Line: 1, Col: 18, Offset: 21}, End: token.Pos{Line: 1, Col: 18, Offset: 21}},
+		{TokenType: token.IDENT, Literal: "s", Start: token.Pos{Line: 1, Col: 20, Offset: 23}, End: token.Pos{Line: 1, Col: 20, Offset: 23}},
+	}
+
+	l := New([]byte(input))
+	for _, test := range tests {
+		tok, err := l.Lex()
+		if err != nil {
+			t.Fatal("Lexer.Lex: error in Lex", err)
+		}
+		if tok != test {
+			t.Errorf("Lexer.Lex wrong token, got=%+v, expected=%+v", tok, test)
+		}
+	}
+}
+
+func TestLexIllegalUnicode(t *testing.T) {
+	for _, input := range []string{"☃", "a\xffb"[1:2]} {
+		l := New([]byte(input))
+		tok, err := l.Lex()
+		if err == nil || tok.TokenType != token.ILLEGAL {
+			t.Errorf("Lexer.Lex should return ILLEGAL for %q, got=%+v", input, tok)
+		}
+	}
+}
+
+func TestLexASCIIAllocation(t *testing.T) {
+	byt, err := os.ReadFile("./lexer_test_file.js")
+	if err != nil {
+		t.Fatal("failed to read file", err)
+	}
+	// AllocsPerRun calls the function once more as a warm up
+	lexers := []*Lexer{New(byt), New(byt)}
+
+	allocs := testing.AllocsPerRun(1, func() {
+		l := lexers[0]
+		lexers = lexers[1:]
+		for {
+			tok, err := l.Lex()
+			if err != nil {
+				t.Fatal("Lexer.Lex: error in Lex", err)
+			}
+			if tok.TokenType == token.EOF {
+				break
+			}
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Lexer.Lex should not allocate on ASCII source, got=%v allocations", allocs)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/jf550-kent/jsgo/compiler"
 	"github.com/jf550-kent/jsgo/evaluator"
@@ -31,9 +32,46 @@ func main() {
 		printOut(VERSION, RESULT)
 		return
 	}
+	if len(os.Args) > 1 && os.Args[1] == "build" {
+		if err := build(os.Args[2:]); err != nil {
+			printError(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "disasm" {
+		if err := disasm(os.Args[2:]); err != nil {
+			printError(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+	// a bytecode file is run by the vm without the interpreter argument
+	if len(os.Args) > 1 && strings.HasSuffix(os.Args[1], BYTECODE_EXT) {
+		if len(os.Args) > 2 && os.Args[2] != "bytecode" {
+			log.Fatalf("flag: %s can only be run by the 'bytecode' interpreter", os.Args[1])
+		}
+		// the linker checks the instructions of the file, a panic left is reported as a bad file
+		defer func() {
+			if r := recover(); r != nil {
+				printError(fmt.Sprintf("%s: %s: %v", os.Args[1], compiler.ErrFormat, r))
+				os.Exit(1)
+			}
+		}()
+		program, err := loadBytecode(os.Args[1])
+		if err != nil {
+			printError(err.Error())
+			os.Exit(1)
+		}
+		runBytecode(program)
+		return
+	}
 	if len(os.Args) < 3 {
 		printError("Please provide file name as the first argument to be run by jsgo\n")
 		printOut("usage ./jsgo <filename> <tree|bytecode> [debug] [-version]", WARNING)
+		printOut("      ./jsgo <filename>"+BYTECODE_EXT, WARNING)
+		printOut("      ./jsgo build <filename> [-o <output>]", WARNING)
+		printOut("      ./jsgo disasm <filename>", WARNING)
 		os.Exit(1)
 	}
 	fileName := os.Args[1]
@@ -95,17 +133,24 @@ func main() {
 		if err := com.Compile(main); err != nil {
 			printError("compiler error: " + err.Error())
 		}
+		runBytecode(com.ByteCode())
+	}
+}
 
-		virtualMachine := vm.New(com.ByteCode())
-		if err := virtualMachine.Run(); err != nil {
-			printError("vm error: " + err.Error())
-			break
+func runBytecode(program *compiler.Bytecode) {
+	virtualMachine := vm.New(program)
+	if err := virtualMachine.Run(); err != nil {
+		if err, ok := err.(*object.Error); ok {
+			printError("vm error: " + err.StackTrace())
+			return
 		}
-
-		result := virtualMachine.LastPopStack()
-		out := fmt.Sprintf("%+v", result)
-		printOut(out, RESULT)
+		printError("vm error: " + err.Error())
+		return
 	}
+
+	result := virtualMachine.LastPopStack()
+	out := fmt.Sprintf("%+v", result)
+	printOut(out, RESULT)
 }
 
 func printError(out string) {
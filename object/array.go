@@ -0,0 +1,412 @@
+package object
+
+import (
+	"math"
+	"slices"
+	"strings"
+)
+
+// ArrayMethods are the methods reachable from an array with the dot operator,
+// the array is passed as the first argument. The callback methods call their
+// function argument with the element, its index and the array.
+var ArrayMethods = map[string]*BuiltIn{
+	"push":     ArrayPush,
+	"pop":      arrayMethod("pop", arrayPop),
+	"shift":    arrayMethod("shift", arrayShift),
+	"unshift":  arrayMethod("unshift", arrayUnshift),
+	"slice":    arrayMethod("slice", arraySlice),
+	"splice":   arrayMethod("splice", arraySplice),
+	"concat":   arrayMethod("concat", arrayConcat),
+	"indexOf":  arrayMethod("indexOf", arrayIndexOf),
+	"includes": arrayMethod("includes", arrayIncludes),
+	"join":     arrayMethod("join", arrayJoin),
+	"reverse":  arrayMethod("reverse", arrayReverse),
+	"map":      arrayMethod("map", arrayMap),
+	"filter":   arrayMethod("filter", arrayFilter),
+	"reduce":   arrayMethod("reduce", arrayReduce),
+	"forEach":  arrayMethod("forEach", arrayForEach),
+	"find":     arrayMethod("find", arrayFind),
+	"some":     arrayMethod("some", arraySome),
+	"every":    arrayMethod("every", arrayEvery),
+	"sort":     arrayMethod("sort", arraySort),
+}
+
+// MaxArrayLength is the most elements an array can hold, as in JS.
+const MaxArrayLength = 1<<32 - 1
+
+// ArrayIndex returns the element an assignment to an array at index writes, a float with no
+// fraction is the equal index. An index that is negative, fractional or not below MaxArrayLength
+// is a RangeError, one that is not a number a TypeError.
+func ArrayIndex(index Object) (int, *Error) {
+	switch index := index.(type) {
+	case *Number:
+		if index.Value >= 0 && index.Value < MaxArrayLength {
+			return int(index.Value), nil
+		}
+	case *Float:
+		if n, ok := floatInt(index.Value); ok && n >= 0 && n < MaxArrayLength {
+			return int(n), nil
+		}
+	default:
+		return 0, &Error{Message: "TypeError: wrong type for array index " + ToString(index)}
+	}
+	return 0, &Error{Message: "RangeError: Invalid array index " + ToString(index)}
+}
+
+// IntegerIndex returns index as an integer when it is a *Number or a float with no fraction,
+// reading an array at it reads the element with that index.
+func IntegerIndex(index Object) (int64, bool) {
+	switch index := index.(type) {
+	case *Number:
+		return index.Value, true
+	case *Float:
+		return floatInt(index.Value)
+	}
+	return 0, false
+}
+
+var ArrayPush = arrayMethod("push", func(caller Caller, arr *Array, args []Object) Object {
+	if err := LimitsOf(caller).AllocValues(len(args)); err != nil {
+		return err
+	}
+	arr.Body = append(arr.Body, args...)
+	return &Number{Value: int64(len(arr.Body))}
+})
+
+// arrayMethod wraps fn into a BuiltIn that checks its receiver is an array.
+func arrayMethod(name string, fn func(caller Caller, arr *Array, args []Object) Object) *BuiltIn {
+	return &BuiltIn{
+		Name: name,
+		Function: func(caller Caller, args ...Object) Object {
+			if len(args) == 0 {
+				return &Error{Message: name + " called without an array"}
+			}
+			arr, ok := args[0].(*Array)
+			if !ok {
+				return &Error{Message: name + " called on non array " + args[0].String()}
+			}
+			return fn(caller, arr, args[1:])
+		},
+	}
+}
+
+func arrayPop(_ Caller, arr *Array, _ []Object) Object {
+	if len(arr.Body) == 0 {
+		return nil
+	}
+	last := arr.Body[len(arr.Body)-1]
+	arr.Body = arr.Body[:len(arr.Body)-1]
+	return last
+}
+
+func arrayShift(_ Caller, arr *Array, _ []Object) Object {
+	if len(arr.Body) == 0 {
+		return nil
+	}
+	first := arr.Body[0]
+	arr.Body = slices.Delete(arr.Body, 0, 1)
+	return first
+}
+
+func arrayUnshift(caller Caller, arr *Array, args []Object) Object {
+	if err := LimitsOf(caller).AllocValues(len(args)); err != nil {
+		return err
+	}
+	arr.Body = slices.Insert(arr.Body, 0, args...)
+	return &Number{Value: int64(len(arr.Body))}
+}
+
+func arraySlice(caller Caller, arr *Array, args []Object) Object {
+	n := len(arr.Body)
+	start := relativeIndex(integerArg(args, 0, 0), n)
+	end := relativeIndex(integerArg(args, 1, n), n)
+	if start >= end {
+		return &Array{Body: []Object{}}
+	}
+	if err := LimitsOf(caller).AllocValues(end - start); err != nil {
+		return err
+	}
+	return &Array{Body: slices.Clone(arr.Body[start:end])}
+}
+
+func arraySplice(caller Caller, arr *Array, args []Object) Object {
+	n := len(arr.Body)
+	start := relativeIndex(integerArg(args, 0, 0), n)
+	deleteCount := n - start
+	if len(args) > 1 {
+		deleteCount = clamp(integerArg(args, 1, 0), 0, n-start)
+	}
+	var items []Object
+	if len(args) > 2 {
+		items = args[2:]
+	}
+	if err := LimitsOf(caller).AllocValues(deleteCount + len(items)); err != nil {
+		return err
+	}
+
+	removed := slices.Clone(arr.Body[start : start+deleteCount])
+	arr.Body = slices.Replace(arr.Body, start, start+deleteCount, items...)
+	return &Array{Body: removed}
+}
+
+func arrayConcat(caller Caller, arr *Array, args []Object) Object {
+	size := len(arr.Body)
+	for _, arg := range args {
+		if other, ok := arg.(*Array); ok {
+			size += len(other.Body)
+			continue
+		}
+		size++
+	}
+	if err := LimitsOf(caller).AllocValues(size); err != nil {
+		return err
+	}
+
+	body := slices.Clone(arr.Body)
+	for _, arg := range args {
+		if other, ok := arg.(*Array); ok {
+			body = append(body, other.Body...)
+			continue
+		}
+		body = append(body, arg)
+	}
+	return &Array{Body: body}
+}
+
+func arrayIndexOf(_ Caller, arr *Array, args []Object) Object {
+	search := argOrNull(args, 0)
+	start := relativeIndex(integerArg(args, 1, 0), len(arr.Body))
+	for i := start; i < len(arr.Body); i++ {
+		if StrictEqual(elementAt(arr, i), search) {
+			return &Number{Value: int64(i)}
+		}
+	}
+	return &Number{Value: -1}
+}
+
+func arrayIncludes(_ Caller, arr *Array, args []Object) Object {
+	search := argOrNull(args, 0)
+	start := relativeIndex(integerArg(args, 1, 0), len(arr.Body))
+	for i := start; i < len(arr.Body); i++ {
+		if sameValueZero(elementAt(arr, i), search) {
+			return &Boolean{Value: true}
+		}
+	}
+	return &Boolean{Value: false}
+}
+
+func arrayJoin(caller Caller, arr *Array, args []Object) Object {
+	sep := ","
+	if len(args) > 0 {
+		sep = ToString(args[0])
+	}
+	elements := make([]string, len(arr.Body))
+	for i, el := range arr.Body {
+		if _, ok := el.(*Null); ok || el == nil {
+			continue
+		}
+		elements[i] = ToString(el)
+	}
+	size := len(sep) * max(len(elements)-1, 0)
+	for _, el := range elements {
+		size += len(el)
+	}
+	if err := LimitsOf(caller).AllocBytes(size); err != nil {
+		return err
+	}
+	return &String{Value: strings.Join(elements, sep)}
+}
+
+func arrayReverse(_ Caller, arr *Array, _ []Object) Object {
+	slices.Reverse(arr.Body)
+	return arr
+}
+
+func arrayMap(caller Caller, arr *Array, args []Object) Object {
+	if err := LimitsOf(caller).AllocValues(len(arr.Body)); err != nil {
+		return err
+	}
+	result := make([]Object, len(arr.Body))
+	for i := range arr.Body {
+		val := callElement(caller, args, arr, i)
+		if isError(val) {
+			return val
+		}
+		result[i] = val
+	}
+	return &Array{Body: result}
+}
+
+func arrayFilter(caller Caller, arr *Array, args []Object) Object {
+	result := []Object{}
+	for i := range arr.Body {
+		el := elementAt(arr, i)
+		val := callElement(caller, args, arr, i)
+		if isError(val) {
+			return val
+		}
+		if ToBoolean(val) {
+			if err := LimitsOf(caller).AllocValues(1); err != nil {
+				return err
+			}
+			result = append(result, el)
+		}
+	}
+	return &Array{Body: result}
+}
+
+func arrayReduce(caller Caller, arr *Array, args []Object) Object {
+	start := 0
+	var acc Object
+	if len(args) > 1 {
+		acc = args[1]
+	} else {
+		if len(arr.Body) == 0 {
+			return &Error{Message: "TypeError: Reduce of empty array with no initial value"}
+		}
+		acc = elementAt(arr, 0)
+		start = 1
+	}
+
+	for i := start; i < len(arr.Body); i++ {
+		acc = caller.Call(argOrNull(args, 0), acc, elementAt(arr, i), &Number{Value: int64(i)}, arr)
+		if isError(acc) {
+			return acc
+		}
+	}
+	return acc
+}
+
+func arrayForEach(caller Caller, arr *Array, args []Object) Object {
+	for i := range arr.Body {
+		if val := callElement(caller, args, arr, i); isError(val) {
+			return val
+		}
+	}
+	return nil
+}
+
+func arrayFind(caller Caller, arr *Array, args []Object) Object {
+	for i := range arr.Body {
+		el := elementAt(arr, i)
+		val := callElement(caller, args, arr, i)
+		if isError(val) {
+			return val
+		}
+		if ToBoolean(val) {
+			return el
+		}
+	}
+	return nil
+}
+
+func arraySome(caller Caller, arr *Array, args []Object) Object {
+	for i := range arr.Body {
+		val := callElement(caller, args, arr, i)
+		if isError(val) {
+			return val
+		}
+		if ToBoolean(val) {
+			return &Boolean{Value: true}
+		}
+	}
+	return &Boolean{Value: false}
+}
+
+func arrayEvery(caller Caller, arr *Array, args []Object) Object {
+	for i := range arr.Body {
+		val := callElement(caller, args, arr, i)
+		if isError(val) {
+			return val
+		}
+		if !ToBoolean(val) {
+			return &Boolean{Value: false}
+		}
+	}
+	return &Boolean{Value: true}
+}
+
+// arraySort sorts arr in place with a stable sort. Without a comparator the elements
+// are compared as strings, the unset elements of a sparse array are moved to the end.
+func arraySort(caller Caller, arr *Array, args []Object) Object {
+	var err Object
+	compare := func(a, b Object) int {
+		if err != nil {
+			return 0
+		}
+		aMissing, bMissing := a == nil, b == nil
+		switch {
+		case aMissing && bMissing:
+			return 0
+		case aMissing:
+			return 1
+		case bMissing:
+			return -1
+		}
+
+		if len(args) == 0 {
+			return CompareStrings(ToString(a), ToString(b))
+		}
+		val := caller.Call(args[0], a, b)
+		if isError(val) {
+			err = val
+			return 0
+		}
+		f := toFloat64(ToNumber(val))
+		switch {
+		case f < 0:
+			return -1
+		case f > 0:
+			return 1
+		}
+		return 0
+	}
+
+	sorted := slices.Clone(arr.Body)
+	slices.SortStableFunc(sorted, compare)
+	if err != nil {
+		return err
+	}
+	arr.Body = sorted
+	return arr
+}
+
+// callElement calls the callback in args[0] with the element at i, i and arr.
+func callElement(caller Caller, args []Object, arr *Array, i int) Object {
+	return caller.Call(argOrNull(args, 0), elementAt(arr, i), &Number{Value: int64(i)}, arr)
+}
+
+// elementAt returns the element at i, an unset element of a sparse array is null.
+// The callback methods visit the length the array had when called, so a callback
+// that shrinks the array sees null past its end.
+func elementAt(arr *Array, i int) Object {
+	if i >= len(arr.Body) || arr.Body[i] == nil {
+		return &Null{}
+	}
+	return arr.Body[i]
+}
+
+func argOrNull(args []Object, i int) Object {
+	if i >= len(args) || args[i] == nil {
+		return &Null{}
+	}
+	return args[i]
+}
+
+// sameValueZero is StrictEqual except NaN is equal to itself, as includes compares.
+func sameValueZero(a, b Object) bool {
+	if StrictEqual(a, b) {
+		return true
+	}
+	return isNaN(a) && isNaN(b)
+}
+
+func isNaN(obj Object) bool {
+	f, ok := obj.(*Float)
+	return ok && math.IsNaN(f.Value)
+}
+
+func isError(obj Object) bool {
+	_, ok := obj.(*Error)
+	return ok
+}
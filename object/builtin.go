@@ -4,29 +4,62 @@ import (
 	"fmt"
 )
 
-var Builtins = []*BuiltIn{
-	{
-		Name: "console.log",
-		Function: func(args ...Object) Object {
-			for _, arg := range args {
-				fmt.Println(arg.String())
-			}
-			return nil
+var Console = &BuiltInObject{
+	Name: "console",
+	Properties: map[string]Object{
+		"log": &BuiltIn{
+			Name: "log",
+			Function: func(_ Caller, args ...Object) Object {
+				for _, arg := range args {
+					fmt.Println(arg.String())
+				}
+				return nil
+			},
 		},
 	},
 }
 
-var ArrayPush = &BuiltIn{
-	Name: "push",
-	Function: func(args ...Object) Object {
-		if len(args) != 2 {
-			panic("built in array push function only accept 2 arguments")
-		}
-		arr, ok := args[0].(*Array)
-		if !ok {
-			panic("not an array for push function")
-		}
-		arr.Body = append(arr.Body, args[1])
-		return nil
-	},
+// Member returns the property name of obj, ok is false if obj does not have the property.
+// Dictionary are not handled here as each engine index them on their own.
+func Member(obj Object, name string) (Object, bool) {
+	switch obj := obj.(type) {
+	case *Array:
+		if name == "length" {
+			return &Number{Value: int64(len(obj.Body))}, true
+		}
+		if method, ok := ArrayMethods[name]; ok {
+			return &BoundMethod{Receiver: obj, Method: method}, true
+		}
+	case *String:
+		if name == "length" {
+			return &Number{Value: int64(obj.Length())}, true
+		}
+		if method, ok := StringMethods[name]; ok {
+			return &BoundMethod{Receiver: obj, Method: method}, true
+		}
+	case *Map:
+		if name == "size" {
+			return &Number{Value: int64(obj.Len())}, true
+		}
+		if method, ok := MapMethods[name]; ok {
+			return &BoundMethod{Receiver: obj, Method: method}, true
+		}
+	case *Set:
+		if name == "size" {
+			return &Number{Value: int64(obj.Len())}, true
+		}
+		if method, ok := SetMethods[name]; ok {
+			return &BoundMethod{Receiver: obj, Method: method}, true
+		}
+	case *WeakMap:
+		if method, ok := WeakMapMethods[name]; ok {
+			return &BoundMethod{Receiver: obj, Method: method}, true
+		}
+	case *BuiltInObject:
+		val, ok := obj.Properties[name]
+		return val, ok
+	case HostObject:
+		return obj.Property(name)
+	}
+	return nil, false
 }
@@ -0,0 +1,189 @@
+package object
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ToBoolean reports whether obj is truthy. false, null, 0, NaN and the empty string are falsy,
+// every other value including empty arrays and dictionaries is truthy.
+func ToBoolean(obj Object) bool {
+	switch obj := obj.(type) {
+	case *Boolean:
+		return obj.Value
+	case *Null:
+		return false
+	case *Number:
+		return obj.Value != 0
+	case *Float:
+		return obj.Value != 0 && !math.IsNaN(obj.Value)
+	case *String:
+		return obj.Value != ""
+	}
+	return obj != nil
+}
+
+// ToPrimitive returns obj if it is a primitive value (number, string, boolean or null),
+// otherwise obj converted to a string the way JS does for arrays and objects.
+func ToPrimitive(obj Object) Object {
+	if isPrimitive(obj) {
+		return obj
+	}
+	return &String{Value: ToString(obj)}
+}
+
+// ToNumber converts obj to a number, the result is either a *Number or a *Float.
+// A value that cannot be converted becomes NaN.
+func ToNumber(obj Object) Object {
+	switch obj := obj.(type) {
+	case *Number, *Float:
+		return obj
+	case *Boolean:
+		if obj.Value {
+			return &Number{Value: 1}
+		}
+		return &Number{Value: 0}
+	case *Null:
+		return &Number{Value: 0}
+	case *String:
+		return stringToNumber(obj.Value)
+	}
+	if isPrimitive(obj) {
+		return &Float{Value: math.NaN()}
+	}
+	return ToNumber(ToPrimitive(obj))
+}
+
+// ToString converts obj to the string JS would produce, which for null is "null"
+// and for an array is its elements joined by a comma.
+func ToString(obj Object) string {
+	switch obj := obj.(type) {
+	case *String:
+		return obj.Value
+	case *Number:
+		return strconv.FormatInt(obj.Value, 10)
+	case *Float:
+		return formatFloat(obj.Value)
+	case *Boolean:
+		return strconv.FormatBool(obj.Value)
+	case *Null:
+		return "null"
+	case *Array:
+		elements := make([]string, len(obj.Body))
+		for i, el := range obj.Body {
+			if _, ok := el.(*Null); ok || el == nil {
+				continue
+			}
+			elements[i] = ToString(el)
+		}
+		return strings.Join(elements, ",")
+	case *Dictionary:
+		return "[object Object]"
+	case *Map:
+		return "[object Map]"
+	case *Set:
+		return "[object Set]"
+	case *WeakMap:
+		return "[object WeakMap]"
+	}
+	return obj.String()
+}
+
+// ToInt32 converts obj to a number and wraps it to a 32 bit integer the way the JS bitwise operators do.
+func ToInt32(obj Object) int32 {
+	switch num := ToNumber(obj).(type) {
+	case *Number:
+		return int32(num.Value)
+	case *Float:
+		if math.IsNaN(num.Value) || math.IsInf(num.Value, 0) {
+			return 0
+		}
+		return int32(int64(math.Mod(math.Trunc(num.Value), 1<<32)))
+	}
+	return 0
+}
+
+func isPrimitive(obj Object) bool {
+	switch obj.(type) {
+	case *Number, *Float, *String, *Boolean, *Null:
+		return true
+	}
+	return false
+}
+
+func isNumeric(obj Object) bool {
+	switch obj.(type) {
+	case *Number, *Float:
+		return true
+	}
+	return false
+}
+
+// toFloat64 returns the value of a *Number or *Float as a float64.
+func toFloat64(obj Object) float64 {
+	switch obj := obj.(type) {
+	case *Number:
+		return float64(obj.Value)
+	case *Float:
+		return obj.Value
+	}
+	return math.NaN()
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	case f == 0:
+		return "0"
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func stringToNumber(s string) Object {
+	s = strings.TrimSpace(s)
+	switch s {
+	case "":
+		return &Number{Value: 0}
+	case "Infinity", "+Infinity":
+		return &Float{Value: math.Inf(1)}
+	case "-Infinity":
+		return &Float{Value: math.Inf(-1)}
+	}
+
+	if len(s) > 2 && s[0] == '0' {
+		base := 0
+		switch s[1] {
+		case 'x', 'X':
+			base = 16
+		case 'o', 'O':
+			base = 8
+		case 'b', 'B':
+			base = 2
+		}
+		if base != 0 {
+			if n, err := strconv.ParseInt(s[2:], base, 64); err == nil {
+				return &Number{Value: n}
+			}
+			return &Float{Value: math.NaN()}
+		}
+	}
+
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return &Number{Value: n}
+	}
+	// ParseFloat also accepts inf, nan and hex floats which are not JS numbers
+	lower := strings.ToLower(s)
+	if strings.Contains(lower, "inf") || strings.Contains(lower, "nan") || strings.Contains(lower, "x") || strings.Contains(s, "_") {
+		return &Float{Value: math.NaN()}
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return &Float{Value: f}
+	}
+	return &Float{Value: math.NaN()}
+}
@@ -0,0 +1,471 @@
+package object
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Map is the JS Map. Unlike a Dictionary any value can be a key, numbers, strings,
+// booleans and null are keys by value and every other object by identity.
+type Map struct {
+	entries collection
+}
+
+func NewMap() *Map { return &Map{entries: newCollection()} }
+
+func (m *Map) Type() ObjectType { return MAP_OBJECT }
+func (m *Map) String() string {
+	items := []string{}
+	m.entries.each(func(key, value Object) bool {
+		items = append(items, key.String()+" => "+value.String())
+		return true
+	})
+	return fmt.Sprintf("Map(%d) {%s}", m.Len(), strings.Join(items, ", "))
+}
+
+func (m *Map) Len() int                        { return m.entries.len() }
+func (m *Map) Get(key Object) (Object, bool)   { return m.entries.get(key) }
+func (m *Map) Set(key, value Object)           { m.entries.set(key, value) }
+func (m *Map) Delete(key Object) bool          { return m.entries.delete(key) }
+func (m *Map) Each(fn func(key, value Object)) { m.entries.each(eachAll(fn)) }
+
+// Set is the JS Set, its values are compared the way the keys of a Map are.
+type Set struct {
+	entries collection
+}
+
+func NewSet() *Set { return &Set{entries: newCollection()} }
+
+func (s *Set) Type() ObjectType { return SET_OBJECT }
+func (s *Set) String() string {
+	items := []string{}
+	s.entries.each(func(key, _ Object) bool {
+		items = append(items, key.String())
+		return true
+	})
+	return fmt.Sprintf("Set(%d) {%s}", s.Len(), strings.Join(items, ", "))
+}
+
+func (s *Set) Len() int                 { return s.entries.len() }
+func (s *Set) Has(value Object) bool    { _, ok := s.entries.get(value); return ok }
+func (s *Set) Add(value Object)         { s.entries.set(value, value) }
+func (s *Set) Delete(value Object) bool { return s.entries.delete(value) }
+
+// WeakMap is the JS WeakMap, only objects compared by identity can be keys and it cannot be iterated.
+// The entries are held like any other Go value, a key is only released with the WeakMap.
+type WeakMap struct {
+	entries map[Object]Object
+}
+
+func NewWeakMap() *WeakMap { return &WeakMap{entries: map[Object]Object{}} }
+
+func (w *WeakMap) Type() ObjectType { return WEAK_MAP_OBJECT }
+func (w *WeakMap) String() string   { return "WeakMap { <items unknown> }" }
+
+var MapBuiltIn = &BuiltIn{
+	Name: "Map",
+	Function: func(caller Caller, args ...Object) Object {
+		m := NewMap()
+		switch init := argOrNull(args, 0).(type) {
+		case *Null:
+		case *Map:
+			if err := LimitsOf(caller).AllocEntries(init.Len()); err != nil {
+				return err
+			}
+			init.Each(m.Set)
+		case *Array:
+			if err := LimitsOf(caller).AllocEntries(len(init.Body)); err != nil {
+				return err
+			}
+			for _, entry := range init.Body {
+				pair, ok := entry.(*Array)
+				if !ok {
+					return &Error{Message: fmt.Sprintf("TypeError: Iterator value %s is not an entry object", ToString(entry))}
+				}
+				m.Set(elementAt(pair, 0), elementAt(pair, 1))
+			}
+		default:
+			return &Error{Message: fmt.Sprintf("TypeError: %s is not iterable", ToString(init))}
+		}
+		return m
+	},
+}
+
+var SetBuiltIn = &BuiltIn{
+	Name: "Set",
+	Function: func(caller Caller, args ...Object) Object {
+		s := NewSet()
+		switch init := argOrNull(args, 0).(type) {
+		case *Null:
+		case *Set:
+			if err := LimitsOf(caller).AllocEntries(init.Len()); err != nil {
+				return err
+			}
+			init.entries.each(eachAll(func(key, _ Object) { s.Add(key) }))
+		case *Array:
+			if err := LimitsOf(caller).AllocEntries(len(init.Body)); err != nil {
+				return err
+			}
+			for i := range init.Body {
+				s.Add(elementAt(init, i))
+			}
+		case *String:
+			if err := LimitsOf(caller).AllocEntries(len(init.Value)); err != nil {
+				return err
+			}
+			for _, r := range init.Value {
+				s.Add(&String{Value: string(r)})
+			}
+		default:
+			return &Error{Message: fmt.Sprintf("TypeError: %s is not iterable", ToString(init))}
+		}
+		return s
+	},
+}
+
+var WeakMapBuiltIn = &BuiltIn{
+	Name: "WeakMap",
+	Function: func(caller Caller, args ...Object) Object {
+		w := NewWeakMap()
+		if init, ok := argOrNull(args, 0).(*Array); ok {
+			if err := LimitsOf(caller).AllocEntries(len(init.Body)); err != nil {
+				return err
+			}
+			for _, entry := range init.Body {
+				pair, ok := entry.(*Array)
+				if !ok {
+					return &Error{Message: fmt.Sprintf("TypeError: Iterator value %s is not an entry object", ToString(entry))}
+				}
+				if err := weakMapSet(w, elementAt(pair, 0), elementAt(pair, 1)); err != nil {
+					return err
+				}
+			}
+		}
+		return w
+	},
+}
+
+// MapMethods are the methods reachable from a map with the dot operator, the map is passed as the first argument.
+var MapMethods = map[string]*BuiltIn{
+	"get": mapMethod("get", func(_ Caller, m *Map, args []Object) Object {
+		val, _ := m.Get(argOrNull(args, 0))
+		return val
+	}),
+	"set": mapMethod("set", func(caller Caller, m *Map, args []Object) Object {
+		if _, ok := m.Get(argOrNull(args, 0)); !ok {
+			if err := LimitsOf(caller).AllocEntries(1); err != nil {
+				return err
+			}
+		}
+		m.Set(argOrNull(args, 0), argOrNull(args, 1))
+		return m
+	}),
+	"has": mapMethod("has", func(_ Caller, m *Map, args []Object) Object {
+		_, ok := m.Get(argOrNull(args, 0))
+		return &Boolean{Value: ok}
+	}),
+	"delete": mapMethod("delete", func(_ Caller, m *Map, args []Object) Object {
+		return &Boolean{Value: m.Delete(argOrNull(args, 0))}
+	}),
+	"clear": mapMethod("clear", func(_ Caller, m *Map, _ []Object) Object {
+		m.entries.clear()
+		return nil
+	}),
+	"forEach": mapMethod("forEach", func(caller Caller, m *Map, args []Object) Object {
+		return forEachEntry(caller, &m.entries, argOrNull(args, 0), m)
+	}),
+	"keys": mapMethod("keys", func(caller Caller, m *Map, _ []Object) Object {
+		return entriesArray(caller, &m.entries, 1, func(key, _ Object) Object { return key })
+	}),
+	"values": mapMethod("values", func(caller Caller, m *Map, _ []Object) Object {
+		return entriesArray(caller, &m.entries, 1, func(_, value Object) Object { return value })
+	}),
+	"entries": mapMethod("entries", func(caller Caller, m *Map, _ []Object) Object {
+		return entriesArray(caller, &m.entries, 3, entryPair)
+	}),
+}
+
+// SetMethods are the methods reachable from a set with the dot operator, the set is passed as the first argument.
+var SetMethods = map[string]*BuiltIn{
+	"add": setMethod("add", func(caller Caller, s *Set, args []Object) Object {
+		if !s.Has(argOrNull(args, 0)) {
+			if err := LimitsOf(caller).AllocEntries(1); err != nil {
+				return err
+			}
+		}
+		s.Add(argOrNull(args, 0))
+		return s
+	}),
+	"has": setMethod("has", func(_ Caller, s *Set, args []Object) Object {
+		return &Boolean{Value: s.Has(argOrNull(args, 0))}
+	}),
+	"delete": setMethod("delete", func(_ Caller, s *Set, args []Object) Object {
+		return &Boolean{Value: s.Delete(argOrNull(args, 0))}
+	}),
+	"clear": setMethod("clear", func(_ Caller, s *Set, _ []Object) Object {
+		s.entries.clear()
+		return nil
+	}),
+	"forEach": setMethod("forEach", func(caller Caller, s *Set, args []Object) Object {
+		return forEachEntry(caller, &s.entries, argOrNull(args, 0), s)
+	}),
+	"values": setMethod("values", func(caller Caller, s *Set, _ []Object) Object {
+		return entriesArray(caller, &s.entries, 1, func(key, _ Object) Object { return key })
+	}),
+	"keys": setMethod("keys", func(caller Caller, s *Set, _ []Object) Object {
+		return entriesArray(caller, &s.entries, 1, func(key, _ Object) Object { return key })
+	}),
+	"entries": setMethod("entries", func(caller Caller, s *Set, _ []Object) Object {
+		return entriesArray(caller, &s.entries, 3, entryPair)
+	}),
+}
+
+// WeakMapMethods are the methods reachable from a weak map with the dot operator, the weak map is passed as the first argument.
+var WeakMapMethods = map[string]*BuiltIn{
+	"get": weakMapMethod("get", func(_ Caller, w *WeakMap, args []Object) Object {
+		return w.entries[argOrNull(args, 0)]
+	}),
+	"set": weakMapMethod("set", func(caller Caller, w *WeakMap, args []Object) Object {
+		if _, ok := w.entries[argOrNull(args, 0)]; !ok {
+			if err := LimitsOf(caller).AllocEntries(1); err != nil {
+				return err
+			}
+		}
+		if err := weakMapSet(w, argOrNull(args, 0), argOrNull(args, 1)); err != nil {
+			return err
+		}
+		return w
+	}),
+	"has": weakMapMethod("has", func(_ Caller, w *WeakMap, args []Object) Object {
+		_, ok := w.entries[argOrNull(args, 0)]
+		return &Boolean{Value: ok}
+	}),
+	"delete": weakMapMethod("delete", func(_ Caller, w *WeakMap, args []Object) Object {
+		key := argOrNull(args, 0)
+		_, ok := w.entries[key]
+		delete(w.entries, key)
+		return &Boolean{Value: ok}
+	}),
+}
+
+func weakMapSet(w *WeakMap, key, value Object) *Error {
+	if isPrimitive(key) {
+		return &Error{Message: "TypeError: Invalid value used as weak map key"}
+	}
+	w.entries[key] = value
+	return nil
+}
+
+func mapMethod(name string, fn func(caller Caller, m *Map, args []Object) Object) *BuiltIn {
+	return &BuiltIn{
+		Name: name,
+		Function: func(caller Caller, args ...Object) Object {
+			if len(args) == 0 {
+				return &Error{Message: name + " called without a map"}
+			}
+			m, ok := args[0].(*Map)
+			if !ok {
+				return &Error{Message: name + " called on non map " + args[0].String()}
+			}
+			return fn(caller, m, args[1:])
+		},
+	}
+}
+
+func setMethod(name string, fn func(caller Caller, s *Set, args []Object) Object) *BuiltIn {
+	return &BuiltIn{
+		Name: name,
+		Function: func(caller Caller, args ...Object) Object {
+			if len(args) == 0 {
+				return &Error{Message: name + " called without a set"}
+			}
+			s, ok := args[0].(*Set)
+			if !ok {
+				return &Error{Message: name + " called on non set " + args[0].String()}
+			}
+			return fn(caller, s, args[1:])
+		},
+	}
+}
+
+func weakMapMethod(name string, fn func(caller Caller, w *WeakMap, args []Object) Object) *BuiltIn {
+	return &BuiltIn{
+		Name: name,
+		Function: func(caller Caller, args ...Object) Object {
+			if len(args) == 0 {
+				return &Error{Message: name + " called without a weak map"}
+			}
+			w, ok := args[0].(*WeakMap)
+			if !ok {
+				return &Error{Message: name + " called on non weak map " + args[0].String()}
+			}
+			return fn(caller, w, args[1:])
+		},
+	}
+}
+
+// forEachEntry calls fn with the value, the key and the receiver of every entry,
+// entries added by fn are visited too.
+func forEachEntry(caller Caller, entries *collection, fn Object, receiver Object) Object {
+	var err Object
+	entries.each(func(key, value Object) bool {
+		if result := caller.Call(fn, value, key, receiver); isError(result) {
+			err = result
+			return false
+		}
+		return true
+	})
+	return err
+}
+
+// entriesArray returns the entries mapped by fn as an array, each entry accounted as size array elements.
+func entriesArray(caller Caller, entries *collection, size int, fn func(key, value Object) Object) Object {
+	if err := LimitsOf(caller).AllocValues(entries.len() * size); err != nil {
+		return err
+	}
+	return entries.array(fn)
+}
+
+func entryPair(key, value Object) Object {
+	return &Array{Body: []Object{key, value}}
+}
+
+func eachAll(fn func(key, value Object)) func(key, value Object) bool {
+	return func(key, value Object) bool {
+		fn(key, value)
+		return true
+	}
+}
+
+// collection keeps the entries of a Map or Set in insertion order. A deleted entry leaves a
+// hole so an iteration in progress keeps its position, the holes are compacted when no iteration is.
+type collection struct {
+	index     map[any]int
+	entries   []collectionEntry
+	removed   int
+	iterating int
+}
+
+type collectionEntry struct {
+	key, value Object
+	deleted    bool
+}
+
+// nullKey is the key of null in the index.
+type nullKey struct{}
+
+func newCollection() collection {
+	return collection{index: map[any]int{}}
+}
+
+// collectionKey returns the index key of obj, 1 and 1.0 are the same key, every NaN is
+// the same key and objects that are not primitives are keyed by their pointer.
+func collectionKey(obj Object) any {
+	switch obj := obj.(type) {
+	case *Number:
+		return obj.Value
+	case *Float:
+		if n, ok := floatInt(obj.Value); ok {
+			return n
+		}
+		return floatKey(obj.Value)
+	case *String:
+		return obj.Value
+	case *Boolean:
+		return obj.Value
+	case *Null:
+		return nullKey{}
+	}
+	return obj
+}
+
+func (c *collection) len() int { return len(c.entries) - c.removed }
+
+func (c *collection) get(key Object) (Object, bool) {
+	i, ok := c.index[collectionKey(key)]
+	if !ok {
+		return nil, false
+	}
+	return c.entries[i].value, true
+}
+
+func (c *collection) set(key, value Object) {
+	k := collectionKey(key)
+	if i, ok := c.index[k]; ok {
+		c.entries[i].value = value
+		return
+	}
+	// -0 is stored as 0
+	if f, ok := key.(*Float); ok && f.Value == 0 {
+		key = &Number{Value: 0}
+	}
+	c.index[k] = len(c.entries)
+	c.entries = append(c.entries, collectionEntry{key: key, value: value})
+}
+
+func (c *collection) delete(key Object) bool {
+	k := collectionKey(key)
+	i, ok := c.index[k]
+	if !ok {
+		return false
+	}
+	delete(c.index, k)
+	c.entries[i] = collectionEntry{deleted: true}
+	c.removed++
+	c.compact()
+	return true
+}
+
+func (c *collection) clear() {
+	clear(c.index)
+	for i := range c.entries {
+		c.entries[i] = collectionEntry{deleted: true}
+	}
+	c.removed = len(c.entries)
+	c.compact()
+}
+
+// compact drops the holes once they are more than half of the entries.
+func (c *collection) compact() {
+	if c.iterating > 0 || c.removed <= len(c.entries)/2 {
+		return
+	}
+	entries := make([]collectionEntry, 0, c.len())
+	for _, entry := range c.entries {
+		if entry.deleted {
+			continue
+		}
+		c.index[collectionKey(entry.key)] = len(entries)
+		entries = append(entries, entry)
+	}
+	c.entries = entries
+	c.removed = 0
+}
+
+// each calls fn for every entry in insertion order until fn returns false.
+func (c *collection) each(fn func(key, value Object) bool) {
+	c.iterating++
+	defer func() {
+		c.iterating--
+		c.compact()
+	}()
+
+	for i := 0; i < len(c.entries); i++ {
+		entry := c.entries[i]
+		if entry.deleted {
+			continue
+		}
+		if !fn(entry.key, entry.value) {
+			return
+		}
+	}
+}
+
+func (c *collection) array(fn func(key, value Object) Object) *Array {
+	body := make([]Object, 0, c.len())
+	c.each(func(key, value Object) bool {
+		body = append(body, fn(key, value))
+		return true
+	})
+	return &Array{Body: body}
+}
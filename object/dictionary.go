@@ -0,0 +1,144 @@
+package object
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dictionary is a hash map that keeps its keys in insertion order.
+// Keys are bucketed by their [Hash] and compared by value inside a bucket,
+// so two keys sharing a hash never overwrite each other.
+type Dictionary struct {
+	buckets map[Hash][]int // index into entries for every key with the hash
+	entries []KeyValue     // insertion order, a removed entry has a nil Key
+	removed int            // number of removed entries still held in entries
+}
+
+// KeyValue is a single entry of a [Dictionary].
+type KeyValue struct {
+	Key   Hasher
+	Value Object
+}
+
+// NewDictionary returns an empty *Dictionary.
+func NewDictionary() *Dictionary {
+	return &Dictionary{buckets: make(map[Hash][]int)}
+}
+
+func (d *Dictionary) Type() ObjectType { return DICTIONARY_OBJECT }
+func (d *Dictionary) String() string {
+	var out strings.Builder
+
+	pairs := []string{}
+	for _, pair := range d.entries {
+		if pair.Key == nil {
+			continue
+		}
+		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.String(), pair.Value.String()))
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// Len returns the number of keys in the dictionary.
+func (d *Dictionary) Len() int { return len(d.entries) - d.removed }
+
+// Get returns the value stored for key.
+func (d *Dictionary) Get(key Hasher) (Object, bool) {
+	i, ok := d.find(key)
+	if !ok {
+		return nil, false
+	}
+	return d.entries[i].Value, true
+}
+
+// Set stores value for key, an existing key keeps its position.
+func (d *Dictionary) Set(key Hasher, value Object) {
+	if i, ok := d.find(key); ok {
+		d.entries[i].Value = value
+		return
+	}
+	h := key.Hash()
+	d.buckets[h] = append(d.buckets[h], len(d.entries))
+	d.entries = append(d.entries, KeyValue{Key: key, Value: value})
+}
+
+// Delete removes key and reports whether it was present.
+func (d *Dictionary) Delete(key Hasher) bool {
+	h := key.Hash()
+	bucket := d.buckets[h]
+	for bi, i := range bucket {
+		if !KeysEqual(d.entries[i].Key, key) {
+			continue
+		}
+		d.entries[i] = KeyValue{}
+		d.removed++
+		if len(bucket) == 1 {
+			delete(d.buckets, h)
+		} else {
+			d.buckets[h] = append(bucket[:bi:bi], bucket[bi+1:]...)
+		}
+		if d.removed > 8 && d.removed > len(d.entries)/2 {
+			d.compact()
+		}
+		return true
+	}
+	return false
+}
+
+// Pairs returns the entries of the dictionary in insertion order.
+func (d *Dictionary) Pairs() []KeyValue {
+	pairs := make([]KeyValue, 0, d.Len())
+	for _, pair := range d.entries {
+		if pair.Key != nil {
+			pairs = append(pairs, pair)
+		}
+	}
+	return pairs
+}
+
+func (d *Dictionary) find(key Hasher) (int, bool) {
+	for _, i := range d.buckets[key.Hash()] {
+		if KeysEqual(d.entries[i].Key, key) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// compact drops the removed entries and rebuilds the buckets.
+func (d *Dictionary) compact() {
+	entries := d.Pairs()
+	d.buckets = make(map[Hash][]int, len(entries))
+	for i, pair := range entries {
+		h := pair.Key.Hash()
+		d.buckets[h] = append(d.buckets[h], i)
+	}
+	d.entries = entries
+	d.removed = 0
+}
+
+// KeysEqual reports whether a and b are the same dictionary key.
+// Numbers are compared like their hash, a float with no fraction equals the same *Number,
+// -0 equals 0 and NaN equals NaN.
+func KeysEqual(a, b Hasher) bool {
+	switch a := a.(type) {
+	case *Number, *Float:
+		switch b.(type) {
+		case *Number, *Float:
+			return a.Hash() == b.Hash()
+		}
+		return false
+	case *String:
+		b, ok := b.(*String)
+		return ok && a.Value == b.Value
+	case *Boolean:
+		b, ok := b.(*Boolean)
+		return ok && a.Value == b.Value
+	}
+	return a == b
+}
@@ -6,6 +6,10 @@ type Environment struct {
 	mu     sync.RWMutex
 	values map[string]Object
 	outer  *Environment
+
+	builtins *Registry // only set on the outermost environment
+	limits   *Limits   // only set on the outermost environment
+	depth    int       // the number of calls in progress, only counted on the outermost environment
 }
 
 func NewEnvironment() *Environment {
@@ -13,6 +17,14 @@ func NewEnvironment() *Environment {
 	return &Environment{values: v, outer: nil}
 }
 
+// NewEnvironmentWithBuiltins returns an outermost environment resolving builtins through builtins
+// instead of Builtins.
+func NewEnvironmentWithBuiltins(builtins *Registry) *Environment {
+	env := NewEnvironment()
+	env.builtins = builtins
+	return env
+}
+
 func NewEnclosedEnvironment(outer *Environment) *Environment {
 	env := NewEnvironment()
 	env.outer = outer
@@ -46,3 +58,44 @@ func (e *Environment) GetIdentifier(name string) (Object, *Environment, bool) {
 	}
 	return obj, returnEnv, ok
 }
+
+// Builtin returns the builtin bound to name in the registry of the outermost environment.
+func (e *Environment) Builtin(name string) (Object, bool) {
+	e = e.root()
+	if e.builtins == nil {
+		return Builtins.Lookup(name)
+	}
+	return e.builtins.Lookup(name)
+}
+
+// SetLimits bounds the programs evaluated in e, e must be an outermost environment.
+func (e *Environment) SetLimits(limits *Limits) {
+	e.limits = limits
+}
+
+// Limits returns the limits of the outermost environment, nil when the program is not bounded.
+func (e *Environment) Limits() *Limits {
+	return e.root().limits
+}
+
+// EnterCall counts a call of a function declared in e and reports whether the calls in progress
+// stay within max, LeaveCall must be called once the call returns.
+func (e *Environment) EnterCall(max int) bool {
+	root := e.root()
+	if root.depth >= max {
+		return false
+	}
+	root.depth++
+	return true
+}
+
+func (e *Environment) LeaveCall() {
+	e.root().depth--
+}
+
+func (e *Environment) root() *Environment {
+	for e.outer != nil {
+		e = e.outer
+	}
+	return e
+}
@@ -0,0 +1,65 @@
+package object
+
+// StrictEqual reports whether a === b. Numbers are compared by value whether they are
+// a *Number or a *Float, strings, booleans and null by value and every other object by identity.
+func StrictEqual(a, b Object) bool {
+	switch a := a.(type) {
+	case *Number, *Float:
+		return isNumeric(b) && numericEqual(a, b)
+	case *String:
+		b, ok := b.(*String)
+		return ok && a.Value == b.Value
+	case *Boolean:
+		b, ok := b.(*Boolean)
+		return ok && a.Value == b.Value
+	case *Null:
+		_, ok := b.(*Null)
+		return ok
+	}
+	return a == b
+}
+
+// LooseEqual reports whether a == b following the abstract equality comparison of JS.
+// null is only equal to null as the language has no undefined.
+func LooseEqual(a, b Object) bool {
+	if a.Type() == b.Type() || isNumeric(a) && isNumeric(b) {
+		return StrictEqual(a, b)
+	}
+
+	_, aNull := a.(*Null)
+	_, bNull := b.(*Null)
+	if aNull || bNull {
+		return false
+	}
+
+	_, aString := a.(*String)
+	_, bString := b.(*String)
+	_, aBool := a.(*Boolean)
+	_, bBool := b.(*Boolean)
+
+	switch {
+	case aBool:
+		return LooseEqual(ToNumber(a), b)
+	case bBool:
+		return LooseEqual(a, ToNumber(b))
+	case isNumeric(a) && bString:
+		return numericEqual(a, ToNumber(b))
+	case aString && isNumeric(b):
+		return numericEqual(ToNumber(a), b)
+	case isPrimitive(a) && !isPrimitive(b):
+		return LooseEqual(a, ToPrimitive(b))
+	case !isPrimitive(a) && isPrimitive(b):
+		return LooseEqual(ToPrimitive(a), b)
+	}
+	return false
+}
+
+// numericEqual compares two numbers, NaN is not equal to anything.
+func numericEqual(a, b Object) bool {
+	if a, ok := a.(*Number); ok {
+		if b, ok := b.(*Number); ok {
+			return a.Value == b.Value
+		}
+	}
+	return toFloat64(a) == toFloat64(b)
+}
@@ -0,0 +1,480 @@
+package object
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// JSONBuiltIn is the global JSON object. Dictionaries are written in insertion order
+// and JSON.parse keeps the order of the keys in the text.
+var JSONBuiltIn = &BuiltInObject{
+	Name: "JSON",
+	Properties: map[string]Object{
+		"parse":     &BuiltIn{Name: "parse", Function: jsonParse},
+		"stringify": &BuiltIn{Name: "stringify", Function: jsonStringify},
+	},
+}
+
+// ParseJSON converts JSON text into objects, objects become dictionaries with string keys.
+// Integers that fit in an int64 become a *Number, every other number a *Float.
+func ParseJSON(text string) (Object, error) {
+	return parseJSON(text, nil)
+}
+
+// parseJSON is ParseJSON accounting the values it creates in limits, running out of memory
+// returns the RangeError of limits.
+func parseJSON(text string, limits *Limits) (Object, error) {
+	dec := &jsonDecoder{Decoder: json.NewDecoder(strings.NewReader(text)), limits: limits}
+	dec.UseNumber()
+
+	val, err := dec.decode()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+	return val, nil
+}
+
+type jsonDecoder struct {
+	*json.Decoder
+	limits *Limits
+}
+
+func (dec *jsonDecoder) decode() (Object, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	switch tok := tok.(type) {
+	case nil:
+		return &Null{}, nil
+	case bool:
+		return &Boolean{Value: tok}, nil
+	case string:
+		if err := dec.limits.AllocBytes(len(tok)); err != nil {
+			return nil, err
+		}
+		return &String{Value: tok}, nil
+	case json.Number:
+		if n, err := strconv.ParseInt(string(tok), 10, 64); err == nil {
+			return &Number{Value: n}, nil
+		}
+		f, err := tok.Float64()
+		if err != nil && !errors.Is(err, strconv.ErrRange) {
+			return nil, err
+		}
+		return &Float{Value: f}, nil
+	case json.Delim:
+		if tok == '[' {
+			arr := &Array{Body: []Object{}}
+			for dec.More() {
+				if err := dec.limits.AllocValues(1); err != nil {
+					return nil, err
+				}
+				el, err := dec.decode()
+				if err != nil {
+					return nil, err
+				}
+				arr.Body = append(arr.Body, el)
+			}
+			_, err := dec.Token()
+			return arr, err
+		}
+
+		dic := NewDictionary()
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			if err := dec.limits.AllocEntries(1); err != nil {
+				return nil, err
+			}
+			val, err := dec.decode()
+			if err != nil {
+				return nil, err
+			}
+			dic.Set(&String{Value: key.(string)}, val)
+		}
+		_, err := dec.Token()
+		return dic, err
+	}
+	return nil, errors.New("unexpected JSON token")
+}
+
+// StringifyJSON converts val to JSON text the way JSON.stringify(val) does.
+func StringifyJSON(val Object) (string, error) {
+	w := &jsonWriter{}
+	if err := w.write(val, ""); err != nil {
+		return "", err
+	}
+	return w.buf.String(), nil
+}
+
+// jsonParse is JSON.parse(text, reviver). The reviver is called bottom up with each key and
+// value, the key of an array element is its index, and its result replaces the value.
+func jsonParse(caller Caller, args ...Object) Object {
+	val, err := parseJSON(stringArg(args, 0), LimitsOf(caller))
+	if err != nil {
+		var limitErr *Error
+		if errors.As(err, &limitErr) {
+			return limitErr
+		}
+		return &Error{Message: "SyntaxError: JSON.parse: " + err.Error(), Err: err}
+	}
+	if len(args) < 2 || !IsCallable(args[1]) {
+		return val
+	}
+	return reviveJSON(caller, args[1], &String{Value: ""}, val)
+}
+
+func reviveJSON(caller Caller, reviver Object, key *String, val Object) Object {
+	switch val := val.(type) {
+	case *Array:
+		for i, el := range val.Body {
+			revived := reviveJSON(caller, reviver, &String{Value: strconv.Itoa(i)}, el)
+			if isError(revived) {
+				return revived
+			}
+			val.Body[i] = revived
+		}
+	case *Dictionary:
+		for _, pair := range val.Pairs() {
+			revived := reviveJSON(caller, reviver, &String{Value: ToString(pair.Key)}, pair.Value)
+			if isError(revived) {
+				return revived
+			}
+			val.Set(pair.Key, revived)
+		}
+	}
+	return caller.Call(reviver, key, val)
+}
+
+// jsonStringify is JSON.stringify(value, replacer, indent). The replacer is either a function
+// called with each key and value whose result is written instead, or an array of the keys to write.
+// Functions are left out of dictionaries and written as null in arrays, a host object is written
+// like a dictionary when it lists its properties. A value that refers back to itself is a TypeError.
+func jsonStringify(caller Caller, args ...Object) Object {
+	w := &jsonWriter{caller: caller, limits: LimitsOf(caller)}
+	if len(args) > 1 {
+		switch replacer := args[1].(type) {
+		case *Array:
+			w.keys = map[string]bool{}
+			for _, key := range replacer.Body {
+				w.keys[ToString(key)] = true
+			}
+		default:
+			if IsCallable(replacer) {
+				w.replacer = replacer
+			}
+		}
+	}
+	if len(args) > 2 {
+		w.indent = jsonIndent(args[2])
+	}
+
+	val := argOrNull(args, 0)
+	if w.replacer != nil {
+		val = w.caller.Call(w.replacer, &String{Value: ""}, val)
+		if err, ok := val.(*Error); ok {
+			return err
+		}
+	}
+	if IsCallable(val) {
+		return nil
+	}
+	if err := w.write(val, ""); err != nil {
+		return err
+	}
+	if err := w.limits.AllocBytes(w.buf.Len()); err != nil {
+		return err
+	}
+	return &String{Value: w.buf.String()}
+}
+
+// jsonIndent returns the indent for a number of spaces or a string, both capped at 10 like JS.
+func jsonIndent(indent Object) string {
+	switch indent := indent.(type) {
+	case *Number, *Float:
+		n := clamp(int(math.Min(toFloat64(indent), 10)), 0, 10)
+		return strings.Repeat(" ", n)
+	case *String:
+		units := indent.CodeUnits()
+		return StringFromCodeUnits(units[:min(len(units), 10)])
+	}
+	return ""
+}
+
+// jsonWriter builds the text of JSON.stringify, every piece is accounted in limits before it is
+// written so a value nested many times over stops at the memory limit instead of after it.
+// The context of limits is checked every checkInterval values written.
+type jsonWriter struct {
+	buf      bytes.Buffer
+	caller   Caller
+	limits   *Limits
+	replacer Object
+	keys     map[string]bool
+	indent   string
+	visiting map[any]struct{}
+	written  int
+}
+
+func (w *jsonWriter) write(val Object, prefix string) *Error {
+	w.written++
+	if w.written%checkInterval == 0 {
+		if err := w.limits.Check(); err != nil {
+			return NewError(err)
+		}
+	}
+	switch val := val.(type) {
+	case nil, *Null:
+		return w.put("null")
+	case *Boolean:
+		return w.put(strconv.FormatBool(val.Value))
+	case *Number:
+		return w.put(strconv.FormatInt(val.Value, 10))
+	case *Float:
+		if math.IsNaN(val.Value) || math.IsInf(val.Value, 0) {
+			return w.put("null")
+		}
+		return w.put(formatFloat(val.Value))
+	case *String:
+		return w.putString(val.Value)
+	case *Array:
+		return w.writeArray(val, prefix)
+	case *Dictionary:
+		return w.writeDictionary(val, prefix)
+	case HostProperties:
+		return w.writeHost(val, prefix)
+	case *BuiltInObject, *Map, *Set, *WeakMap, HostObject:
+		return w.put("{}")
+	default:
+		if IsCallable(val) {
+			return w.put("null")
+		}
+		return w.putString(ToString(val))
+	}
+}
+
+func (w *jsonWriter) writeArray(arr *Array, prefix string) *Error {
+	if err := w.enter(arr); err != nil {
+		return err
+	}
+	if len(arr.Body) == 0 {
+		w.leave(arr)
+		return w.put("[]")
+	}
+
+	inner := prefix + w.indent
+	if err := w.put("["); err != nil {
+		return err
+	}
+	for i, el := range arr.Body {
+		if i > 0 {
+			if err := w.put(","); err != nil {
+				return err
+			}
+		}
+		if err := w.newline(inner); err != nil {
+			return err
+		}
+		el, err := w.replace(&String{Value: strconv.Itoa(i)}, el)
+		if err != nil {
+			return err
+		}
+		if err := w.write(el, inner); err != nil {
+			return err
+		}
+	}
+	if err := w.newline(prefix); err != nil {
+		return err
+	}
+	w.leave(arr)
+	return w.put("]")
+}
+
+func (w *jsonWriter) writeDictionary(dic *Dictionary, prefix string) *Error {
+	pairs := dic.Pairs()
+	names := make([]string, len(pairs))
+	values := make([]Object, len(pairs))
+	for i, pair := range pairs {
+		names[i] = ToString(pair.Key)
+		values[i] = pair.Value
+	}
+	return w.writeObject(dic, names, values, prefix)
+}
+
+// writeHost writes the properties of a host object that can be read, like a dictionary.
+func (w *jsonWriter) writeHost(host HostProperties, prefix string) *Error {
+	var names []string
+	var values []Object
+	for _, name := range host.PropertyNames() {
+		if val, ok := host.Property(name); ok {
+			names = append(names, name)
+			values = append(values, val)
+		}
+	}
+	return w.writeObject(host.Value(), names, values, prefix)
+}
+
+// writeObject writes the names and values of a dictionary or a host object identified by id.
+func (w *jsonWriter) writeObject(id any, names []string, values []Object, prefix string) *Error {
+	if err := w.enter(id); err != nil {
+		return err
+	}
+
+	inner := prefix + w.indent
+	written := 0
+	if err := w.put("{"); err != nil {
+		return err
+	}
+	for i, key := range names {
+		if w.keys != nil && !w.keys[key] {
+			continue
+		}
+		val, err := w.replace(&String{Value: key}, values[i])
+		if err != nil {
+			return err
+		}
+		if IsCallable(val) {
+			continue
+		}
+
+		if written > 0 {
+			if err := w.put(","); err != nil {
+				return err
+			}
+		}
+		written++
+		if err := w.newline(inner); err != nil {
+			return err
+		}
+		if err := w.putString(key); err != nil {
+			return err
+		}
+		colon := ":"
+		if w.indent != "" {
+			colon = ": "
+		}
+		if err := w.put(colon); err != nil {
+			return err
+		}
+		if err := w.write(val, inner); err != nil {
+			return err
+		}
+	}
+	if written > 0 {
+		if err := w.newline(prefix); err != nil {
+			return err
+		}
+	}
+	w.leave(id)
+	return w.put("}")
+}
+
+// put accounts for s and writes it.
+func (w *jsonWriter) put(s string) *Error {
+	if err := w.limits.AllocBytes(len(s)); err != nil {
+		return err
+	}
+	w.buf.WriteString(s)
+	return nil
+}
+
+// putString accounts for s with its quotes and writes it as a JSON string, escapes are not accounted.
+func (w *jsonWriter) putString(s string) *Error {
+	if err := w.limits.AllocBytes(len(s) + 2); err != nil {
+		return err
+	}
+	writeJSONString(&w.buf, s)
+	return nil
+}
+
+// replace returns the value the replacer function gives for key, or val without one.
+func (w *jsonWriter) replace(key *String, val Object) (Object, *Error) {
+	if w.replacer == nil {
+		return val, nil
+	}
+	if val == nil {
+		val = &Null{}
+	}
+	val = w.caller.Call(w.replacer, key, val)
+	if err, ok := val.(*Error); ok {
+		return nil, err
+	}
+	return val, nil
+}
+
+func (w *jsonWriter) newline(prefix string) *Error {
+	if w.indent == "" {
+		return nil
+	}
+	return w.put("\n" + prefix)
+}
+
+// enter records that obj is being written, writing it again inside itself is a cycle.
+func (w *jsonWriter) enter(obj any) *Error {
+	if _, ok := w.visiting[obj]; ok {
+		return &Error{Message: "TypeError: Converting circular structure to JSON"}
+	}
+	if w.visiting == nil {
+		w.visiting = map[any]struct{}{}
+	}
+	w.visiting[obj] = struct{}{}
+	return nil
+}
+
+func (w *jsonWriter) leave(obj any) {
+	delete(w.visiting, obj)
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == utf8.RuneError {
+				buf.WriteString(`\u`)
+				buf.WriteString(strconv.FormatInt(int64(r)|0x10000, 16)[1:])
+				continue
+			}
+			buf.WriteRune(r)
+		}
+	}
+	buf.WriteByte('"')
+}
+
+// IsCallable reports whether obj is a function of either engine or a builtin.
+func IsCallable(obj Object) bool {
+	switch obj.(type) {
+	case *Function, *Closure, *BuiltIn, *BoundMethod:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,192 @@
+package object
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/jf550-kent/jsgo/token"
+)
+
+var (
+	// ErrInterrupted stops a program whose context is done, the error returned also wraps the context error.
+	ErrInterrupted = errors.New("execution interrupted")
+	// ErrBudgetExceeded stops a program that took more steps than its budget.
+	ErrBudgetExceeded = errors.New("step budget exceeded")
+	// ErrMemoryLimit is wrapped by the RangeError of a program allocating more than its memory limit.
+	ErrMemoryLimit = errors.New("memory limit exceeded")
+	// ErrStackOverflow is wrapped by the RangeError of a call nested deeper than the maximum depth.
+	ErrStackOverflow = errors.New("maximum call stack size exceeded")
+)
+
+// DefaultMaxDepth is the number of nested calls a program can make when its limits do not set one.
+const DefaultMaxDepth = 1024
+
+// checkInterval is the number of steps between two checks of the context.
+const checkInterval = 1024
+
+// The approximate sizes used to account for allocations.
+const (
+	valueSize = 16 // an element of an array, the size of an Object interface
+	entrySize = 48 // a dictionary, map or set entry with its bucket index
+)
+
+// Limits bounds a running program by a context, a budget of steps and a memory limit. A step is
+// a loop iteration or a function call, both engines count them so a program that never ends is
+// still stopped. Memory is accounted when arrays, dictionaries and strings are created or grown,
+// it is the total allocated during the run and not what is still reachable.
+// Limits also carry the generator of Math.random so a host can seed each program apart.
+// A nil *Limits never stops a program.
+type Limits struct {
+	ctx    context.Context
+	budget int64
+	steps  int64
+
+	memoryLimit int64
+	allocated   int64
+
+	maxDepth int
+
+	random *rand.Rand
+}
+
+// NewLimits returns limits stopping a program when ctx is done or after budget steps,
+// a budget of 0 or less is unlimited.
+func NewLimits(ctx context.Context, budget int64) *Limits {
+	return &Limits{ctx: ctx, budget: budget}
+}
+
+// Step counts one step. It returns ErrBudgetExceeded when the budget is spent and ErrInterrupted
+// when the context is done, the context is checked every checkInterval steps.
+func (l *Limits) Step() error {
+	if l == nil {
+		return nil
+	}
+	l.steps++
+	if l.budget > 0 && l.steps > l.budget {
+		return ErrBudgetExceeded
+	}
+	if l.steps%checkInterval == 0 {
+		return l.Check()
+	}
+	return nil
+}
+
+// Check returns ErrInterrupted if the context is done.
+func (l *Limits) Check() error {
+	if l == nil {
+		return nil
+	}
+	if err := l.ctx.Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInterrupted, err)
+	}
+	return nil
+}
+
+// Steps returns the number of steps counted so far.
+func (l *Limits) Steps() int64 {
+	if l == nil {
+		return 0
+	}
+	return l.steps
+}
+
+// SetMemoryLimit stops the program with a RangeError once it allocated more than bytes,
+// 0 or less removes the limit.
+func (l *Limits) SetMemoryLimit(bytes int64) {
+	l.memoryLimit = bytes
+}
+
+// Allocated returns the number of bytes accounted so far.
+func (l *Limits) Allocated() int64 {
+	if l == nil {
+		return 0
+	}
+	return l.allocated
+}
+
+// AllocValues accounts for n array elements, it must be called before they are allocated.
+func (l *Limits) AllocValues(n int) *Error {
+	return l.alloc(mulSize(n, valueSize))
+}
+
+// AllocEntries accounts for n dictionary, map or set entries.
+func (l *Limits) AllocEntries(n int) *Error {
+	return l.alloc(mulSize(n, entrySize))
+}
+
+// AllocBytes accounts for a string of n bytes.
+func (l *Limits) AllocBytes(n int) *Error {
+	return l.alloc(int64(n))
+}
+
+func (l *Limits) alloc(bytes int64) *Error {
+	if l == nil {
+		return nil
+	}
+	l.allocated = min(l.allocated+bytes, math.MaxInt64/2)
+	if l.memoryLimit > 0 && l.allocated > l.memoryLimit {
+		return &Error{
+			Message: fmt.Sprintf("RangeError: memory limit of %d bytes exceeded", l.memoryLimit),
+			Err:     ErrMemoryLimit,
+		}
+	}
+	return nil
+}
+
+// mulSize returns n elements of size bytes, saturating instead of overflowing.
+func mulSize(n int, size int64) int64 {
+	if int64(n) > math.MaxInt64/(2*size) {
+		return math.MaxInt64 / 2
+	}
+	return int64(n) * size
+}
+
+// SetMaxDepth sets the number of nested calls after which a call is a RangeError,
+// 0 or less is DefaultMaxDepth.
+func (l *Limits) SetMaxDepth(depth int) {
+	l.maxDepth = depth
+}
+
+// MaxDepth returns the number of nested calls a program can make.
+func (l *Limits) MaxDepth() int {
+	if l == nil || l.maxDepth <= 0 {
+		return DefaultMaxDepth
+	}
+	return l.maxDepth
+}
+
+// SetRandom makes Math.random draw from r, nil uses a generator shared by every program.
+// The host keeps r across runs to continue one sequence.
+func (l *Limits) SetRandom(r *rand.Rand) {
+	l.random = r
+}
+
+// Random returns the generator of Math.random, nil when the program uses the shared one.
+func (l *Limits) Random() *rand.Rand {
+	if l == nil {
+		return nil
+	}
+	return l.random
+}
+
+// StackOverflowError is the RangeError of calling the function name declared at pos one level too deep.
+func StackOverflowError(name string, pos token.Pos) *Error {
+	if name == "" {
+		name = "anonymous"
+	}
+	return &Error{
+		Message: fmt.Sprintf("RangeError: Maximum call stack size exceeded in %s %d:%d", name, pos.Line, pos.Col),
+		Err:     ErrStackOverflow,
+	}
+}
+
+// LimitsOf returns the limits of the engine calling a builtin, nil when it has none.
+func LimitsOf(caller Caller) *Limits {
+	if c, ok := caller.(interface{ Limits() *Limits }); ok {
+		return c.Limits()
+	}
+	return nil
+}
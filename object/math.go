@@ -0,0 +1,154 @@
+package object
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// MathBuiltIn is the global Math object. Rounding, abs, min, max and pow on integers
+// return a *Number when the result is an integer, every other result is a *Float.
+var MathBuiltIn = &BuiltInObject{
+	Name: "Math",
+	Properties: map[string]Object{
+		"PI":     &Float{Value: math.Pi},
+		"E":      &Float{Value: math.E},
+		"floor":  mathRounding("floor", math.Floor),
+		"ceil":   mathRounding("ceil", math.Ceil),
+		"round":  mathRounding("round", roundHalfUp),
+		"trunc":  mathRounding("trunc", math.Trunc),
+		"abs":    &BuiltIn{Name: "abs", Function: mathAbs},
+		"min":    &BuiltIn{Name: "min", Function: mathMinMax(math.Inf(1), func(a, b float64) bool { return a < b })},
+		"max":    &BuiltIn{Name: "max", Function: mathMinMax(math.Inf(-1), func(a, b float64) bool { return a > b })},
+		"pow":    &BuiltIn{Name: "pow", Function: mathPow},
+		"sqrt":   mathFloat("sqrt", math.Sqrt),
+		"sin":    mathFloat("sin", math.Sin),
+		"cos":    mathFloat("cos", math.Cos),
+		"tan":    mathFloat("tan", math.Tan),
+		"log":    mathFloat("log", math.Log),
+		"exp":    mathFloat("exp", math.Exp),
+		"atan2":  &BuiltIn{Name: "atan2", Function: mathAtan2},
+		"random": &BuiltIn{Name: "random", Function: mathRandom},
+	},
+}
+
+var (
+	randomMu sync.Mutex
+	random   = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// mathRandom draws from the generator of the limits of the caller, programs without one share
+// a generator seeded with the time.
+func mathRandom(caller Caller, _ ...Object) Object {
+	if r := LimitsOf(caller).Random(); r != nil {
+		return &Float{Value: r.Float64()}
+	}
+	randomMu.Lock()
+	defer randomMu.Unlock()
+	return &Float{Value: random.Float64()}
+}
+
+// mathRounding returns an integer argument as is and rounds a float with round.
+func mathRounding(name string, round func(float64) float64) *BuiltIn {
+	return &BuiltIn{
+		Name: name,
+		Function: func(_ Caller, args ...Object) Object {
+			num := numericArg(args, 0)
+			if _, ok := num.(*Number); ok {
+				return num
+			}
+			return numberResult(round(toFloat64(num)))
+		},
+	}
+}
+
+// roundHalfUp rounds half way values towards +Infinity as Math.round does, math.Round rounds away from zero.
+func roundHalfUp(f float64) float64 {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return f
+	}
+	r := math.Floor(f)
+	if f-r >= 0.5 {
+		r++
+	}
+	if r == 0 && math.Signbit(f) {
+		return math.Copysign(0, -1)
+	}
+	return r
+}
+
+func mathFloat(name string, fn func(float64) float64) *BuiltIn {
+	return &BuiltIn{
+		Name: name,
+		Function: func(_ Caller, args ...Object) Object {
+			return &Float{Value: fn(toFloat64(numericArg(args, 0)))}
+		},
+	}
+}
+
+func mathAbs(_ Caller, args ...Object) Object {
+	switch num := numericArg(args, 0).(type) {
+	case *Number:
+		if num.Value >= 0 {
+			return num
+		}
+		if num.Value == math.MinInt64 {
+			return &Float{Value: -float64(num.Value)}
+		}
+		return &Number{Value: -num.Value}
+	case *Float:
+		return &Float{Value: math.Abs(num.Value)}
+	}
+	return &Float{Value: math.NaN()}
+}
+
+// mathMinMax returns the argument for which better holds against every other, NaN if any argument is NaN.
+func mathMinMax(empty float64, better func(a, b float64) bool) BuiltInFunction {
+	return func(_ Caller, args ...Object) Object {
+		var result Object = &Float{Value: empty}
+		for i := range args {
+			num := numericArg(args, i)
+			f := toFloat64(num)
+			if math.IsNaN(f) {
+				return &Float{Value: math.NaN()}
+			}
+			if i == 0 || better(f, toFloat64(result)) {
+				result = num
+			}
+		}
+		return result
+	}
+}
+
+func mathPow(_ Caller, args ...Object) Object {
+	base, exponent := numericArg(args, 0), numericArg(args, 1)
+	f := math.Pow(toFloat64(base), toFloat64(exponent))
+	if _, ok := base.(*Number); ok {
+		if exp, ok := exponent.(*Number); ok && exp.Value >= 0 && math.Abs(f) < 1<<53 {
+			return &Number{Value: int64(f)}
+		}
+	}
+	return &Float{Value: f}
+}
+
+func mathAtan2(_ Caller, args ...Object) Object {
+	return &Float{Value: math.Atan2(toFloat64(numericArg(args, 0)), toFloat64(numericArg(args, 1)))}
+}
+
+// numericArg returns argument i converted with ToNumber, a missing argument is NaN.
+func numericArg(args []Object, i int) Object {
+	if i >= len(args) {
+		return &Float{Value: math.NaN()}
+	}
+	return ToNumber(args[i])
+}
+
+// numberResult returns f as a *Number when it is an integer that fits, otherwise as a *Float.
+// -0 stays a *Float so its sign is kept.
+func numberResult(f float64) Object {
+	if f == math.Trunc(f) && math.Abs(f) < 1<<63 && !(f == 0 && math.Signbit(f)) {
+		return &Number{Value: int64(f)}
+	}
+	return &Float{Value: f}
+}
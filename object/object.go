@@ -3,11 +3,13 @@ package object
 import (
 	"fmt"
 	"hash/fnv"
+	"math"
 	"strconv"
 	"strings"
 
 	"github.com/jf550-kent/jsgo/ast"
 	"github.com/jf550-kent/jsgo/bytecode"
+	"github.com/jf550-kent/jsgo/token"
 )
 
 type ObjectType string
@@ -30,6 +32,12 @@ const (
 	DICTIONARY_OBJECT        ObjectType = "DICTIONARY_OBJECT"
 	BYTECODE_FUNCTION_OBJECT ObjectType = "BYTECODE_FUNCTION_OBJECT"
 	CLOSURE_OBJ              ObjectType = "CLOSURE"
+	BUILT_IN_OBJECT_OBJECT   ObjectType = "BUILT_IN_OBJECT"
+	BOUND_METHOD_OBJECT      ObjectType = "BOUND_METHOD"
+	MAP_OBJECT               ObjectType = "MAP"
+	SET_OBJECT               ObjectType = "SET"
+	WEAK_MAP_OBJECT          ObjectType = "WEAK_MAP"
+	HOST_OBJECT              ObjectType = "HOST_OBJECT"
 )
 
 // Object is used in the evaluator to represent value in when evaluating the AST of JSGO.
@@ -53,7 +61,34 @@ type Float struct {
 
 func (f *Float) String() string   { return strconv.FormatFloat(f.Value, 'f', -1, 64) }
 func (f *Float) Type() ObjectType { return FLOAT_OBJECT }
-func (f *Float) Hash() Hash       { return Hash{Type: f.Type(), Key: uint64(f.Value)} }
+
+// Hash of a float with no fraction is the hash of the equal *Number, so 1.0 and 1 are the same key.
+func (f *Float) Hash() Hash {
+	if n, ok := floatInt(f.Value); ok {
+		return Hash{Type: NUMBER_OBJECT, Key: uint64(n)}
+	}
+	return Hash{Type: f.Type(), Key: floatKey(f.Value)}
+}
+
+// floatKey returns the bits of f with every NaN folded into one and -0 folded into 0,
+// so equal keys have the same hash.
+func floatKey(f float64) uint64 {
+	switch {
+	case f != f:
+		return 0x7FF8000000000001
+	case f == 0:
+		return 0
+	}
+	return math.Float64bits(f)
+}
+
+// floatInt returns f as an int64 when it has no fraction and fits in one.
+func floatInt(f float64) (int64, bool) {
+	if f == math.Trunc(f) && math.Abs(f) < 1<<63 {
+		return int64(f), true
+	}
+	return 0, false
+}
 
 // Boolean represent the boolean value in the language when evaluating the ast
 type Boolean struct {
@@ -81,6 +116,8 @@ type Function struct {
 	Parameters []*ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
+	Name       string    // the variable the function was declared to, empty when anonymous
+	Pos        token.Pos // where the function was declared
 }
 
 func (f *Function) String() string {
@@ -134,70 +171,190 @@ func (a *Array) String() string {
 	return out.String()
 }
 
-type BuiltInFunction func(args ...Object) Object
+// Caller calls a function value from inside a builtin, each engine passes itself as the
+// Caller so builtins such as map and sort can run script callbacks. An error is returned as *Error.
+type Caller interface {
+	Call(fn Object, args ...Object) Object
+}
+
+type BuiltInFunction func(caller Caller, args ...Object) Object
 type BuiltIn struct {
-	Name     string
+	Name string
+	// Arity is the number of parameters the function declares, 0 when it takes any number.
+	Arity    int
 	Function BuiltInFunction
 }
 
 func (b *BuiltIn) Type() ObjectType { return BUITL_IN_OBJECT }
 func (b *BuiltIn) String() string   { return b.Name }
 
-// ReturnValue represent the value that is being returned
-type ReturnValue struct {
-	Value Object
+// BuiltInObject is a builtin namespace such as console, its properties are accessed with the dot operator.
+type BuiltInObject struct {
+	Name       string
+	Properties map[string]Object
 }
 
-func (rv *ReturnValue) String() string   { return rv.Value.String() }
-func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJECT }
+func (b *BuiltInObject) Type() ObjectType { return BUILT_IN_OBJECT_OBJECT }
+func (b *BuiltInObject) String() string   { return b.Name }
 
-// Hasher verify that the Object can be used as a dictionary key.
-type Hasher interface {
-	Hash() Hash
-	String() string
+// HostObject is a value of the host program, its properties are read and assigned with the dot operator.
+// Its Type must be HOST_OBJECT.
+type HostObject interface {
+	Object
+	Property(name string) (Object, bool)
+	// SetProperty returns the error the program sees when name cannot be set to val.
+	SetProperty(name string, val Object) *Error
 }
 
-type KeyValue struct {
-	Key   Object
-	Value Object
+// HostProperties is a HostObject whose properties can be listed, JSON.stringify writes it like a
+// dictionary of them and the other host objects as {}. Value is the host value it exposes, two
+// host objects exposing the same value are the same object when looking for cycles.
+type HostProperties interface {
+	HostObject
+	PropertyNames() []string
+	Value() any
 }
 
-type Dictionary struct {
-	Value map[Hash]KeyValue
+// BoundMethod is a builtin method together with the receiver it was accessed from, arr.push.
+// The receiver is passed as the first argument when called.
+type BoundMethod struct {
+	Receiver Object
+	Method   *BuiltIn
 }
 
-func (d *Dictionary) Type() ObjectType { return DICTIONARY_OBJECT }
-func (d *Dictionary) String() string {
-	var out strings.Builder
+func (b *BoundMethod) Type() ObjectType { return BOUND_METHOD_OBJECT }
+func (b *BoundMethod) String() string   { return b.Method.Name }
 
-	pairs := []string{}
-	for _, pair := range d.Value {
-		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.String(), pair.Value.String()))
-	}
+// Call invokes the method with the receiver followed by args.
+func (b *BoundMethod) Call(caller Caller, args ...Object) Object {
+	callArgs := make([]Object, 0, len(args)+1)
+	callArgs = append(callArgs, b.Receiver)
+	callArgs = append(callArgs, args...)
+	return b.Method.Function(caller, callArgs...)
+}
 
-	out.WriteString("{")
-	out.WriteString(strings.Join(pairs, ", "))
-	out.WriteString("}")
+// ReturnValue represent the value that is being returned
+type ReturnValue struct {
+	Value Object
+}
 
-	return out.String()
+func (rv *ReturnValue) String() string   { return rv.Value.String() }
+func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJECT }
+
+// Hasher verify that the Object can be used as a dictionary key.
+type Hasher interface {
+	Object
+	Hash() Hash
 }
 
 type BytecodeFunction struct {
 	Instructions bytecode.Instructions
 	NumLocals    int
+	DebugInfo
+}
+
+// DebugInfo maps a compiled function back to its source for errors and tools, the VM does not
+// need it to run the function.
+type DebugInfo struct {
+	Name   string             // the variable the function was declared to, empty when anonymous
+	Pos    token.Pos          // where the function was declared
+	Lines  bytecode.LineTable // the line and column of every instruction
+	Locals []string           // the names of the parameters and local variables by index
+	// Callees is the variable each call calls by the offset of its OpCall, when it is one.
+	Callees map[int]string
 }
 
 func (b *BytecodeFunction) Type() ObjectType { return BYTECODE_FUNCTION_OBJECT }
 func (b *BytecodeFunction) String() string   { return fmt.Sprintf("BytecodeFunction[%p]", b) }
 
 // Error represent the error object in when evaluating the AST.
+// Err is the Go error it was made from, it is kept so errors.Is and errors.As
+// still match after the error crossed a builtin that called back into the program.
 type Error struct {
 	Message string
+	Err     error
+
+	File  string       // the file of the program that raised the error, empty when unknown
+	Pos   token.Pos    // where the error was raised, the zero Pos when unknown
+	Stack []StackFrame // the calls the error left, innermost first
+}
+
+// StackFrame is a call left by an error: the function called and where it was called from.
+// Call is the zero Pos when the function was called by a builtin or by the host.
+type StackFrame struct {
+	Function string
+	Call     token.Pos
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJECT }
 func (e *Error) String() string   { return "error: " + e.Message }
 func (e *Error) Error() string    { return e.Message }
+func (e *Error) Unwrap() error    { return e.Err }
+
+// At sets where the error was raised unless it is already known.
+func (e *Error) At(pos token.Pos) {
+	if e.Pos.Line == 0 {
+		e.Pos = pos
+	}
+}
+
+// Called records that the error left the function named name, called at call.
+func (e *Error) Called(name string, call token.Pos) {
+	e.Stack = append(e.Stack, StackFrame{Function: name, Call: call})
+}
+
+// StackTrace returns the message followed by a line for every call in progress when the error
+// was raised, innermost first, and the program itself when it made the outermost call.
+//
+//	TypeError: ...
+//	    at inner (main.js:2:10)
+//	    at outer (main.js:5:3)
+//	    at <main> (main.js:7:1)
+func (e *Error) StackTrace() string {
+	var out strings.Builder
+	out.WriteString(e.Message)
+
+	pos := e.Pos
+	for _, frame := range e.Stack {
+		name := frame.Function
+		if name == "" {
+			name = "<anonymous>"
+		}
+		fmt.Fprintf(&out, "\n    at %s (%s)", name, e.position(pos))
+		pos = frame.Call
+	}
+	if pos.Line != 0 {
+		fmt.Fprintf(&out, "\n    at <main> (%s)", e.position(pos))
+	}
+	return out.String()
+}
+
+func (e *Error) position(pos token.Pos) string {
+	if pos.Line == 0 {
+		return "native"
+	}
+	if e.File == "" {
+		return fmt.Sprintf("%d:%d", pos.Line, pos.Col)
+	}
+	return fmt.Sprintf("%s:%d:%d", e.File, pos.Line, pos.Col)
+}
+
+// NewError wraps err into an *Error, err is returned as is if it already is one.
+func NewError(err error) *Error {
+	if e, ok := err.(*Error); ok {
+		return e
+	}
+	return &Error{Message: err.Error(), Err: err}
+}
+
+// NotFunctionError is the error of calling fn when it is not a function, name is the variable
+// fn was called through and empty when the callee is not a variable.
+func NotFunctionError(fn Object, name string) *Error {
+	if name == "" {
+		return &Error{Message: fmt.Sprintf("not a function: %s", fn.Type())}
+	}
+	return &Error{Message: fmt.Sprintf("not a function: %s (%s)", name, fn.Type())}
+}
 
 func ConvertFloat(node Object) *Float {
 	switch node := node.(type) {
@@ -0,0 +1,136 @@
+package object
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Builtins is the registry used by both engines when the host does not give one.
+// A host can register its own functions here before compiling or evaluating a program.
+var Builtins = NewStandardRegistry()
+
+// Registry holds the global builtins of a program by name. Both engines resolve a global
+// that is not a variable through it, the compiler refers to an entry by its index so the
+// index of a name never changes once defined. A registry must not change while a program
+// using it is running.
+type Registry struct {
+	entries []RegistryEntry
+	index   map[string]int
+}
+
+type RegistryEntry struct {
+	Name  string
+	Value Object
+}
+
+func NewRegistry() *Registry {
+	return &Registry{index: map[string]int{}}
+}
+
+// NewStandardRegistry returns a registry holding console, String, Math, JSON, Map, Set and WeakMap.
+func NewStandardRegistry() *Registry {
+	r := NewRegistry()
+	r.Define("console", Console)
+	r.Define("String", StringBuiltIn)
+	r.Define("Math", MathBuiltIn)
+	r.Define("JSON", JSONBuiltIn)
+	r.Define("Map", MapBuiltIn)
+	r.Define("Set", SetBuiltIn)
+	r.Define("WeakMap", WeakMapBuiltIn)
+	return r
+}
+
+// Define binds name to value and returns its index, redefining a name keeps its index.
+func (r *Registry) Define(name string, value Object) int {
+	if i, ok := r.index[name]; ok {
+		r.entries[i].Value = value
+		return i
+	}
+	r.entries = append(r.entries, RegistryEntry{Name: name, Value: value})
+	r.index[name] = len(r.entries) - 1
+	return len(r.entries) - 1
+}
+
+// Function registers fn under name. A dotted name such as "console.warn" adds fn to the
+// namespace before the dot, the namespace is created if it does not exist and copied if it
+// does so a shared one such as Math is left unchanged for other registries. A name with more
+// dots nests namespaces the same way. A call with fewer than arity arguments has the missing
+// ones set to null.
+// Function panics when a namespace in name is bound to a value that is not a *BuiltInObject,
+// such as Map, or when a part of name is empty.
+func (r *Registry) Function(name string, arity int, fn BuiltInFunction) *BuiltIn {
+	path := strings.Split(name, ".")
+	if slices.Contains(path, "") {
+		panic(fmt.Sprintf("object: invalid builtin name %q", name))
+	}
+	short := path[len(path)-1]
+
+	b := &BuiltIn{Name: short, Arity: arity, Function: fn}
+	if arity > 0 {
+		b.Function = func(caller Caller, args ...Object) Object {
+			for len(args) < arity {
+				args = append(args, &Null{})
+			}
+			return fn(caller, args...)
+		}
+	}
+	if len(path) == 1 {
+		r.Define(name, b)
+		return b
+	}
+
+	old, _ := r.Lookup(path[0])
+	r.Define(path[0], withProperty(old, path[0], path[1:], b))
+	return b
+}
+
+// withProperty returns a copy of the namespace old named name, a new one when old is nil,
+// with value set at path inside it. The namespaces along path are copied the same way.
+func withProperty(old Object, name string, path []string, value Object) *BuiltInObject {
+	ns := &BuiltInObject{Name: name, Properties: map[string]Object{}}
+	if old != nil {
+		old, ok := old.(*BuiltInObject)
+		if !ok {
+			panic(fmt.Sprintf("object: cannot add a builtin to %s, it is not a namespace", name))
+		}
+		ns.Properties = maps.Clone(old.Properties)
+	}
+	if len(path) == 1 {
+		ns.Properties[path[0]] = value
+		return ns
+	}
+	ns.Properties[path[0]] = withProperty(ns.Properties[path[0]], name+"."+path[0], path[1:], value)
+	return ns
+}
+
+// Lookup returns the value bound to name.
+func (r *Registry) Lookup(name string) (Object, bool) {
+	i, ok := r.index[name]
+	if !ok {
+		return nil, false
+	}
+	return r.entries[i].Value, true
+}
+
+// Index returns the index of name, the operand of the instruction loading it.
+func (r *Registry) Index(name string) (int, bool) {
+	i, ok := r.index[name]
+	return i, ok
+}
+
+// At returns the value at index, nil if there is no such entry.
+func (r *Registry) At(index int) Object {
+	if index < 0 || index >= len(r.entries) {
+		return nil
+	}
+	return r.entries[index].Value
+}
+
+func (r *Registry) Len() int { return len(r.entries) }
+
+// Entries returns the entries in the order they were defined, the position of an entry is its index.
+func (r *Registry) Entries() []RegistryEntry {
+	return append([]RegistryEntry(nil), r.entries...)
+}
@@ -0,0 +1,550 @@
+package object
+
+import (
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Strings are stored as UTF-8 but JS measures and indexes them in UTF-16 code units,
+// a character outside the Basic Multilingual Plane counts as two units.
+
+// MaxStringLength is the most UTF-16 code units a string built by repeat or padding can hold,
+// a longer one is a RangeError.
+const MaxStringLength = 1<<29 - 24
+
+func invalidStringLength() *Error {
+	return &Error{Message: "RangeError: Invalid string length"}
+}
+
+// Length returns the number of UTF-16 code units in s.
+func (s *String) Length() int {
+	n := 0
+	for i := 0; i < len(s.Value); {
+		if s.Value[i] < utf8.RuneSelf {
+			n++
+			i++
+			continue
+		}
+		r, width := utf8.DecodeRuneInString(s.Value[i:])
+		n += unitLen(r)
+		i += width
+	}
+	return n
+}
+
+// unitLen returns the number of UTF-16 code units needed to encode r.
+func unitLen(r rune) int {
+	if r >= 0x10000 && r <= utf8.MaxRune {
+		return 2
+	}
+	return 1
+}
+
+// CodeUnits returns s encoded as UTF-16.
+func (s *String) CodeUnits() []uint16 {
+	return utf16.Encode([]rune(s.Value))
+}
+
+// CodeUnitAt returns the UTF-16 code unit at index i, ok is false when i is out of range.
+func (s *String) CodeUnitAt(i int) (uint16, bool) {
+	if i < 0 {
+		return 0, false
+	}
+	n := 0
+	for _, r := range s.Value {
+		size := unitLen(r)
+		if i < n+size {
+			if size == 1 {
+				return uint16(r), true
+			}
+			high, low := utf16.EncodeRune(r)
+			if i == n {
+				return uint16(high), true
+			}
+			return uint16(low), true
+		}
+		n += size
+	}
+	return 0, false
+}
+
+// StringFromCodeUnits decodes UTF-16 code units into a string,
+// an unpaired surrogate becomes U+FFFD as it cannot be held in UTF-8.
+func StringFromCodeUnits(units []uint16) string {
+	return string(utf16.Decode(units))
+}
+
+// CompareStrings compares a and b by their UTF-16 code units the way the JS relational operators do.
+// The result is 0 if a == b, -1 if a < b, and +1 if a > b.
+func CompareStrings(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if a[i] < utf8.RuneSelf && b[j] < utf8.RuneSelf {
+			if a[i] != b[j] {
+				return compareUnits(uint16(a[i]), uint16(b[j]))
+			}
+			i++
+			j++
+			continue
+		}
+		ra, wa := utf8.DecodeRuneInString(a[i:])
+		rb, wb := utf8.DecodeRuneInString(b[j:])
+		if ra != rb {
+			return compareRunes(ra, rb)
+		}
+		i += wa
+		j += wb
+	}
+	switch {
+	case i < len(a):
+		return 1
+	case j < len(b):
+		return -1
+	}
+	return 0
+}
+
+// compareRunes compares two different runes by their UTF-16 encoding.
+func compareRunes(a, b rune) int {
+	ua := utf16.AppendRune(nil, a)
+	ub := utf16.AppendRune(nil, b)
+	if ua[0] != ub[0] {
+		return compareUnits(ua[0], ub[0])
+	}
+	return compareUnits(ua[1], ub[1])
+}
+
+func compareUnits(a, b uint16) int {
+	if a < b {
+		return -1
+	}
+	return 1
+}
+
+// StringMethods are the methods reachable from a string with the dot operator,
+// the string is passed as the first argument. Positions and lengths are in UTF-16 code units.
+var StringMethods = map[string]*BuiltIn{
+	"slice":       stringMethod("slice", stringSlice),
+	"substring":   stringMethod("substring", stringSubstring),
+	"indexOf":     stringMethod("indexOf", stringIndexOf),
+	"lastIndexOf": stringMethod("lastIndexOf", stringLastIndexOf),
+	"includes":    stringMethod("includes", stringIncludes),
+	"startsWith":  stringMethod("startsWith", stringStartsWith),
+	"endsWith":    stringMethod("endsWith", stringEndsWith),
+	"split":       allocatingStringMethod("split", splitSize, stringSplit),
+	"trim":        stringMethod("trim", stringTrim(strings.TrimFunc)),
+	"trimStart":   stringMethod("trimStart", stringTrim(strings.TrimLeftFunc)),
+	"trimEnd":     stringMethod("trimEnd", stringTrim(strings.TrimRightFunc)),
+	"toUpperCase": allocatingStringMethod("toUpperCase", caseSize, stringCase(strings.ToUpper)),
+	"toLowerCase": allocatingStringMethod("toLowerCase", caseSize, stringCase(strings.ToLower)),
+	"replace":     allocatingStringMethod("replace", replaceSize(1), stringReplace(1)),
+	"replaceAll":  allocatingStringMethod("replaceAll", replaceSize(-1), stringReplace(-1)),
+	"padStart":    allocatingStringMethod("padStart", padSize, stringPad(true)),
+	"padEnd":      allocatingStringMethod("padEnd", padSize, stringPad(false)),
+	"repeat":      allocatingStringMethod("repeat", repeatSize, stringRepeat),
+	"charCodeAt":  stringMethod("charCodeAt", stringCharCodeAt),
+}
+
+// StringBuiltIn is the global String object.
+var StringBuiltIn = &BuiltInObject{
+	Name: "String",
+	Properties: map[string]Object{
+		"fromCharCode": &BuiltIn{
+			Name: "fromCharCode",
+			Function: func(_ Caller, args ...Object) Object {
+				units := make([]uint16, len(args))
+				for i, arg := range args {
+					units[i] = uint16(ToInt32(arg))
+				}
+				return &String{Value: StringFromCodeUnits(units)}
+			},
+		},
+	},
+}
+
+// stringMethod wraps fn into a BuiltIn that checks its receiver is a string.
+func stringMethod(name string, fn func(s *String, args []Object) Object) *BuiltIn {
+	return &BuiltIn{
+		Name: name,
+		Function: func(_ Caller, args ...Object) Object {
+			if len(args) == 0 {
+				return &Error{Message: name + " called without a string"}
+			}
+			s, ok := args[0].(*String)
+			if !ok {
+				return &Error{Message: name + " called on non string " + args[0].String()}
+			}
+			return fn(s, args[1:])
+		},
+	}
+}
+
+// allocatingStringMethod is stringMethod for a method whose result can be far larger than its
+// receiver, size returns the bytes of the result which are accounted before it is built.
+func allocatingStringMethod(name string, size func(s *String, args []Object) float64, fn func(s *String, args []Object) Object) *BuiltIn {
+	method := stringMethod(name, fn)
+	call := method.Function
+	method.Function = func(caller Caller, args ...Object) Object {
+		if len(args) > 0 {
+			if s, ok := args[0].(*String); ok {
+				bytes := int(min(size(s, args[1:]), math.MaxInt32))
+				if err := LimitsOf(caller).AllocBytes(bytes); err != nil {
+					return err
+				}
+			}
+		}
+		return call(caller, args...)
+	}
+	return method
+}
+
+func padSize(s *String, args []Object) float64 {
+	if len(args) == 0 {
+		return 0
+	}
+	return math.Max(toFloat64(ToNumber(args[0]))-float64(s.Length()), 0)
+}
+
+func repeatSize(s *String, args []Object) float64 {
+	if len(args) == 0 {
+		return 0
+	}
+	count := toFloat64(ToNumber(args[0]))
+	if math.IsNaN(count) || count < 0 {
+		return 0
+	}
+	return float64(len(s.Value)) * count
+}
+
+// splitSize is the bytes of the parts and of the array holding them.
+func splitSize(s *String, args []Object) float64 {
+	parts := 1
+	if len(args) > 0 {
+		if sep := ToString(args[0]); sep != "" {
+			parts = strings.Count(s.Value, sep) + 1
+		} else {
+			parts = s.Length()
+		}
+	}
+	if limit, ok := numberArg(args, 1); ok {
+		parts = clamp(limit, 0, parts)
+	}
+	return float64(len(s.Value)) + float64(parts)*valueSize
+}
+
+func caseSize(s *String, _ []Object) float64 {
+	return float64(len(s.Value))
+}
+
+// replaceSize is the bytes of the string with n matches replaced, every match when n is negative.
+// A $& in the replacement is counted as the pattern and a $` or $' as the whole string.
+func replaceSize(n int) func(*String, []Object) float64 {
+	return func(s *String, args []Object) float64 {
+		pattern := stringArg(args, 0)
+		replacement := stringArg(args, 1)
+		matches := strings.Count(s.Value, pattern)
+		if n >= 0 {
+			matches = min(matches, n)
+		}
+		size := float64(len(replacement)) +
+			float64(len(pattern))*float64(strings.Count(replacement, "$&")) +
+			float64(len(s.Value))*float64(strings.Count(replacement, "$`")+strings.Count(replacement, "$'"))
+		return float64(len(s.Value)) + float64(matches)*size
+	}
+}
+
+func stringSlice(s *String, args []Object) Object {
+	units := s.CodeUnits()
+	n := len(units)
+	start := relativeIndex(integerArg(args, 0, 0), n)
+	end := relativeIndex(integerArg(args, 1, n), n)
+	if start >= end {
+		return &String{Value: ""}
+	}
+	return &String{Value: StringFromCodeUnits(units[start:end])}
+}
+
+func stringSubstring(s *String, args []Object) Object {
+	units := s.CodeUnits()
+	n := len(units)
+	start := clamp(integerArg(args, 0, 0), 0, n)
+	end := clamp(integerArg(args, 1, n), 0, n)
+	if start > end {
+		start, end = end, start
+	}
+	return &String{Value: StringFromCodeUnits(units[start:end])}
+}
+
+func stringIndexOf(s *String, args []Object) Object {
+	units := s.CodeUnits()
+	search := utf16.Encode([]rune(stringArg(args, 0)))
+	from := clamp(integerArg(args, 1, 0), 0, len(units))
+	return &Number{Value: int64(indexUnits(units, search, from))}
+}
+
+func stringLastIndexOf(s *String, args []Object) Object {
+	units := s.CodeUnits()
+	search := utf16.Encode([]rune(stringArg(args, 0)))
+	from := len(units) - len(search)
+	if pos, ok := numberArg(args, 1); ok {
+		from = min(from, clamp(pos, 0, len(units)))
+	}
+	for i := from; i >= 0; i-- {
+		if unitsHavePrefix(units[i:], search) {
+			return &Number{Value: int64(i)}
+		}
+	}
+	return &Number{Value: -1}
+}
+
+func stringIncludes(s *String, args []Object) Object {
+	units := s.CodeUnits()
+	search := utf16.Encode([]rune(stringArg(args, 0)))
+	from := clamp(integerArg(args, 1, 0), 0, len(units))
+	return &Boolean{Value: indexUnits(units, search, from) >= 0}
+}
+
+func stringStartsWith(s *String, args []Object) Object {
+	units := s.CodeUnits()
+	search := utf16.Encode([]rune(stringArg(args, 0)))
+	start := clamp(integerArg(args, 1, 0), 0, len(units))
+	return &Boolean{Value: unitsHavePrefix(units[start:], search)}
+}
+
+func stringEndsWith(s *String, args []Object) Object {
+	units := s.CodeUnits()
+	search := utf16.Encode([]rune(stringArg(args, 0)))
+	end := clamp(integerArg(args, 1, len(units)), 0, len(units))
+	start := end - len(search)
+	return &Boolean{Value: start >= 0 && unitsHavePrefix(units[start:end], search)}
+}
+
+func stringSplit(s *String, args []Object) Object {
+	limit := -1
+	if l, ok := numberArg(args, 1); ok {
+		limit = max(l, 0)
+	}
+	if len(args) == 0 {
+		return newStringArray([]string{s.Value}, limit)
+	}
+	sep := ToString(args[0])
+	if sep != "" {
+		return newStringArray(strings.Split(s.Value, sep), limit)
+	}
+	units := s.CodeUnits()
+	parts := make([]string, len(units))
+	for i, u := range units {
+		parts[i] = StringFromCodeUnits([]uint16{u})
+	}
+	return newStringArray(parts, limit)
+}
+
+func newStringArray(parts []string, limit int) *Array {
+	if limit >= 0 && limit < len(parts) {
+		parts = parts[:limit]
+	}
+	body := make([]Object, len(parts))
+	for i, part := range parts {
+		body[i] = &String{Value: part}
+	}
+	return &Array{Body: body}
+}
+
+func stringTrim(trim func(string, func(rune) bool) string) func(*String, []Object) Object {
+	return func(s *String, _ []Object) Object {
+		return &String{Value: trim(s.Value, isJSSpace)}
+	}
+}
+
+// isJSSpace reports whether r is white space or a line terminator as trim sees it.
+func isJSSpace(r rune) bool {
+	return unicode.IsSpace(r) || r == '\uFEFF'
+}
+
+func stringCase(convert func(string) string) func(*String, []Object) Object {
+	return func(s *String, _ []Object) Object {
+		return &String{Value: convert(s.Value)}
+	}
+}
+
+// stringReplace replaces the first n matches of the pattern, every match when n is negative.
+// The replacement understands the $$, $&, $` and $' patterns.
+func stringReplace(n int) func(*String, []Object) Object {
+	return func(s *String, args []Object) Object {
+		pattern := stringArg(args, 0)
+		replacement := stringArg(args, 1)
+
+		var out strings.Builder
+		last := 0
+		for i := 0; n < 0 || i < n; i++ {
+			idx := strings.Index(s.Value[last:], pattern)
+			if idx < 0 {
+				break
+			}
+			idx += last
+			out.WriteString(s.Value[last:idx])
+			expandReplacement(&out, replacement, s.Value, idx, idx+len(pattern))
+			last = idx + len(pattern)
+
+			if pattern == "" {
+				if last == len(s.Value) {
+					return &String{Value: out.String()}
+				}
+				_, width := utf8.DecodeRuneInString(s.Value[last:])
+				out.WriteString(s.Value[last : last+width])
+				last += width
+			}
+		}
+		out.WriteString(s.Value[last:])
+		return &String{Value: out.String()}
+	}
+}
+
+func expandReplacement(out *strings.Builder, replacement, s string, start, end int) {
+	for i := 0; i < len(replacement); i++ {
+		if replacement[i] != '$' || i+1 == len(replacement) {
+			out.WriteByte(replacement[i])
+			continue
+		}
+		switch replacement[i+1] {
+		case '$':
+			out.WriteByte('$')
+		case '&':
+			out.WriteString(s[start:end])
+		case '`':
+			out.WriteString(s[:start])
+		case '\'':
+			out.WriteString(s[end:])
+		default:
+			out.WriteByte('$')
+			continue
+		}
+		i++
+	}
+}
+
+func stringPad(atStart bool) func(*String, []Object) Object {
+	return func(s *String, args []Object) Object {
+		units := s.CodeUnits()
+		target := integerArg(args, 0, 0)
+		pad := " "
+		if len(args) > 1 {
+			pad = ToString(args[1])
+		}
+		if target <= len(units) || pad == "" {
+			return s
+		}
+		if target > MaxStringLength {
+			return invalidStringLength()
+		}
+
+		padUnits := utf16.Encode([]rune(pad))
+		fill := make([]uint16, target-len(units))
+		for i := range fill {
+			fill[i] = padUnits[i%len(padUnits)]
+		}
+		if atStart {
+			return &String{Value: StringFromCodeUnits(append(fill, units...))}
+		}
+		return &String{Value: StringFromCodeUnits(append(units, fill...))}
+	}
+}
+
+func stringRepeat(s *String, args []Object) Object {
+	count := 0.0
+	if len(args) > 0 {
+		count = toFloat64(ToNumber(args[0]))
+	}
+	if math.IsNaN(count) {
+		count = 0
+	}
+	if count < 0 || math.IsInf(count, 1) {
+		return &Error{Message: "RangeError: Invalid count value: " + formatFloat(count)}
+	}
+	if s.Value == "" || count < 1 {
+		return &String{Value: ""}
+	}
+	if float64(s.Length())*count > MaxStringLength {
+		return invalidStringLength()
+	}
+	return &String{Value: strings.Repeat(s.Value, int(count))}
+}
+
+func stringCharCodeAt(s *String, args []Object) Object {
+	unit, ok := s.CodeUnitAt(integerArg(args, 0, 0))
+	if !ok {
+		return &Float{Value: math.NaN()}
+	}
+	return &Number{Value: int64(unit)}
+}
+
+// indexUnits returns the index of the first search in units at or after from, or -1.
+func indexUnits(units, search []uint16, from int) int {
+	for i := from; i+len(search) <= len(units); i++ {
+		if unitsHavePrefix(units[i:], search) {
+			return i
+		}
+	}
+	return -1
+}
+
+func unitsHavePrefix(units, prefix []uint16) bool {
+	if len(prefix) > len(units) {
+		return false
+	}
+	for i, u := range prefix {
+		if units[i] != u {
+			return false
+		}
+	}
+	return true
+}
+
+// stringArg returns argument i converted to a string, a missing argument is "undefined" as in JS.
+func stringArg(args []Object, i int) string {
+	if i >= len(args) {
+		return "undefined"
+	}
+	return ToString(args[i])
+}
+
+// numberArg returns argument i converted to an integer, ok is false when the argument is missing.
+// NaN converts to 0 and infinities saturate.
+func numberArg(args []Object, i int) (n int, ok bool) {
+	if i >= len(args) {
+		return 0, false
+	}
+	f := math.Trunc(toFloat64(ToNumber(args[i])))
+	switch {
+	case math.IsNaN(f):
+		return 0, true
+	case f >= math.MaxInt32:
+		return math.MaxInt32, true
+	case f <= math.MinInt32:
+		return math.MinInt32, true
+	}
+	return int(f), true
+}
+
+// integerArg is numberArg with def used for a missing argument.
+func integerArg(args []Object, i, def int) int {
+	if n, ok := numberArg(args, i); ok {
+		return n
+	}
+	return def
+}
+
+// relativeIndex resolves a negative index from the end of a sequence of length n and clamps it into [0, n].
+func relativeIndex(i, n int) int {
+	if i < 0 {
+		i += n
+	}
+	return clamp(i, 0, n)
+}
+
+func clamp(i, lo, hi int) int {
+	return max(lo, min(i, hi))
+}
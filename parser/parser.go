@@ -9,7 +9,8 @@ import (
 	"github.com/jf550-kent/jsgo/token"
 )
 
-// every parse<> needs to make the current parser to point at ; then is up to the line:37 to skip ;
+// every parse<> leaves the current token at the last token of the statement, [parser.parse] then
+// ends the statement with [parser.consumeSemicolon] following the automatic semicolon insertion rules.
 
 type (
 	unaryExpressionFunc  func() ast.Expression
@@ -36,18 +37,21 @@ const (
 )
 
 var precedences = map[token.TokenType]int{
-	token.EQUAL:     EQUALS,
-	token.NOT_EQUAL: EQUALS,
-	token.GTR:       LESSGREATER,
-	token.LSS:       LESSGREATER,
-	token.ADD:       SUM,
-	token.MINUS:     SUM,
-	token.DIVIDE:    PRODUCT,
-	token.MUL:       PRODUCT,
-	token.LPAREN:    CALL,
-	token.LBRACKET:  INDEX,
-	token.SHL:       LESSGREATER,
-	token.XOR:       EQUALS,
+	token.EQUAL:            EQUALS,
+	token.NOT_EQUAL:        EQUALS,
+	token.STRICT_EQUAL:     EQUALS,
+	token.STRICT_NOT_EQUAL: EQUALS,
+	token.GTR:              LESSGREATER,
+	token.LSS:              LESSGREATER,
+	token.ADD:              SUM,
+	token.MINUS:            SUM,
+	token.DIVIDE:           PRODUCT,
+	token.MUL:              PRODUCT,
+	token.LPAREN:           CALL,
+	token.LBRACKET:         INDEX,
+	token.DOT:              INDEX,
+	token.SHL:              LESSGREATER,
+	token.XOR:              EQUALS,
 }
 
 func Parse(filename string, src []byte) *ast.Main {
@@ -103,41 +107,77 @@ func new(filename string, l *lexer.Lexer) *parser {
 		token.LBRACKET: p.parseArrayExpression,
 		token.NULL:     p.parseNullExpression,
 		token.LBRACE:   p.parseDictionary,
+		token.DELETE:   p.parseDeleteExpression,
+		token.NEW:      p.parseNewExpression,
 	}
 
 	p.binaryExpressionFunc = map[token.TokenType]binaryExpressionFunc{
-		token.ADD:       p.parseBinaryExpression,
-		token.MINUS:     p.parseBinaryExpression,
-		token.MUL:       p.parseBinaryExpression,
-		token.DIVIDE:    p.parseBinaryExpression,
-		token.LSS:       p.parseBinaryExpression,
-		token.GTR:       p.parseBinaryExpression,
-		token.NOT_EQUAL: p.parseBinaryExpression,
-		token.EQUAL:     p.parseBinaryExpression,
-		token.LPAREN:    p.parseCallExpression,
-		token.LBRACKET:  p.parseIndexExpression,
-		token.SHL:       p.parseBinaryExpression,
-		token.XOR:       p.parseBinaryExpression,
+		token.ADD:              p.parseBinaryExpression,
+		token.MINUS:            p.parseBinaryExpression,
+		token.MUL:              p.parseBinaryExpression,
+		token.DIVIDE:           p.parseBinaryExpression,
+		token.LSS:              p.parseBinaryExpression,
+		token.GTR:              p.parseBinaryExpression,
+		token.NOT_EQUAL:        p.parseBinaryExpression,
+		token.EQUAL:            p.parseBinaryExpression,
+		token.STRICT_EQUAL:     p.parseBinaryExpression,
+		token.STRICT_NOT_EQUAL: p.parseBinaryExpression,
+		token.LPAREN:           p.parseCallExpression,
+		token.LBRACKET:         p.parseIndexExpression,
+		token.DOT:              p.parseMemberExpression,
+		token.SHL:              p.parseBinaryExpression,
+		token.XOR:              p.parseBinaryExpression,
 	}
 
 	return p
 }
 
 func (p *parser) parse() ast.Statement {
+	var stmt ast.Statement
 	switch p.currentToken.TokenType {
+	case token.SEMICOLON:
+		// empty statement
+		return nil
+	case token.FOR:
+		return p.parseForStatement()
 	case token.VAR:
-		return p.parseVarStatement()
+		stmt = p.parseVarStatement()
 	case token.RETURN:
-		return p.parseReturnStatement()
+		stmt = p.parseReturnStatement()
 	case token.IDENT:
-		switch p.nextToken.TokenType {
-		case token.ASSIGN:
-			return p.parseAssignmentStatement()
+		if p.peekExpect(token.ASSIGN) {
+			stmt = p.parseAssignmentStatement()
+			break
 		}
-	case token.FOR:
-		return p.parseForStatement()
+		stmt = p.parseExpressionStatement()
+	default:
+		stmt = p.parseExpressionStatement()
+	}
+
+	if stmt == nil {
+		return nil
+	}
+	if exprStmt, ok := stmt.(*ast.ExpressionStatement); ok {
+		if _, ok := exprStmt.Expression.(*ast.IFExpression); ok {
+			return stmt
+		}
+	}
+	p.consumeSemicolon(stmt)
+	return stmt
+}
+
+// consumeSemicolon ends stmt by consuming the ; after it. When there is no ; a semicolon is
+// inserted only if the next token is preceded by a line terminator, is } or is the end of input,
+// otherwise it is a syntax error.
+func (p *parser) consumeSemicolon(stmt ast.Statement) {
+	switch {
+	case p.peekExpect(token.SEMICOLON):
+		p.next()
+	case p.peekExpect(token.RBRACE), p.peekExpect(token.EOF), p.nextToken.NewLine:
+	default:
+		errMsg := fmt.Sprintf("%s : expect ; before %s", stmt, p.nextToken.Literal)
+		p.panicError(errMsg, SYNTAX_ERROR, p.nextToken.Start)
 	}
-	return p.parseExpressionStatement()
 }
 
 func (p *parser) parseVarStatement() ast.Statement {
@@ -171,26 +211,20 @@ func (p *parser) parseVarStatement() ast.Statement {
 		f.Name = varStmt.Variable.Literal
 	}
 
-	if p.peekExpect(token.SEMICOLON) {
-		p.next()
-	}
-
 	return varStmt
 }
 
 func (p *parser) parseReturnStatement() ast.Statement {
 	st := &ast.ReturnStatement{Token: p.currentToken}
-	p.next()
-
-	st.ReturnExpression = p.parseExpression(1)
 
-	if !p.peekExpect(token.SEMICOLON) {
-		errMsg := st.String() + " :expect ; after expression in return statement"
-		p.panicError(errMsg, SYNTAX_ERROR, st.End())
-		return nil
+	// return is a restricted production, a line terminator after it ends the statement
+	if p.peekExpect(token.SEMICOLON) || p.peekExpect(token.RBRACE) || p.peekExpect(token.EOF) || p.nextToken.NewLine {
+		return st
 	}
 	p.next()
 
+	st.ReturnExpression = p.parseExpression(1)
+
 	return st
 }
 
@@ -201,10 +235,6 @@ func (p *parser) parseExpressionStatement() ast.Statement {
 	if stmt.Expression == nil {
 		return nil
 	}
-
-	if p.peekExpect(token.SEMICOLON) {
-		p.next()
-	}
 	return stmt
 }
 
@@ -226,9 +256,6 @@ func (p *parser) parseAssignmentStatement() ast.Statement {
 	p.next()
 	assign.Expression = p.parseExpression(1)
 
-	if p.peekExpect(token.SEMICOLON) {
-		p.next()
-	}
 	return assign
 }
 
@@ -320,6 +347,30 @@ func (p *parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	return exp
 }
 
+// parseMemberExpression parses apple.color, an assignment apple.color = <expression>
+// is parsed into an [ast.BracketDeclaration] the same as apple["color"] = <expression>
+// A keyword is a valid property name, map.delete(key).
+func (p *parser) parseMemberExpression(left ast.Expression) ast.Expression {
+	startTok := p.currentToken
+	if !p.peekExpect(token.IDENT) && !token.IsKeyword(p.nextToken.Literal) {
+		err := left.String() + ". : expect property name after ."
+		p.panicError(err, SYNTAX_ERROR, p.nextToken.Start)
+	}
+	p.next()
+	property := &ast.Identifier{Token: p.currentToken, Literal: p.currentToken.Literal}
+
+	if p.peekExpect(token.ASSIGN) {
+		p.next()
+		key := &ast.String{Token: property.Token, Value: property.Literal}
+		dicDecl := &ast.BracketDeclaration{Token: startTok, Identifier: left, Key: key}
+		p.next()
+		dicDecl.Value = p.parseExpression(LOWEST)
+
+		return dicDecl
+	}
+	return &ast.Member{Token: startTok, Identifier: left, Property: property}
+}
+
 func (p *parser) parseUnaryExpression() ast.Expression {
 	ury := &ast.UnaryExpression{
 		Token:    p.currentToken,
@@ -331,6 +382,38 @@ func (p *parser) parseUnaryExpression() ast.Expression {
 	return ury
 }
 
+// parseDeleteExpression parses delete apple.color and delete apple["color"],
+// only a property access can be deleted.
+func (p *parser) parseDeleteExpression() ast.Expression {
+	del := &ast.DeleteExpression{Token: p.currentToken}
+	p.next()
+	del.Expression = p.parseExpression(PREFIX)
+
+	switch del.Expression.(type) {
+	case *ast.Index, *ast.Member:
+	default:
+		errMsg := del.String() + " : delete expects a property access"
+		p.panicError(errMsg, SYNTAX_ERROR, del.Start())
+	}
+	return del
+}
+
+// parseNewExpression parses new Map() and new Set([1, 2]), without an argument list
+// new Map is the same as new Map(). new Map().size accesses the size of the new map.
+func (p *parser) parseNewExpression() ast.Expression {
+	exp := &ast.NewExpression{Token: p.currentToken}
+	p.next()
+	constructor := p.parseExpression(CALL)
+
+	if !p.peekExpect(token.LPAREN) {
+		exp.Call = &ast.CallExpression{Token: exp.Token, Function: constructor, Arguments: []ast.Expression{}}
+		return exp
+	}
+	p.next()
+	exp.Call = p.parseCallExpression(constructor).(*ast.CallExpression)
+	return exp
+}
+
 func (p *parser) parseIFExpression() ast.Expression {
 	exp := &ast.IFExpression{Token: p.currentToken}
 
@@ -368,10 +451,6 @@ func (p *parser) parseIFExpression() ast.Expression {
 		exp.Else = p.parseBlockStatement()
 	}
 
-	if p.peekExpect(token.SEMICOLON) {
-		p.next()
-	}
-
 	return exp
 }
 
@@ -395,9 +474,6 @@ func (p *parser) parseFunctionDeclaration() ast.Expression {
 
 	f.Body = p.parseBlockStatement()
 
-	if p.peekExpect(token.SEMICOLON) {
-		p.next()
-	}
 	return f
 }
 
@@ -470,8 +546,13 @@ func (p *parser) parseForStatement() ast.Statement {
 	}
 	p.next()
 
+	// semicolons are never inserted in the for header
 	if !p.expect(token.SEMICOLON) {
 		forStmt.Init = p.parseVarStatement()
+		if !p.peekExpect(token.SEMICOLON) {
+			p.panicError(fmt.Sprintf("%s : expecting ; after init", forStmt), SYNTAX_ERROR, p.currentToken.End)
+		}
+		p.next()
 	}
 	p.next()
 
@@ -493,9 +574,6 @@ func (p *parser) parseForStatement() ast.Statement {
 	p.next()
 	forStmt.Body = p.parseBlockStatement()
 
-	if p.peekExpect(token.SEMICOLON) {
-		p.next()
-	}
 	return forStmt
 }
 
@@ -566,6 +644,7 @@ func (p *parser) parseDictionary() ast.Expression {
 		value := p.parseExpression(LOWEST)
 
 		dic.Object[key] = value
+		dic.Keys = append(dic.Keys, key)
 		p.next()
 	}
 
@@ -100,6 +100,64 @@ func TestReturn(t *testing.T) {
 	}
 }
 
+func TestAutomaticSemicolonInsertion(t *testing.T) {
+	tests := []struct {
+		input      string
+		statements int
+	}{
+		{"var a = 1\nvar b = 2", 2},
+		{"a = 1\nb = 2\n", 2},
+		{"var f = function() { return 1 }\nf()", 2},
+		{"a\n(b)", 1},
+		{"a\n+ b", 1},
+		{"a\n[0]", 1},
+		{";;a;;", 1},
+		{"if (a) { b } c", 2},
+		{"a\u2028b", 2},
+	}
+
+	for _, tt := range tests {
+		main := Parse("", []byte(tt.input))
+		if len(main.Statements) != tt.statements {
+			t.Errorf("%q: wrong number of statements. expected=%d, got=%d", tt.input, tt.statements, len(main.Statements))
+		}
+	}
+}
+
+func TestRestrictedReturn(t *testing.T) {
+	main := Parse("", []byte("function() { return\na + b }"))
+
+	exp := checkStatement[*ast.ExpressionStatement](t, main.Statements[0])
+	fn := checkExpression[*ast.FunctionDeclaration](t, exp.Expression)
+	if len(fn.Body.Statements) != 2 {
+		t.Fatalf("wrong number of statements in body. got=%d", len(fn.Body.Statements))
+	}
+	returnStmt := checkStatement[*ast.ReturnStatement](t, fn.Body.Statements[0])
+	if returnStmt.ReturnExpression != nil {
+		t.Errorf("return followed by a new line should not have an expression. got=%s", returnStmt.ReturnExpression)
+	}
+}
+
+func TestMissingSemicolon(t *testing.T) {
+	tests := []string{
+		"var a = 1 var b = 2",
+		"a = 1 b",
+		"return 1 2",
+		"for (var i = 0\n i < 2; i = i + 1) {}",
+	}
+
+	for _, input := range tests {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("%q: expected a syntax error", input)
+				}
+			}()
+			Parse("", []byte(input))
+		}()
+	}
+}
+
 func TestBinaryExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -542,6 +600,113 @@ func TestParsingIndexString(t *testing.T) {
 	testValueExpression(t, index.Index, "length")
 }
 
+func TestParsingMember(t *testing.T) {
+	input := `node.next.value; node.next = tail;`
+
+	main := Parse("", []byte(input))
+	if len(main.Statements) != 2 {
+		t.Fatalf("wrong number of statements. got=%d", len(main.Statements))
+	}
+
+	expr := checkStatement[*ast.ExpressionStatement](t, main.Statements[0])
+	member := checkExpression[*ast.Member](t, expr.Expression)
+	if member.Property.Literal != "value" {
+		t.Errorf("wrong property. got=%s", member.Property.Literal)
+	}
+	if member.String() != "((node.next).value)" {
+		t.Errorf("wrong member string. got=%s", member.String())
+	}
+	inner := checkExpression[*ast.Member](t, member.Identifier)
+	testIdentifier(t, inner.Identifier, "node")
+
+	declExpr := checkStatement[*ast.ExpressionStatement](t, main.Statements[1])
+	dcl := checkExpression[*ast.BracketDeclaration](t, declExpr.Expression)
+	testIdentifier(t, dcl.Identifier, "node")
+	testString(t, dcl.Key, "next")
+	testIdentifier(t, dcl.Value, "tail")
+}
+
+func TestParsingMemberCall(t *testing.T) {
+	input := `console.log(arr.length);`
+
+	main := Parse("", []byte(input))
+
+	expr := checkStatement[*ast.ExpressionStatement](t, main.Statements[0])
+	call := checkExpression[*ast.CallExpression](t, expr.Expression)
+	fn := checkExpression[*ast.Member](t, call.Function)
+	testIdentifier(t, fn.Identifier, "console")
+	if fn.Property.Literal != "log" {
+		t.Errorf("wrong property. got=%s", fn.Property.Literal)
+	}
+	arg := checkExpression[*ast.Member](t, call.Arguments[0])
+	testIdentifier(t, arg.Identifier, "arr")
+}
+
+func TestParsingMemberError(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("expected a syntax error for a missing property name")
+		}
+	}()
+	Parse("", []byte(`node.5;`))
+}
+
+func TestParsingDelete(t *testing.T) {
+	main := Parse("", []byte(`delete apple.color; delete apple["taste"];`))
+	if len(main.Statements) != 2 {
+		t.Fatalf("wrong number of statements. got=%d", len(main.Statements))
+	}
+
+	expr := checkStatement[*ast.ExpressionStatement](t, main.Statements[0])
+	del := checkExpression[*ast.DeleteExpression](t, expr.Expression)
+	member := checkExpression[*ast.Member](t, del.Expression)
+	testIdentifier(t, member.Identifier, "apple")
+
+	expr = checkStatement[*ast.ExpressionStatement](t, main.Statements[1])
+	del = checkExpression[*ast.DeleteExpression](t, expr.Expression)
+	index := checkExpression[*ast.Index](t, del.Expression)
+	testString(t, index.Index, "taste")
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("expected a syntax error when deleting a variable")
+		}
+	}()
+	Parse("", []byte(`delete apple;`))
+}
+
+func TestParsingNew(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"new Map();", "(new Map())"},
+		{"new Map;", "(new Map())"},
+		{"new Set([1, 2]);", "(new Set([1, 2]))"},
+		{"new Map().size;", "((new Map()).size)"},
+		{"new Map().get(1);", "((new Map()).get)(1)"},
+		{"new lib.Map(1, 2);", "(new (lib.Map)(1, 2))"},
+		{"new Map().delete(1);", "((new Map()).delete)(1)"},
+	}
+
+	for _, tt := range tests {
+		main := Parse("", []byte(tt.input))
+		expr := checkStatement[*ast.ExpressionStatement](t, main.Statements[0])
+		if expr.Expression.String() != tt.expected {
+			t.Errorf("wrong expression. expected=%q, got=%q", tt.expected, expr.Expression.String())
+		}
+	}
+
+	main := Parse("", []byte("new Set(a);"))
+	expr := checkStatement[*ast.ExpressionStatement](t, main.Statements[0])
+	n := checkExpression[*ast.NewExpression](t, expr.Expression)
+	testIdentifier(t, n.Call.Function, "Set")
+	if len(n.Call.Arguments) != 1 {
+		t.Fatalf("wrong number of arguments. got=%d", len(n.Call.Arguments))
+	}
+	testIdentifier(t, n.Call.Arguments[0], "a")
+}
+
 func TestParsingEmptyDictionary(t *testing.T) {
 	input := "{}"
 
@@ -4,9 +4,12 @@ import (
 	"strconv"
 )
 
+// Pos is a position in the source text. Col counts runes from the start of the
+// line while Offset is the byte offset from the start of the source.
 type Pos struct {
-	Line int
-	Col  int
+	Line   int
+	Col    int
+	Offset int
 }
 
 // Token is samllest valid element of the language
@@ -15,6 +18,7 @@ type Token struct {
 	Literal   string
 	Start     Pos
 	End       Pos
+	NewLine   bool // a line terminator appears between the previous token and this token
 }
 
 // TokenType represent the set of valid token type in the language
@@ -45,8 +49,10 @@ const (
 	BANG   // !
 	ASSIGN // =
 
-	NOT_EQUAL // !=
-	EQUAL     // ==
+	NOT_EQUAL        // !=
+	EQUAL            // ==
+	STRICT_NOT_EQUAL // !==
+	STRICT_EQUAL     // ===
 
 	COMMA     // ,
 	SEMICOLON // ;
@@ -81,6 +87,8 @@ const (
 	FALSE    // false
 	FOR      // for
 	NULL
+	DELETE // delete
+	NEW    // new
 
 	keywordEnd
 )
@@ -97,57 +105,63 @@ var keywords = map[string]TokenType{
 	"false":    FALSE,
 	"for":      FOR,
 	"null":     NULL,
+	"delete":   DELETE,
+	"new":      NEW,
 }
 
 // tokens store the repective string representation of the token
 var tokens = [...]string{
-	ILLEGAL:   "ILLEGAL",
-	EOF:       "EOF",
-	IDENT:     "IDENTIFIER",
-	NUMBER:    "NUMBER",
-	STRING:    "STRING",
-	FLOAT:     "FLOAT",
-	ADD:       "+",
-	MINUS:     "-",
-	MUL:       "*",
-	DIVIDE:    "/",
-	LSS:       "<",
-	GTR:       ">",
-	BANG:      "!",
-	ASSIGN:    "=",
-	NOT_EQUAL: "!=",
-	EQUAL:     "==",
-	COMMA:     ",",
-	SEMICOLON: ";",
-	DOT:       ".",
-	COLON:     ":",
-	LPAREN:    "(",
-	RPAREN:    ")",
-	LBRACE:    "{",
-	RBRACE:    "}",
-	LBRACKET:  "[",
-	RBRACKET:  "]",
-	AND:       "&",
-	OR:        "|",
-	XOR:       "^",
-	SHL:       "<<",
-	SHR:       ">>",
-	AND_NOT:   "&^",
-	FUNCTION:  "function",
-	VAR:       "var",
-	IF:        "if",
-	ELSE:      "else",
-	ELSEIF:    "elseif",
-	RETURN:    "return",
-	TRUE:      "true",
-	FALSE:     "false",
-	FOR:       "for",
-	NULL:      "null",
+	ILLEGAL:          "ILLEGAL",
+	EOF:              "EOF",
+	IDENT:            "IDENTIFIER",
+	NUMBER:           "NUMBER",
+	STRING:           "STRING",
+	FLOAT:            "FLOAT",
+	ADD:              "+",
+	MINUS:            "-",
+	MUL:              "*",
+	DIVIDE:           "/",
+	LSS:              "<",
+	GTR:              ">",
+	BANG:             "!",
+	ASSIGN:           "=",
+	NOT_EQUAL:        "!=",
+	EQUAL:            "==",
+	STRICT_NOT_EQUAL: "!==",
+	STRICT_EQUAL:     "===",
+	COMMA:            ",",
+	SEMICOLON:        ";",
+	DOT:              ".",
+	COLON:            ":",
+	LPAREN:           "(",
+	RPAREN:           ")",
+	LBRACE:           "{",
+	RBRACE:           "}",
+	LBRACKET:         "[",
+	RBRACKET:         "]",
+	AND:              "&",
+	OR:               "|",
+	XOR:              "^",
+	SHL:              "<<",
+	SHR:              ">>",
+	AND_NOT:          "&^",
+	FUNCTION:         "function",
+	VAR:              "var",
+	IF:               "if",
+	ELSE:             "else",
+	ELSEIF:           "elseif",
+	RETURN:           "return",
+	TRUE:             "true",
+	FALSE:            "false",
+	FOR:              "for",
+	NULL:             "null",
+	DELETE:           "delete",
+	NEW:              "new",
 }
 
 func (t Token) Precedence() int {
 	switch t.TokenType {
-	case EQUAL, NOT_EQUAL:
+	case EQUAL, NOT_EQUAL, STRICT_EQUAL, STRICT_NOT_EQUAL:
 		return 2
 	case LSS, GTR:
 		return 3
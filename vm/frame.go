@@ -3,6 +3,7 @@ package vm
 import (
 	"github.com/jf550-kent/jsgo/bytecode"
 	"github.com/jf550-kent/jsgo/object"
+	"github.com/jf550-kent/jsgo/token"
 )
 
 type Frame struct {
@@ -22,3 +23,9 @@ func NewFrame(fn *object.Closure, basePointer int) *Frame {
 func (f *Frame) Instructions() bytecode.Instructions {
 	return f.function.Fn.Instructions
 }
+
+// Pos returns the source position of the instruction the frame is at, the zero Pos when unknown.
+func (f *Frame) Pos() token.Pos {
+	pos, _ := f.function.Fn.Lines.Lookup(max(f.ip, 0))
+	return pos
+}
@@ -7,12 +7,18 @@ import (
 	"github.com/jf550-kent/jsgo/bytecode"
 	"github.com/jf550-kent/jsgo/compiler"
 	"github.com/jf550-kent/jsgo/object"
+	"github.com/jf550-kent/jsgo/token"
 )
 
 const (
-	STACK_SIZE           = 2048
-	MAX_GLOBAL_VARIABLES = 65536
-	MAX_FRAMES           = 1024
+	MAX_STACK_SIZE       = 1 << 20 // the default ceiling of the stack, see SetMaxStackSize
+	MAX_GLOBAL_VARIABLES = 65536   // globals are addressed by a 16 bit operand
+)
+
+// The stack and the frames start small and double when they are full.
+const (
+	initialStackSize = 64
+	initialFrames    = 16
 )
 
 var (
@@ -24,34 +30,79 @@ var (
 type VM struct {
 	constants []object.Object
 	globals   []object.Object
+	builtins  *object.Registry
+
+	file        string   // the file of the program, reported by its errors
+	globalNames []string // the names of the globals by index, reported by their errors
 
 	stack        []object.Object
 	stackPointer int // Must always points to the new value, the object at the top of the stack is stack[stackPointer -1]
+	maxStack     int
 
 	frames      []*Frame
 	framesIndex int
+
+	limits *object.Limits
 }
 
 func New(bytecode *compiler.Bytecode) *VM {
-	mainFn := &object.BytecodeFunction{Instructions: bytecode.Instructions}
+	mainFn := &object.BytecodeFunction{
+		Instructions: bytecode.Instructions,
+		DebugInfo:    object.DebugInfo{Lines: bytecode.Lines, Callees: bytecode.Callees},
+	}
 	mainClosure := &object.Closure{Fn: mainFn}
 	mainFrame := NewFrame(mainClosure, 0)
 
-	frames := make([]*Frame, MAX_FRAMES)
+	frames := make([]*Frame, initialFrames)
 	frames[0] = mainFrame
 
+	builtins := bytecode.Builtins
+	if builtins == nil {
+		builtins = object.Builtins
+	}
+
 	return &VM{
 		constants: bytecode.Constants,
-		globals:   make([]object.Object, MAX_GLOBAL_VARIABLES),
+		builtins:  builtins,
 
-		stack:        make([]object.Object, STACK_SIZE),
+		file:        bytecode.File,
+		globalNames: bytecode.Globals,
+		globals:     make([]object.Object, bytecode.NumGlobals),
+
+		stack:        make([]object.Object, initialStackSize),
 		stackPointer: 0,
+		maxStack:     MAX_STACK_SIZE,
 
 		frames:      frames,
 		framesIndex: 1,
 	}
 }
 
+// NewWithGlobals returns a VM using globals as its global store so values set by an earlier
+// program stay visible. globals must hold bytecode.NumGlobals elements, a global set past its
+// end is only stored in the VM.
+func NewWithGlobals(bytecode *compiler.Bytecode, globals []object.Object) *VM {
+	vm := New(bytecode)
+	vm.globals = globals
+	return vm
+}
+
+// SetMaxStackSize sets the number of values the stack can grow to, a program needing more
+// fails with a RangeError. The depth of calls is bounded by the limits.
+func (vm *VM) SetMaxStackSize(size int) {
+	vm.maxStack = size
+}
+
+// SetLimits bounds the programs run by vm, a nil limits leaves them unbounded.
+func (vm *VM) SetLimits(limits *object.Limits) {
+	vm.limits = limits
+}
+
+// Limits returns the limits of vm, builtins account their allocations to them.
+func (vm *VM) Limits() *object.Limits {
+	return vm.limits
+}
+
 func (vm *VM) StackTop() object.Object {
 	if vm.stackPointer == 0 {
 		return nil
@@ -61,6 +112,34 @@ func (vm *VM) StackTop() object.Object {
 }
 
 func (vm *VM) Run() error {
+	return vm.run(1)
+}
+
+// run executes instructions until the frame at depth returns, the main frame at depth 1
+// never returns and runs to the end of its instructions. An error is returned as an
+// *object.Error located at the instruction that raised it with the calls it left.
+func (vm *VM) run(depth int) error {
+	err := vm.execute(depth)
+	if err == nil {
+		return nil
+	}
+	e := object.NewError(err)
+	e.At(vm.currentFrame().Pos())
+	// the frame at depth - 1 was called by a builtin and the main frame is not a call
+	for i := vm.framesIndex - 1; i >= depth-1 && i > 0; i-- {
+		var call token.Pos
+		if i > depth-1 {
+			call = vm.frames[i-1].Pos()
+		}
+		e.Called(vm.frames[i].function.Fn.Name, call)
+	}
+	if e.File == "" {
+		e.File = vm.file
+	}
+	return e
+}
+
+func (vm *VM) execute(depth int) error {
 	var ip int
 	var ins bytecode.Instructions
 	var op bytecode.Opcode
@@ -93,7 +172,7 @@ func (vm *VM) Run() error {
 			if err := vm.push(FALSE); err != nil {
 				return err
 			}
-		case bytecode.OpEqual, bytecode.OpGreaterThan, bytecode.OpNotEqual:
+		case bytecode.OpEqual, bytecode.OpGreaterThan, bytecode.OpNotEqual, bytecode.OpStrictEqual, bytecode.OpStrictNotEqual:
 			if err := vm.runComparison(op); err != nil {
 				return err
 			}
@@ -118,6 +197,12 @@ func (vm *VM) Run() error {
 		case bytecode.OpJump:
 			// [OpJump 0, 3, OpConstant 0, 9]
 			pos := int(bytecode.ReadUint16(ins[ip+1:]))
+			if pos <= ip {
+				// a jump back is a loop iteration
+				if err := vm.limits.Step(); err != nil {
+					return err
+				}
+			}
 			vm.currentFrame().ip = pos - 1
 		case bytecode.OpNull:
 			if err := vm.push(NULL); err != nil {
@@ -130,11 +215,17 @@ func (vm *VM) Run() error {
 			if err != nil {
 				return err
 			}
+			if int(globalIndex) >= len(vm.globals) {
+				vm.globals = append(vm.globals, make([]object.Object, int(globalIndex)+1-len(vm.globals))...)
+			}
 			vm.globals[globalIndex] = value
 		case bytecode.OpArray:
 			size := int(bytecode.ReadUint16(ins[ip+1:]))
 			vm.currentFrame().ip += 2
 			start := vm.stackPointer - size
+			if err := vm.limits.AllocValues(size); err != nil {
+				return err
+			}
 			array := vm.makeArray(start, vm.stackPointer, size)
 			vm.stackPointer = start
 
@@ -157,31 +248,62 @@ func (vm *VM) Run() error {
 
 			switch val := ident.(type) {
 			case *object.Array:
-				index, ok := index.(*object.Number)
-				if !ok {
-					return fmt.Errorf("wrong type for array indexing")
+				index, err := object.ArrayIndex(index)
+				if err != nil {
+					return err
 				}
-				if int(index.Value) >= len(val.Body) {
-					newArr := make([]object.Object, int(index.Value)+1)
+				if index >= len(val.Body) {
+					if err := vm.limits.AllocValues(index + 1 - len(val.Body)); err != nil {
+						return err
+					}
+					newArr := make([]object.Object, index+1)
 					copy(newArr, val.Body)
+					// the elements between the old end and index are null
+					for i := len(val.Body); i < index; i++ {
+						newArr[i] = NULL
+					}
 					val.Body = newArr
 				}
-				val.Body[index.Value] = expr
+				val.Body[index] = expr
 			case *object.Dictionary:
 				hash, ok := index.(object.Hasher)
 				if !ok {
 					return fmt.Errorf("dictionary key unhashbale: %s", index.String())
 				}
-				keyHash := hash.Hash()
-				val.Value[keyHash] = object.KeyValue{Key: index, Value: expr}
+				if _, ok := val.Get(hash); !ok {
+					if err := vm.limits.AllocEntries(1); err != nil {
+						return err
+					}
+				}
+				val.Set(hash, expr)
+			case object.HostObject:
+				if err := val.SetProperty(object.ToString(index), expr); err != nil {
+					return err
+				}
 			default:
 				return fmt.Errorf("cannot index with type=%v", ident)
 			}
 
+		case bytecode.OpDelete:
+			index, err := vm.pop()
+			if err != nil {
+				return err
+			}
+			ident, err := vm.pop()
+			if err != nil {
+				return err
+			}
+			if err := vm.runDelete(ident, index); err != nil {
+				return err
+			}
+
 		case bytecode.OpDic:
 			size := int(bytecode.ReadUint16(ins[ip+1:]))
 			vm.currentFrame().ip += 2
 			start := vm.stackPointer - size
+			if err := vm.limits.AllocEntries(size / 2); err != nil {
+				return err
+			}
 			dictionary, err := vm.makeDictionary(start, vm.stackPointer)
 			if err != nil {
 				return err
@@ -207,10 +329,13 @@ func (vm *VM) Run() error {
 		case bytecode.OpGetGlobal:
 			globalIndex := bytecode.ReadUint16(ins[ip+1:])
 			vm.currentFrame().ip += 2
-			value := vm.globals[globalIndex]
-			if value == nil {
+			if int(globalIndex) >= len(vm.globals) || vm.globals[globalIndex] == nil {
+				if int(globalIndex) < len(vm.globalNames) && vm.globalNames[globalIndex] != "" {
+					return fmt.Errorf("variable not defined: %s", vm.globalNames[globalIndex])
+				}
 				return fmt.Errorf("variable not defined")
 			}
+			value := vm.globals[globalIndex]
 
 			if err := vm.push(value); err != nil {
 				return err
@@ -252,6 +377,9 @@ func (vm *VM) Run() error {
 			if err := vm.push(returnValue); err != nil {
 				return err
 			}
+			if vm.framesIndex < depth {
+				return nil
+			}
 		case bytecode.OpReturn:
 			frame := vm.popFrame()
 			vm.stackPointer = frame.basePointer
@@ -260,12 +388,14 @@ func (vm *VM) Run() error {
 			if err := vm.push(NULL); err != nil {
 				return err
 			}
+			if vm.framesIndex < depth {
+				return nil
+			}
 		case bytecode.OpGetBuiltIn:
-			index := bytecode.ReadUnit8(ins[ip+1:])
-			vm.currentFrame().ip += 1
+			index := int(bytecode.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
 
-			def := object.Builtins[index]
-			err := vm.push(def)
+			err := vm.push(vm.builtins.At(index))
 			if err != nil {
 				return err
 			}
@@ -316,12 +446,19 @@ func (vm *VM) pushClosure(index, free int) error {
 	return vm.push(closure)
 }
 
-func (vm *VM) pushFrame(f *Frame) {
-	if vm.framesIndex > MAX_FRAMES {
-		panic("maximum frame reached")
+// pushFrame pushes the frame of a call, the main frame is not a call so the calls in progress
+// are framesIndex - 1.
+func (vm *VM) pushFrame(f *Frame) error {
+	if vm.framesIndex > vm.limits.MaxDepth() {
+		fn := f.function.Fn
+		return object.StackOverflowError(fn.Name, fn.Pos)
+	}
+	if vm.framesIndex == len(vm.frames) {
+		vm.frames = append(vm.frames, f)
 	}
 	vm.frames[vm.framesIndex] = f
 	vm.framesIndex++
+	return nil
 }
 
 func (vm *VM) popFrame() *Frame {
@@ -333,43 +470,44 @@ func (vm *VM) runIndexExpression(identifier, index object.Object) error {
 	identifierType := identifier.Type()
 	indexType := index.Type()
 	switch {
-	case identifierType == object.ARRAY_OBJECT && indexType == object.NUMBER_OBJECT:
+	case identifierType == object.ARRAY_OBJECT && (indexType == object.NUMBER_OBJECT || indexType == object.FLOAT_OBJECT):
 		return vm.runArrayIndex(identifier, index)
-	case identifierType == object.ARRAY_OBJECT && indexType == object.STRING_OBJECT:
-		return vm.runArrayMethod(identifier, index)
+	case identifierType == object.STRING_OBJECT && indexType == object.NUMBER_OBJECT:
+		return vm.runStringIndex(identifier, index)
 	case identifierType == object.DICTIONARY_OBJECT:
 		return vm.runDictionaryIndex(identifier, index)
+	case identifierType == object.NULL_OBJECT && indexType == object.STRING_OBJECT:
+		// null.foo compiles to an index, it fails with the error of the evaluator
+		return fmt.Errorf("cannot read property %s of null", index.(*object.String).Value)
+	case indexType == object.STRING_OBJECT:
+		// any other value reads its property like the evaluator, a missing one is null
+		return vm.runMember(identifier, index)
 	}
 
 	return fmt.Errorf("index operation not supported for %s[%s]", identifierType, indexType)
 }
 
-func (vm *VM) runArrayMethod(identifier, index object.Object) error {
-	arrayObj, ok := identifier.(*object.Array)
+func (vm *VM) runMember(identifier, index object.Object) error {
+	name, ok := index.(*object.String)
 	if !ok {
-		return fmt.Errorf("not array object passed to index array")
+		return fmt.Errorf("non string is used for member access %s", identifier.Type())
 	}
-	methdName, ok := index.(*object.String)
+	val, ok := object.Member(identifier, name.Value)
 	if !ok {
-		return fmt.Errorf("non string is used for method access array")
+		return vm.push(NULL)
 	}
+	return vm.push(val)
+}
 
-	switch methdName.Value {
-	case "push":
-		if err := vm.push(arrayObj); err != nil {
-			return nil
-		}
-		if err := vm.push(object.ArrayPush); err != nil {
-			return nil
-		}
-	case "size":
-		if err := vm.push(&object.Number{Value: int64(len(arrayObj.Body))}); err != nil {
-			return err
-		}
-	default:
-		return fmt.Errorf("undefined array method: %s", methdName.Value)
+// runStringIndex pushes the UTF-16 code unit at the index as a one unit string, NULL when out of range.
+func (vm *VM) runStringIndex(identifier, index object.Object) error {
+	str := identifier.(*object.String)
+	num := index.(*object.Number)
+	unit, ok := str.CodeUnitAt(int(num.Value))
+	if !ok {
+		return vm.push(NULL)
 	}
-	return nil
+	return vm.push(&object.String{Value: object.StringFromCodeUnits([]uint16{unit})})
 }
 
 func (vm *VM) runArrayIndex(identifier, index object.Object) error {
@@ -377,11 +515,10 @@ func (vm *VM) runArrayIndex(identifier, index object.Object) error {
 	if !ok {
 		return fmt.Errorf("not array object passed to index array")
 	}
-	num, ok := index.(*object.Number)
+	numIdex, ok := object.IntegerIndex(index)
 	if !ok {
-		return fmt.Errorf("non number is used to index array")
+		return fmt.Errorf("index operation not supported for %s[%s]", identifier.Type(), index.Type())
 	}
-	numIdex := num.Value
 	max := int64(len(arrayObj.Body) - 1)
 
 	if numIdex < 0 || numIdex > max {
@@ -396,45 +533,94 @@ func (vm *VM) runCall(numArgs int) error {
 	case *object.Closure:
 		return vm.callClosure(caller, numArgs)
 	case *object.BuiltIn:
-		if caller == object.ArrayPush {
-			arr := vm.stack[vm.stackPointer-2-numArgs]
-			arr, ok := arr.(*object.Array)
-			if !ok {
-				return fmt.Errorf("not an array?")
-			}
-			arg := vm.stack[vm.stackPointer-numArgs]
-			object.ArrayPush.Function(arr, arg)
-			vm.stackPointer = vm.stackPointer - numArgs - 1
-			return nil
-		}
-		return vm.callBuiltin(caller, numArgs)
+		args := vm.stack[vm.stackPointer-numArgs : vm.stackPointer]
+		return vm.callBuiltin(caller.Name, caller.Function(vm, args...), numArgs)
+	case *object.BoundMethod:
+		args := vm.stack[vm.stackPointer-numArgs : vm.stackPointer]
+		return vm.callBuiltin(caller.Method.Name, caller.Call(vm, args...), numArgs)
 	}
-	return fmt.Errorf("calling non-function and non-built-in")
+	// the OpCall and its operand were read, the frame is past them
+	frame := vm.currentFrame()
+	return object.NotFunctionError(caller, frame.function.Fn.Callees[frame.ip-1])
 }
 
 func (vm *VM) callClosure(fn *object.Closure, numArgs int) error {
+	if err := vm.limits.Step(); err != nil {
+		return err
+	}
 
 	frame := NewFrame(fn, vm.stackPointer-numArgs)
-	vm.pushFrame(frame)
+	if size := frame.basePointer + fn.Fn.NumLocals; size > len(vm.stack) && !vm.growStack(size) {
+		return object.StackOverflowError(fn.Fn.Name, fn.Fn.Pos)
+	}
+	if err := vm.pushFrame(frame); err != nil {
+		return err
+	}
 
+	// parameters without an argument are null
+	for i := vm.stackPointer; i < frame.basePointer+fn.Fn.NumLocals; i++ {
+		vm.stack[i] = NULL
+	}
 	vm.stackPointer = frame.basePointer + fn.Fn.NumLocals
 
 	return nil
 }
 
-func (vm *VM) callBuiltin(builtin *object.BuiltIn, numArgs int) error {
-	args := vm.stack[vm.stackPointer-numArgs : vm.stackPointer]
+// Call runs fn with args and returns its result, it lets builtins call back into the program.
+// A closure runs in a nested run loop on top of the current frames, an error stops it and
+// is returned as an *object.Error.
+func (vm *VM) Call(fn object.Object, args ...object.Object) object.Object {
+	switch fn := fn.(type) {
+	case *object.Closure:
+		stackPointer, framesIndex := vm.stackPointer, vm.framesIndex
+		result, err := vm.runClosure(fn, args)
+		if err != nil {
+			vm.stackPointer, vm.framesIndex = stackPointer, framesIndex
+			return object.NewError(err)
+		}
+		return result
+	case *object.BuiltIn:
+		return fn.Function(vm, args...)
+	case *object.BoundMethod:
+		return fn.Call(vm, args...)
+	}
+	return object.NotFunctionError(fn, "")
+}
 
-	result := builtin.Function(args...)
+// runClosure pushes fn and its arguments and runs it until it returns.
+func (vm *VM) runClosure(fn *object.Closure, args []object.Object) (object.Object, error) {
+	if err := vm.push(fn); err != nil {
+		return nil, err
+	}
+	for _, arg := range args {
+		if err := vm.push(arg); err != nil {
+			return nil, err
+		}
+	}
+	if err := vm.callClosure(fn, len(args)); err != nil {
+		return nil, err
+	}
+	if err := vm.run(vm.framesIndex); err != nil {
+		return nil, err
+	}
+	return vm.pop()
+}
+
+// callBuiltin replaces the callee and its arguments on the stack with the result of a builtin call.
+// An error raised by a function the builtin called back records the builtin in its stack.
+func (vm *VM) callBuiltin(name string, result object.Object, numArgs int) error {
 	vm.stackPointer = vm.stackPointer - numArgs - 1
 
+	if err, ok := result.(*object.Error); ok {
+		if err.Pos.Line != 0 {
+			err.Called(name, vm.currentFrame().Pos())
+		}
+		return err
+	}
 	if result != nil {
-		vm.push(result)
-	} else {
-		vm.push(NULL)
+		return vm.push(result)
 	}
-
-	return nil
+	return vm.push(NULL)
 }
 
 func (vm *VM) runDictionaryIndex(identifier, index object.Object) error {
@@ -448,15 +634,34 @@ func (vm *VM) runDictionaryIndex(identifier, index object.Object) error {
 		return fmt.Errorf("unable to hash key for indexing dictionary")
 	}
 
-	keyValue, ok := dic.Value[key.Hash()]
+	val, ok := dic.Get(key)
 	if !ok {
 		return vm.push(NULL)
 	}
-	return vm.push(keyValue.Value)
+	return vm.push(val)
+}
+
+// runDelete removes index from a dictionary, deleting an array element leaves NULL in its place.
+func (vm *VM) runDelete(identifier, index object.Object) error {
+	switch identifier := identifier.(type) {
+	case *object.Dictionary:
+		key, ok := index.(object.Hasher)
+		if !ok {
+			return fmt.Errorf("dictionary key unhashbale: %s", index.String())
+		}
+		identifier.Delete(key)
+	case *object.Array:
+		if idx, ok := index.(*object.Number); ok && idx.Value >= 0 && idx.Value < int64(len(identifier.Body)) {
+			identifier.Body[idx.Value] = NULL
+		}
+	default:
+		return fmt.Errorf("cannot delete property of %s", identifier.Type())
+	}
+	return vm.push(TRUE)
 }
 
 func (vm *VM) makeDictionary(start, end int) (object.Object, error) {
-	dic := make(map[object.Hash]object.KeyValue)
+	dic := object.NewDictionary()
 
 	for i := start; i < end; i += 2 {
 		key := vm.stack[i]
@@ -467,10 +672,9 @@ func (vm *VM) makeDictionary(start, end int) (object.Object, error) {
 			return nil, fmt.Errorf("dictionary key unhashbale: %s", key.String())
 		}
 
-		keyHash := hash.Hash()
-		dic[keyHash] = object.KeyValue{Key: key, Value: value}
+		dic.Set(hash, value)
 	}
-	return &object.Dictionary{Value: dic}, nil
+	return dic, nil
 }
 
 func (vm *VM) makeArray(start, end, size int) object.Object {
@@ -488,19 +692,46 @@ func (vm *VM) runBinaryOperation(op bytecode.Opcode) error {
 		return err
 	}
 
-	switch {
-	case left.Type() == object.NUMBER_OBJECT || left.Type() == object.FLOAT_OBJECT:
-		left, right, isNumber, err := vm.checkNumberType(left, right)
-		if err != nil {
-			break
-		}
+	if l, r, isNumber, err := vm.checkNumberType(left, right); err == nil {
 		if isNumber {
-			return vm.runNumberOperation(op, left, right)
+			return vm.runNumberOperation(op, l, r)
 		}
-		return vm.runFloatOperation(op, left, right)
+		return vm.runFloatOperation(op, l, r)
+	}
+
+	if op == bytecode.OpAdd {
+		left, right = object.ToPrimitive(left), object.ToPrimitive(right)
+		_, lString := left.(*object.String)
+		_, rString := right.(*object.String)
+		if lString || rString {
+			l, r := object.ToString(left), object.ToString(right)
+			if err := vm.limits.AllocBytes(len(l) + len(r)); err != nil {
+				return err
+			}
+			return vm.push(&object.String{Value: l + r})
+		}
+	}
+
+	l, r, isNumber, err := vm.checkNumberType(object.ToNumber(left), object.ToNumber(right))
+	if err != nil {
+		return err
+	}
+	if isNumber {
+		return vm.runNumberOperation(op, l, r)
 	}
+	return vm.runFloatOperation(op, l, r)
+}
 
-	return fmt.Errorf("unsupported binary operation: %s %s", left.Type(), right.Type())
+// runBitwiseOperation applies a bitwise operator on the operands wrapped to 32 bit integers.
+func (vm *VM) runBitwiseOperation(op bytecode.Opcode, left, right object.Object) error {
+	l, r := object.ToInt32(left), object.ToInt32(right)
+	switch op {
+	case bytecode.OpSHL:
+		return vm.push(&object.Number{Value: int64(l << (uint32(r) & 31))})
+	case bytecode.OpXOR:
+		return vm.push(&object.Number{Value: int64(l ^ r)})
+	}
+	return fmt.Errorf("unknown bitwise operator: %d", op)
 }
 
 func (vm *VM) checkNumberType(left, right object.Object) (object.Object, object.Object, bool, error) {
@@ -540,16 +771,14 @@ func (vm *VM) runNumberOperation(op bytecode.Opcode, left, right object.Object)
 	case bytecode.OpAdd:
 		result = leftValue.Value + rightValue.Value
 	case bytecode.OpDiv:
-		if (leftValue.Value % rightValue.Value) != 0 {
+		if rightValue.Value == 0 || (leftValue.Value%rightValue.Value) != 0 {
 			l := float64(leftValue.Value)
 			r := float64(rightValue.Value)
 			return vm.push(&object.Float{Value: l / r})
 		}
 		result = leftValue.Value / rightValue.Value
-	case bytecode.OpSHL:
-		result = leftValue.Value << rightValue.Value
-	case bytecode.OpXOR:
-		result = leftValue.Value ^ rightValue.Value
+	case bytecode.OpSHL, bytecode.OpXOR:
+		return vm.runBitwiseOperation(op, left, right)
 	default:
 		return fmt.Errorf("unknown number operator: %d", op)
 	}
@@ -577,6 +806,8 @@ func (vm *VM) runFloatOperation(op bytecode.Opcode, left, right object.Object) e
 		result = leftValue.Value + rightValue.Value
 	case bytecode.OpDiv:
 		result = leftValue.Value / rightValue.Value
+	case bytecode.OpSHL, bytecode.OpXOR:
+		return vm.runBitwiseOperation(op, left, right)
 	default:
 		return fmt.Errorf("unknown float operator: %d", op)
 	}
@@ -589,25 +820,32 @@ func (vm *VM) runComparison(op bytecode.Opcode) error {
 		return err
 	}
 
-	if l, r, isNumber, err := vm.checkNumberType(left, right); err == nil {
-		if isNumber {
-			return vm.compareNumber(op, l, r)
-		}
-		return vm.compareFloat(op, l, r)
-	} else {
-		if err.Error() != "left and right is not a number or float" {
-			return err
-		}
-	}
-
 	switch op {
 	case bytecode.OpEqual:
-		return vm.push(nativeBool(right == left))
+		return vm.push(nativeBool(object.LooseEqual(left, right)))
 	case bytecode.OpNotEqual:
-		return vm.push(nativeBool(right != left))
-	default:
-		return fmt.Errorf("unknown operator: %d (%s %s)", op, left.Type(), right.Type())
+		return vm.push(nativeBool(!object.LooseEqual(left, right)))
+	case bytecode.OpStrictEqual:
+		return vm.push(nativeBool(object.StrictEqual(left, right)))
+	case bytecode.OpStrictNotEqual:
+		return vm.push(nativeBool(!object.StrictEqual(left, right)))
+	}
+
+	left, right = object.ToPrimitive(left), object.ToPrimitive(right)
+	if l, ok := left.(*object.String); ok {
+		if r, ok := right.(*object.String); ok {
+			return vm.push(nativeBool(object.CompareStrings(l.Value, r.Value) > 0))
+		}
 	}
+
+	l, r, isNumber, err := vm.checkNumberType(object.ToNumber(left), object.ToNumber(right))
+	if err != nil {
+		return err
+	}
+	if isNumber {
+		return vm.compareNumber(op, l, r)
+	}
+	return vm.compareFloat(op, l, r)
 }
 
 func (vm *VM) compareNumber(op bytecode.Opcode, left, right object.Object) error {
@@ -621,10 +859,6 @@ func (vm *VM) compareNumber(op bytecode.Opcode, left, right object.Object) error
 	}
 
 	switch op {
-	case bytecode.OpEqual:
-		return vm.push(nativeBool(rightValue.Value == leftValue.Value))
-	case bytecode.OpNotEqual:
-		return vm.push(nativeBool(rightValue.Value != leftValue.Value))
 	case bytecode.OpGreaterThan:
 		return vm.push(nativeBool(leftValue.Value > rightValue.Value))
 	default:
@@ -643,10 +877,6 @@ func (vm *VM) compareFloat(op bytecode.Opcode, left, right object.Object) error
 	}
 
 	switch op {
-	case bytecode.OpEqual:
-		return vm.push(nativeBool(rightValue.Value == leftValue.Value))
-	case bytecode.OpNotEqual:
-		return vm.push(nativeBool(rightValue.Value != leftValue.Value))
 	case bytecode.OpGreaterThan:
 		return vm.push(nativeBool(leftValue.Value > rightValue.Value))
 	default:
@@ -660,15 +890,7 @@ func (vm *VM) runBang() error {
 		return err
 	}
 
-	switch operand {
-	case TRUE:
-		return vm.push(FALSE)
-	case FALSE:
-		return vm.push(TRUE)
-	case NULL:
-		return vm.push(TRUE)
-	}
-	return vm.push(FALSE)
+	return vm.push(nativeBool(!object.ToBoolean(operand)))
 }
 
 func (vm *VM) runMinus() error {
@@ -677,7 +899,7 @@ func (vm *VM) runMinus() error {
 		return err
 	}
 
-	switch r := operand.(type) {
+	switch r := object.ToNumber(operand).(type) {
 	case *object.Number:
 		return vm.push(&object.Number{Value: -r.Value})
 	case *object.Float:
@@ -701,8 +923,9 @@ func (vm *VM) popLeftRight() (object.Object, object.Object, error) {
 }
 
 func (vm *VM) push(ob object.Object) error {
-	if vm.stackPointer >= STACK_SIZE {
-		return errors.New("stack overflow")
+	if vm.stackPointer >= len(vm.stack) && !vm.growStack(vm.stackPointer+1) {
+		fn := vm.currentFrame().function.Fn
+		return object.StackOverflowError(fn.Name, fn.Pos)
 	}
 
 	vm.stack[vm.stackPointer] = ob
@@ -711,6 +934,18 @@ func (vm *VM) push(ob object.Object) error {
 	return nil
 }
 
+// growStack makes room for size values on the stack and reports whether it fits under the ceiling.
+// A builtin holds its arguments as a slice of the old stack, they are left as they are.
+func (vm *VM) growStack(size int) bool {
+	if size > vm.maxStack {
+		return false
+	}
+	stack := make([]object.Object, min(max(2*len(vm.stack), size), vm.maxStack))
+	copy(stack, vm.stack)
+	vm.stack = stack
+	return true
+}
+
 func (vm *VM) pop() (object.Object, error) {
 	if vm.stackPointer == 0 {
 		return nil, errors.New("trying to pop an empty stack")
@@ -735,13 +970,5 @@ func nativeBool(input bool) *object.Boolean {
 }
 
 func isTruthy(obj object.Object) bool {
-	switch obj := obj.(type) {
-
-	case *object.Boolean:
-		return obj.Value
-	case *object.Null:
-		return false
-	default:
-		return true
-	}
+	return object.ToBoolean(obj)
 }
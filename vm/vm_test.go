@@ -1,7 +1,14 @@
 package vm
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/jf550-kent/jsgo/compiler"
 	"github.com/jf550-kent/jsgo/object"
@@ -92,6 +99,72 @@ func TestBooleanExpression(t *testing.T) {
 	testVmTests(t, tests)
 }
 
+func TestEquality(t *testing.T) {
+	tests := []vmTestCase{
+		{`var a = "x"; var b = "x"; a == b;`, true},
+		{`"1" == 1`, true},
+		{`"1" === 1`, false},
+		{`1 === 1.0`, true},
+		{`1 !== 2`, true},
+		{`true == 1`, true},
+		{`true === 1`, false},
+		{`false == "0"`, true},
+		{`false == ""`, true},
+		{`null == null`, true},
+		{`null === null`, true},
+		{`null == 0`, false},
+		{`null == false`, false},
+		{`"" == 0`, true},
+		{`" 12 " == 12`, true},
+		{`"abc" == 0`, false},
+		{`[1, 2] == "1,2"`, true},
+		{`[1] == 1`, true},
+		{`[1] == [1]`, false},
+		{`var a = [1]; a === a;`, true},
+		{`{"a": 1} == "[object Object]"`, true},
+		{`0.1 + 0.2 == 0.3`, false},
+		{`"b" != "a"`, true},
+	}
+	testVmTests(t, tests)
+}
+
+func TestCoercion(t *testing.T) {
+	tests := []vmTestCase{
+		{"5 + true;", 6},
+		{"-true;", -1},
+		{"true + false;", 1},
+		{"true - false;", 1},
+		{"5 + null;", 5},
+		{`"5" * "2";`, 10},
+		{`"6" / 4;`, 1.5},
+		{`"3" - 1;`, 2},
+		{`1 + "2";`, "12"},
+		{`"a" + 1.5;`, "a1.5"},
+		{`"a" + null;`, "anull"},
+		{`"a" + true;`, "atrue"},
+		{`[1, 2] + "";`, "1,2"},
+		{`[1, 2] + [3];`, "1,23"},
+		{`{} + "";`, "[object Object]"},
+		{`1 + 2 + "3";`, "33"},
+		{`"1" + 2 + 3;`, "123"},
+		{`"10" > 9;`, true},
+		{`1 / 0 > 1000000;`, true},
+		{`0 / 0 == 0 / 0;`, false},
+		{"1 << 32;", 1},
+		{"1.5 ^ 3;", 2},
+		{"if (0) { 1 } else { 2 };", 2},
+		{`if ("") { 1 } else { 2 };`, 2},
+		{`if ("0") { 1 } else { 2 };`, 1},
+		{"if ([]) { 1 } else { 2 };", 1},
+		{"if (0.0) { 1 } else { 2 };", 2},
+		{"!0;", true},
+		{`!"";`, true},
+		{"!{};", false},
+		{"!null;", true},
+	}
+	testVmTests(t, tests)
+}
+
 func TestConditionals(t *testing.T) {
 	tests := []vmTestCase{
 		{"if (true) { 100 }", 100},
@@ -114,7 +187,7 @@ func TestGlobalStatement(t *testing.T) {
 		{"var apple = 99; apple", 99},
 		{"var one = 80.9; var two = 2; one + two", 82.9},
 		{"var one = 1; var two = one + one; one + two", 3},
-		{"var one = 1; one = 29 one;", 29},
+		{"var one = 1; one = 29\none;", 29},
 	}
 
 	testVmTests(t, tests)
@@ -123,6 +196,76 @@ func TestGlobalStatement(t *testing.T) {
 func TestStringExpression(t *testing.T) {
 	tests := []vmTestCase{
 		{`"Hello world"`, "Hello world"},
+		{`"foo" + "bar";`, "foobar"},
+		{`"n" + 1 + 2;`, "n12"},
+		{`"a" < "b";`, true},
+		{`"B" < "a";`, true},
+		{`"abc" > "ab";`, true},
+		{`"abc" < "abc";`, false},
+		{`"10" < "9";`, true},
+		{`"\uFFFF" < "😀";`, false},
+		{`"abc".length;`, 3},
+		{`"héllo".length;`, 5},
+		{`"😀".length;`, 2},
+		{`"".length;`, 0},
+		{`var s = "xyz"; s.length;`, 3},
+		{`"abc"[1];`, "b"},
+		{`"héllo"[1];`, "é"},
+		{`"abc"["length"];`, 3},
+		{`var s = "abc"; s[s.length - 1];`, "c"},
+		{`"abc"[5];`, NULL},
+		{`"abc"[-1];`, NULL},
+	}
+	testVmTests(t, tests)
+}
+
+func TestStringMethods(t *testing.T) {
+	tests := []vmTestCase{
+		{`"hello world".slice(6);`, "world"},
+		{`"hello world".slice(-5, -2);`, "wor"},
+		{`"hello".slice(3, 1);`, ""},
+		{`"hello".substring(4, 1);`, "ell"},
+		{`"hello".substring(-3);`, "hello"},
+		{`"a😀b".slice(1, 3);`, "😀"},
+		{`"banana".indexOf("an");`, 1},
+		{`"banana".indexOf("an", 2);`, 3},
+		{`"banana".indexOf("x");`, -1},
+		{`"banana".lastIndexOf("an");`, 3},
+		{`"banana".lastIndexOf("an", 2);`, 1},
+		{`"😀banana".indexOf("b");`, 2},
+		{`"banana".includes("nan");`, true},
+		{`"banana".includes("nab");`, false},
+		{`"banana".startsWith("ban");`, true},
+		{`"banana".startsWith("nan", 2);`, true},
+		{`"banana".endsWith("ana");`, true},
+		{`"banana".endsWith("ban", 3);`, true},
+		{`"a,b,,c".split(",").length;`, 4},
+		{`"a,b,,c".split(",")[3];`, "c"},
+		{`"a,b,c".split(",", 2).length;`, 2},
+		{`"abc".split("")[1];`, "b"},
+		{`"abc".split().length;`, 1},
+		{`"".split(",").length;`, 1},
+		{`"  hi \n".trim();`, "hi"},
+		{`"  hi ".trimStart();`, "hi "},
+		{`"  hi ".trimEnd();`, "  hi"},
+		{`"Hello".toUpperCase();`, "HELLO"},
+		{`"Hello".toLowerCase();`, "hello"},
+		{`"a-b-c".replace("-", "+");`, "a+b-c"},
+		{`"a-b-c".replaceAll("-", "+");`, "a+b+c"},
+		{`"abc".replace("b", "[$&]");`, "a[b]c"},
+		{`"abc".replace("b", "$$");`, "a$c"},
+		{`"ab".replaceAll("", "-");`, "-a-b-"},
+		{`"5".padStart(3, "0");`, "005"},
+		{`"abc".padStart(6, "12");`, "121abc"},
+		{`"abc".padEnd(5);`, "abc  "},
+		{`"abc".padEnd(2, "x");`, "abc"},
+		{`"ab".repeat(3);`, "ababab"},
+		{`"ab".repeat(0);`, ""},
+		{`"abc".charCodeAt(1);`, 98},
+		{`"😀".charCodeAt(0);`, 55357},
+		{`String.fromCharCode(104, 105);`, "hi"},
+		{`String.fromCharCode(55357, 56832);`, "😀"},
+		{`var s = "x"; s.padStart(2, "-") + s.repeat(2);`, "-xxx"},
 	}
 	testVmTests(t, tests)
 }
@@ -140,20 +283,20 @@ func TestArray(t *testing.T) {
 func TestDictionary(t *testing.T) {
 	tests := []vmTestCase{
 		{
-			"{}", map[object.Hash]int64{},
+			"{}", map[object.Hasher]int64{},
 		},
 		{
 			"{1: 2, 2: 3}",
-			map[object.Hash]int64{
-				(&object.Number{Value: 1}).Hash(): 2,
-				(&object.Number{Value: 2}).Hash(): 3,
+			map[object.Hasher]int64{
+				&object.Number{Value: 1}: 2,
+				&object.Number{Value: 2}: 3,
 			},
 		},
 		{
 			"{1 + 1: 2 * 2, 3 + 3: 4 * 4}",
-			map[object.Hash]int64{
-				(&object.Number{Value: 2}).Hash(): 4,
-				(&object.Number{Value: 6}).Hash(): 16,
+			map[object.Hasher]int64{
+				&object.Number{Value: 2}: 4,
+				&object.Number{Value: 6}: 16,
 			},
 		},
 	}
@@ -161,6 +304,52 @@ func TestDictionary(t *testing.T) {
 	testVmTests(t, tests)
 }
 
+func TestDictionaryOrderAndKeys(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"b": 1, "a": 2, "c": 3}`, "{b: 1, a: 2, c: 3}"},
+		{`var d = {"b": 1}; d["a"] = 2; d["b"] = 3; d;`, "{b: 3, a: 2}"},
+		{`{1.2: 1, 1.7: 2}`, "{1.2: 1, 1.7: 2}"},
+		{`var d = {"a": 1, "b": 2, "c": 3}; delete d.b; d;`, "{a: 1, c: 3}"},
+		{`var d = {"a": 1, "b": 2}; delete d["a"]; d["a"] = 3; d;`, "{b: 2, a: 3}"},
+		{`var d = {}; d[1] = "a"; d[1.0] = "b"; d;`, "{1: b}"},
+		{`var d = {}; d[4 / 2] = "a"; d[2] = "b"; d[2.5] = "c"; delete d[2.0]; d;`, "{2.5: c}"},
+	}
+
+	for _, tt := range tests {
+		main := parser.Parse("", []byte(tt.input))
+
+		com := compiler.New()
+		if err := com.Compile(main); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		vm := New(com.ByteCode())
+		if err := vm.Run(); err != nil {
+			t.Fatalf("vm error: %s", err)
+		}
+
+		dic := checkObject[*object.Dictionary](t, vm.LastPopStack())
+		if dic.String() != tt.expected {
+			t.Errorf("wrong dictionary. expected=%s, got=%s", tt.expected, dic.String())
+		}
+	}
+}
+
+func TestDelete(t *testing.T) {
+	tests := []vmTestCase{
+		{`var d = {1.2: 1, 1.7: 2}; d[1.7];`, 2},
+		{`var d = {"a": 1}; delete d.a;`, true},
+		{`var d = {"a": 1}; delete d.a; d.a;`, NULL},
+		{`var d = {"a": 1}; delete d.missing; d.a;`, 1},
+		{`var arr = [1, 2]; delete arr[0]; arr[0];`, NULL},
+	}
+
+	testVmTests(t, tests)
+}
+
 func TestIndexing(t *testing.T) {
 	tests := []vmTestCase{
 		{"[1, 2, 3][1]", 2},
@@ -186,6 +375,9 @@ func TestCallingFunction(t *testing.T) {
 		{input: "var a = function() { return 1; 3; 2; }; a();", expected: 1},
 		{input: "var a = function() {  1; return 3; return 2; }; a();", expected: 3},
 		{input: "var a = function() { }; a();", expected: NULL},
+		{input: "var a = function() { return; }; a();", expected: NULL},
+		{input: "var a = function() { return\n 3; }; a();", expected: NULL},
+		{input: "var a = function() {\n var b = 1\n return b +\n 2\n}\na()", expected: 3},
 	}
 	testVmTests(t, tests)
 }
@@ -195,7 +387,7 @@ func TestCallingFunctionsWithLocalScope(t *testing.T) {
 		{input: "var foo = function() { var apple = 98; apple; }; foo();", expected: 98},
 		{input: "var sum = function() { var first = 9; var second = 9; return first + second; }; sum()", expected: 18},
 		{input: "var sum = function() { var first = 9; var second = 9; return first + second; }; var ten = function() { var five = 5; return five + five;}; ten() + sum()", expected: 28},
-		{input: "var globalNumber = 90; var minus = function() { var num = 1 return globalNumber - num; } var add = function() { var num = 8 return globalNumber + num; } minus() + add()", expected: 187},
+		{input: "var globalNumber = 90; var minus = function() { var num = 1; return globalNumber - num; }; var add = function() { var num = 8; return globalNumber + num; }; minus() + add()", expected: 187},
 		{input: "var sum = function(a, b) { return a + b; }; sum(2,2)", expected: 4},
 		{input: "var sum = function(a, b) { var c = a + b; return c; }; sum(1, 2);", expected: 3},
 		{input: "var sum = function(a, b) { var c = a + b; return c; }; sum(1, 2) + sum(3, 4);", expected: 10},
@@ -239,11 +431,14 @@ func TestRecursiveFibonacci(t *testing.T) {
 func TestBracket(t *testing.T) {
 	tests := []vmTestCase{
 		{input: "var arr = [10]; arr[1] = 90; arr;", expected: []int{10, 90}},
+		{input: "var arr = [10]; arr[3] = 90; arr[1] == null;", expected: true},
+		{input: "var arr = []; arr[Math.sqrt(4)] = 7; arr[2];", expected: 7},
+		{input: "var arr = [1, 2, 3]; arr[4 / 2.0];", expected: 3},
 		{
-			input: `var dic = { "next": 10}; dic["current"] = 20 dic;`,
-			expected: map[object.Hash]int64{
-				(&object.String{Value: "next"}).Hash():    10,
-				(&object.String{Value: "current"}).Hash(): 20,
+			input: `var dic = { "next": 10}; dic["current"] = 20; dic;`,
+			expected: map[object.Hasher]int64{
+				&object.String{Value: "next"}:    10,
+				&object.String{Value: "current"}: 20,
 			},
 		},
 	}
@@ -254,8 +449,8 @@ func TestBracket(t *testing.T) {
 func TestForLoop(t *testing.T) {
 	tests := []vmTestCase{
 		// {input: "for (var i = 0; i < 10; i = i + 1) { 29; }; i;", expected: 10},
-		// {input: "var i = 0 for (; i < 0;) { 29; }; i;", expected: 0},
-		{input: "var i = 0 for (; i < 0;) { 29; }; i;", expected: 0},
+		// {input: "var i = 0; for (; i < 0;) { 29; }; i;", expected: 0},
+		{input: "var i = 0; for (; i < 0;) { 29; }; i;", expected: 0},
 		{input: `
 		
 		var isShorterThan = function(x, y) {
@@ -302,14 +497,286 @@ func TestDebug(t *testing.T) {
 	}
 }
 
+func TestMember(t *testing.T) {
+	tests := []vmTestCase{
+		{`var node = {"next": {"value": 3}}; node.next.value;`, 3},
+		{`var node = {"value": 1}; node.value = 7; node.value;`, 7},
+		{`var node = {"value": 1}; node.missing;`, NULL},
+		{`var list = {"items": [1, 2]}; list.items.push(3); list.items.length;`, 3},
+		{`var x = 5; x.foo;`, NULL},
+		{`var x = 1.5; x["foo"];`, NULL},
+		{`var x = true; x.foo;`, NULL},
+		{`[1].map(function(v) { return v.q; })[0];`, NULL},
+	}
+	testVmTests(t, tests)
+}
+
 func TestBuiltIn(t *testing.T) {
 	tests := []vmTestCase{
 		{`var arr = []; arr["push"](10); arr["push"](19); arr;`, []int{10, 19}},
-		{`var arr = [1, 2, 3]; arr["size"];`, 3},
+		{`var arr = [1, 2, 3]; arr.length;`, 3},
+		{`var arr = [1]; arr.push(2, 3);`, 3},
+		{`var arr = []; arr.push(4); arr.push(5); arr;`, []int{4, 5}},
+		{`var arr = [1, 2]; arr["length"];`, 2},
+	}
+	testVmTests(t, tests)
+}
+
+func TestArrayMethods(t *testing.T) {
+	tests := []vmTestCase{
+		{`var arr = [1, 2, 3]; arr.pop() + arr.length;`, 5},
+		{`var arr = []; arr.pop();`, NULL},
+		{`var arr = [1, 2, 3]; arr.shift() + arr[0];`, 3},
+		{`var arr = [3]; arr.unshift(1, 2); arr.join("");`, "123"},
+		{`[1, 2, 3, 4].slice(1, -1).join();`, "2,3"},
+		{`var arr = [1, 2, 3, 4]; arr.splice(1, 2, "a").join() + ";" + arr.join();`, "2,3;1,a,4"},
+		{`var arr = [1, 2, 3]; arr.splice(1).length + arr.length;`, 3},
+		{`[1].concat([2, 3], 4).join();`, "1,2,3,4"},
+		{`[1, 2, 1].indexOf(1, 1);`, 2},
+		{`[1, 2].indexOf("1");`, -1},
+		{`[1, 0 / 0].includes(0 / 0);`, true},
+		{`[1, 2].includes(3);`, false},
+		{`[1, null, "a"].join("-");`, "1--a"},
+		{`[1, 2, 3].reverse().join();`, "3,2,1"},
+		{`[1, 2, 3].map(function(x) { return x * 2; }).join();`, "2,4,6"},
+		{`[5, 6].map(function(x, i) { return i; }).join();`, "0,1"},
+		{`[1, 2, 3, 4].filter(function(x) { return x > 2; }).join();`, "3,4"},
+		{`[1, 2, 3].reduce(function(acc, x) { return acc + x; });`, 6},
+		{`[1, 2, 3].reduce(function(acc, x) { return acc + x; }, "");`, "123"},
+		{`var sum = [0]; [1, 2, 3].forEach(function(x) { sum[0] = sum[0] + x; }); sum[0];`, 6},
+		{`[1, 2, 3].find(function(x) { return x > 1; });`, 2},
+		{`[1, 2, 3].find(function(x) { return x > 5; });`, NULL},
+		{`[1, 2, 3].some(function(x) { return x == 2; });`, true},
+		{`[1, 2, 3].every(function(x) { return x > 1; });`, false},
+		{`[10, 9, 1].sort().join();`, "1,10,9"},
+		{`[3, 1, 2].sort(function(a, b) { return a - b; }).join();`, "1,2,3"},
+		{`var pairs = [[1, "a"], [0, "b"], [1, "c"], [0, "d"]];
+		pairs.sort(function(x, y) { return x[0] - y[0]; });
+		pairs.map(function(p) { return p[1]; }).join("");`, "bdac"},
+		{`var offset = 10; [1, 2].map(function(x) { return x + offset; }).join();`, "11,12"},
+		{`[[1, 2], [3]].map(function(row) { return row.map(function(x) { return x * 10; }).join(" "); }).join();`, "10 20,30"},
+		{`function() { var n = 2; return [1, 2].map(function(x) { return x * n; }).join(); }();`, "2,4"},
+	}
+	testVmTests(t, tests)
+}
+
+func TestCallbackError(t *testing.T) {
+	main := parser.Parse("", []byte(`[1, 2].map(function(x) { return x(); });`))
+
+	com := compiler.New()
+	if err := com.Compile(main); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	vm := New(com.ByteCode())
+	err := vm.Run()
+	if err == nil {
+		t.Fatalf("expected error from callback")
+	}
+	if err.Error() != "not a function: x (NUMBER)" {
+		t.Errorf("wrong error. got=%q", err)
+	}
+	if vm.framesIndex != 1 {
+		t.Errorf("frames not unwound. got=%d", vm.framesIndex)
+	}
+}
+
+// apply is a Go builtin that calls its first argument with the rest.
+var apply = &object.BuiltIn{
+	Name: "apply",
+	Function: func(caller object.Caller, args ...object.Object) object.Object {
+		return caller.Call(args[0], args[1:]...)
+	},
+}
+
+func TestReentrantCall(t *testing.T) {
+	vm := runVm(t, `[
+		function(f, n) { return [n, n + 1].map(function(x) { return f(x) * 10; }); },
+		function(x) { return x + 1; }
+	];`)
+	functions := vm.LastPopStack().(*object.Array).Body
+	fn, inner := functions[0], functions[1]
+
+	// Go calls the script which calls back into Go which calls the script again
+	result := vm.Call(apply, fn, inner, &object.Number{Value: 1})
+	if err, ok := result.(*object.Error); ok {
+		t.Fatalf("call failed: %s", err)
+	}
+	testObject(t, []int{20, 30}, result)
+
+	result = vm.Call(fn, inner, &object.Number{Value: 5})
+	testObject(t, []int{60, 70}, result)
+
+	if vm.framesIndex != 1 {
+		t.Errorf("frames not unwound. got=%d", vm.framesIndex)
+	}
+}
+
+func TestReentrantCallError(t *testing.T) {
+	errHost := errors.New("host failure")
+	fail := &object.BuiltIn{
+		Name: "fail",
+		Function: func(_ object.Caller, _ ...object.Object) object.Object {
+			return object.NewError(errHost)
+		},
+	}
+
+	vm := runVm(t, `[
+		function(f) { return [1, 2].map(function(x) { return f(x); }); },
+		function(a, b) { return a + b; }
+	];`)
+	functions := vm.LastPopStack().(*object.Array).Body
+	fn, add := functions[0], functions[1]
+	stackPointer := vm.stackPointer
+
+	result := vm.Call(fn, fail)
+	err, ok := result.(*object.Error)
+	if !ok {
+		t.Fatalf("expected error, got=%T (%+v)", result, result)
+	}
+	if !errors.Is(err, errHost) {
+		t.Errorf("error does not wrap the host error. got=%q", err)
+	}
+	if vm.framesIndex != 1 || vm.stackPointer != stackPointer {
+		t.Errorf("vm not restored. frames=%d, stack=%d", vm.framesIndex, vm.stackPointer)
+	}
+
+	// the vm is still usable after the error
+	testObject(t, 3, vm.Call(apply, add, &object.Number{Value: 1}, &object.Number{Value: 2}))
+}
+
+func runVm(t *testing.T, input string) *VM {
+	t.Helper()
+
+	com := compiler.New()
+	if err := com.Compile(parser.Parse("", []byte(input))); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	vm := New(com.ByteCode())
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	return vm
+}
+
+func TestCollections(t *testing.T) {
+	tests := []vmTestCase{
+		{`var m = new Map(); m.set(1, "a"); m.get(1);`, "a"},
+		{`var m = new Map(); m.set(1, "a").set(1.0, "b"); m.size;`, 1},
+		{`var m = new Map(); m.set("1", "a"); m.get(1);`, NULL},
+		{`var k = [1]; var m = new Map(); m.set(k, 5); m.get(k);`, 5},
+		{`var m = new Map(); m.set([1], 5); m.get([1]);`, NULL},
+		{`var f = function() {}; var m = new Map([[f, 1], [{}, 2]]); m.get(f);`, 1},
+		{`var m = new Map([[0 / 0, 1]]); m.get(0 / 0);`, 1},
+		{`var m = new Map([[null, 1]]); m.has(null);`, true},
+		{`var m = new Map([["b", 1], ["a", 2], ["c", 3]]); m.delete("a"); m.set("a", 4); m.keys().join();`, "b,c,a"},
+		{`var m = new Map([["x", 1]]); m.delete("x") + m.delete("x");`, 1},
+		{`var m = new Map([["x", 1], ["y", 2]]); m.clear(); m.size;`, 0},
+		{`new Map([["x", 1], ["y", 2]]).values().join();`, "1,2"},
+		{`new Map([["x", 1]]).entries()[0].join();`, "x,1"},
+		{`var m = new Map([["x", 1], ["y", 2]]); var out = []; m.forEach(function(v, k) { out.push(k + v); }); out.join();`, "x1,y2"},
+		{`var m = new Map([[1, 1]]); m.forEach(function(v, k) { if (k < 4) { m.set(k + 1, v) }; }); m.size;`, 4},
+		{`var m = new Map([[1, 1]]); new Map(m).get(1);`, 1},
+		{`var s = new Set([1, 2, 2, 3]); s.size;`, 3},
+		{`var s = new Set(); s.add(1).add(1.0).add("1"); s.size;`, 2},
+		{`var a = []; var s = new Set([a]); s.has(a) === !s.has([]);`, true},
+		{`var s = new Set([3, 1, 2]); s.delete(1); s.add(1); s.values().join();`, "3,2,1"},
+		{`new Set("hello").size;`, 4},
+		{`var s = new Set([1, 2]); var total = [0]; s.forEach(function(v, k) { total[0] = total[0] + v + k; }); total[0];`, 6},
+		{`new Set([1, 2]).entries()[1].join();`, "2,2"},
+		{`var s = new Set([1]); s.clear(); s.has(1);`, false},
+		{`var w = new WeakMap(); var k = {}; w.set(k, 1); w.get(k);`, 1},
+		{`var w = new WeakMap(); var k = {}; w.set(k, 1); w.delete(k); w.has(k);`, false},
+		{`new WeakMap().get({});`, NULL},
+		{`var m = new Map(); m.set("a", 1); m["size"];`, 1},
+		{`new Map() + "";`, "[object Map]"},
+		{`JSON.stringify([new Set([1])]);`, "[{}]"},
 	}
 	testVmTests(t, tests)
 }
 
+func TestJSON(t *testing.T) {
+	tests := []vmTestCase{
+		{`JSON.parse("42");`, 42},
+		{`JSON.parse("1.5e2");`, 150.0},
+		{`JSON.parse("\"hi\"");`, "hi"},
+		{`JSON.parse("[1, [2, true], null]")[1][1];`, true},
+		{`JSON.parse("{\"b\": 1, \"a\": {\"c\": [3]}}").a.c[0];`, 3},
+		{`JSON.stringify(JSON.parse("{\"b\": 1, \"a\": 2, \"c\": 3}"));`, `{"b":1,"a":2,"c":3}`},
+		{`JSON.parse("{\"a\": 1, \"b\": 2}", function(k, v) { if (k == "a") { return v * 10; }; return v; }).a;`, 10},
+		{`JSON.parse("[1, 2]", function(k, v) { if (k == "") { return v.length; }; return v; });`, 2},
+		{`JSON.stringify(1);`, "1"},
+		{`JSON.stringify(1.5);`, "1.5"},
+		{`JSON.stringify(0 / 0);`, "null"},
+		{`JSON.stringify("a\"b\n");`, `"a\"b\n"`},
+		{`JSON.stringify(null);`, "null"},
+		{`JSON.stringify([1, "a", false, null]);`, `[1,"a",false,null]`},
+		{`JSON.stringify({"z": 1, "a": [2], "m": {}});`, `{"z":1,"a":[2],"m":{}}`},
+		{`var d = {"x": 1}; d["y"] = 2; delete d["x"]; d["x"] = 3; JSON.stringify(d);`, `{"y":2,"x":3}`},
+		{`JSON.stringify({"f": function() {}, "a": [function() {}]});`, `{"a":[null]}`},
+		{`JSON.stringify([1, [2]], null, 2);`, "[\n  1,\n  [\n    2\n  ]\n]"},
+		{`JSON.stringify({"a": 1, "b": []}, null, "--");`, "{\n--\"a\": 1,\n--\"b\": []\n}"},
+		{`JSON.stringify({"a": 1, "b": 2, "c": 3}, ["c", "a"]);`, `{"a":1,"c":3}`},
+		{`JSON.stringify({"a": 1, "b": "x"}, function(k, v) { if (k == "a") { return v + 1; }; return v; });`, `{"a":2,"b":"x"}`},
+		{`var shared = [1]; JSON.stringify([shared, shared]);`, "[[1],[1]]"},
+	}
+	testVmTests(t, tests)
+}
+
+func TestMath(t *testing.T) {
+	tests := []vmTestCase{
+		{"Math.floor(2.7);", 2},
+		{"Math.floor(-2.5);", -3},
+		{"Math.floor(5);", 5},
+		{"Math.ceil(2.1);", 3},
+		{"Math.round(2.5);", 3},
+		{"Math.round(-2.5);", -2},
+		{"Math.round(0.49999999999999994);", 0},
+		{"Math.trunc(-2.7);", -2},
+		{"Math.floor(1 / 0) > 1000;", true},
+		{`Math.floor("3.5");`, 3},
+		{"Math.abs(-4);", 4},
+		{"Math.abs(-4.5);", 4.5},
+		{"Math.sqrt(16);", 4.0},
+		{"Math.pow(2, 10);", 1024},
+		{"Math.pow(2, 0.5) == Math.sqrt(2);", true},
+		{"Math.pow(2, -1);", 0.5},
+		{"Math.pow(2.5, 2);", 6.25},
+		{"Math.min(3, 1.5, 2);", 1.5},
+		{"Math.max(3, 1.5, 2);", 3},
+		{"Math.max() < 0;", true},
+		{"Math.min(1, 0 / 0) == Math.min(1, 0 / 0);", false},
+		{"Math.sin(0);", 0.0},
+		{"Math.cos(0);", 1.0},
+		{"Math.tan(0);", 0.0},
+		{"Math.atan2(0, 1);", 0.0},
+		{"Math.log(1);", 0.0},
+		{"Math.exp(0);", 1.0},
+		{"Math.PI > 3.14 === Math.PI < 3.15;", true},
+		{"Math.E;", math.E},
+		{"var r = Math.random(); if (r > -1) { r < 1 } else { false };", true},
+		{"var floor = Math.floor; floor(9.9);", 9},
+	}
+	testVmTests(t, tests)
+
+	seeded := func() object.Object {
+		com := compiler.New()
+		if err := com.Compile(parser.Parse("", []byte("Math.random();"))); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		vm := New(com.ByteCode())
+		limits := object.NewLimits(context.Background(), 0)
+		limits.SetRandom(rand.New(rand.NewSource(7)))
+		vm.SetLimits(limits)
+		if err := vm.Run(); err != nil {
+			t.Fatalf("vm error: %s", err)
+		}
+		return vm.LastPopStack()
+	}
+	first := seeded()
+	testFloat(t, first.(*object.Float).Value, seeded())
+}
+
 func testVmTests(t *testing.T, tests []vmTestCase) {
 	t.Helper()
 
@@ -353,22 +820,22 @@ func testObject(t *testing.T, expected any, actual object.Object) {
 		for i, expectedElem := range expected {
 			testNumberObject(t, int64(expectedElem), array.Body[i])
 		}
-	case map[object.Hash]int64:
+	case map[object.Hasher]int64:
 		dic, ok := actual.(*object.Dictionary)
 		if !ok {
 			t.Errorf("object is not Dictinary. got=%T (%+v)", actual, actual)
 		}
 
-		if len(dic.Value) != len(expected) {
-			t.Errorf("dictionary has wrong number of key-value pair. want=%d, got=%d", len(expected), len(dic.Value))
+		if dic.Len() != len(expected) {
+			t.Errorf("dictionary has wrong number of key-value pair. want=%d, got=%d", len(expected), dic.Len())
 		}
 
 		for expectedKey, expectedValue := range expected {
-			pair, ok := dic.Value[expectedKey]
+			val, ok := dic.Get(expectedKey)
 			if !ok {
 				t.Errorf("no pair for given key in Pairs")
 			}
-			testNumberObject(t, expectedValue, pair.Value)
+			testNumberObject(t, expectedValue, val)
 		}
 	case *object.Null:
 		if expected != NULL {
@@ -423,3 +890,394 @@ func checkObject[expected any](t *testing.T, obj object.Object) expected {
 	}
 	return v
 }
+
+func TestHostBuiltins(t *testing.T) {
+	registry := object.NewStandardRegistry()
+	registry.Function("double", 1, func(_ object.Caller, args ...object.Object) object.Object {
+		return &object.Number{Value: args[0].(*object.Number).Value * 2}
+	})
+	registry.Function("second", 2, func(_ object.Caller, args ...object.Object) object.Object {
+		return args[1]
+	})
+	registry.Function("console.count", 0, func(_ object.Caller, args ...object.Object) object.Object {
+		return &object.Number{Value: int64(len(args))}
+	})
+	registry.Function("units.length.km", 1, func(_ object.Caller, args ...object.Object) object.Object {
+		return &object.Number{Value: args[0].(*object.Number).Value * 1000}
+	})
+	registry.Function("units.length.cm", 1, func(_ object.Caller, args ...object.Object) object.Object {
+		return &object.Number{Value: args[0].(*object.Number).Value / 100}
+	})
+	// more than 256 builtins need the two byte operand of OpGetBuiltIn
+	for i := 0; i < 300; i++ {
+		registry.Define(fmt.Sprintf("host%d", i), &object.Number{Value: int64(i)})
+	}
+
+	tests := []vmTestCase{
+		{"double(21);", 42},
+		{"second(1);", NULL},
+		{"console.count(1, 2, 3);", 3},
+		{"host299 + host0;", 299},
+		{"Math.floor(1.5);", 1},
+		{"units.length.km(2) + units.length.cm(300);", 2003},
+	}
+
+	for _, tt := range tests {
+		com := compiler.NewWithBuiltins(registry)
+		if err := com.Compile(parser.Parse("", []byte(tt.input))); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		vm := New(com.ByteCode())
+		if err := vm.Run(); err != nil {
+			t.Fatalf("vm error: %s", err)
+		}
+		testObject(t, tt.expected, vm.LastPopStack())
+	}
+
+	if _, ok := object.Console.Properties["count"]; ok {
+		t.Errorf("registering console.count changed the shared console")
+	}
+	com := compiler.New()
+	if err := com.Compile(parser.Parse("", []byte("double(1);"))); err == nil {
+		t.Errorf("expected double to be undefined with the default builtins")
+	}
+}
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		input  string
+		budget int64
+		steps  int64
+	}{
+		{"for (var i = 0; i < 10; i = i + 1) { i; }", 0, 10},
+		{"var f = function(n) { return n; }; f(1); f(2);", 0, 2},
+		{"var f = function(n) { return n; }; [1, 2, 3].map(f);", 0, 3},
+		{"var i = 0; if (i < 1) { i = 1; } else { i = 2; }", 0, 0},
+	}
+
+	for _, tt := range tests {
+		com := compiler.New()
+		if err := com.Compile(parser.Parse("", []byte(tt.input))); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		vm := New(com.ByteCode())
+		limits := object.NewLimits(context.Background(), tt.budget)
+		vm.SetLimits(limits)
+		if err := vm.Run(); err != nil {
+			t.Fatalf("vm error: %s", err)
+		}
+		if limits.Steps() != tt.steps {
+			t.Errorf("%q wrong number of steps. want=%d got=%d", tt.input, tt.steps, limits.Steps())
+		}
+	}
+}
+
+func TestLimitsStopProgram(t *testing.T) {
+	tests := []struct {
+		input string
+		ctx   func() (context.Context, context.CancelFunc)
+		want  error
+	}{
+		{"for (;true;) { 1; }", budgetOnly, object.ErrBudgetExceeded},
+		{"var f = function() { return f(); }; f();", budgetOnly, object.ErrBudgetExceeded},
+		{"[1].map(function() { for (;true;) { 1; } });", budgetOnly, object.ErrBudgetExceeded},
+		{"for (;true;) { 1; }", cancelled, object.ErrInterrupted},
+		{"for (;true;) { 1; }", timeout, object.ErrInterrupted},
+	}
+
+	for _, tt := range tests {
+		ctx, cancel := tt.ctx()
+		com := compiler.New()
+		if err := com.Compile(parser.Parse("", []byte(tt.input))); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		vm := New(com.ByteCode())
+		budget := int64(0)
+		if tt.want == object.ErrBudgetExceeded {
+			budget = 500
+		}
+		vm.SetLimits(object.NewLimits(ctx, budget))
+		err := vm.Run()
+		cancel()
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%q wrong error. want=%v got=%v", tt.input, tt.want, err)
+		}
+	}
+}
+
+func budgetOnly() (context.Context, context.CancelFunc) {
+	return context.WithCancel(context.Background())
+}
+
+func cancelled() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx, cancel
+}
+
+func timeout() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), 10*time.Millisecond)
+}
+
+func TestMemoryLimit(t *testing.T) {
+	tests := []struct {
+		input string
+		fails bool
+	}{
+		{"var a = []; a[100000000] = 1;", true},
+		{`var s = "ab"; s.repeat(100000000);`, true},
+		{`"a".padStart(100000000);`, true},
+		{`var s = "abcdefgh"; for (var i = 0; i < 20; i = i + 1) { s = s + s; }`, true},
+		{"var d = {}; for (var i = 0; i < 100000; i = i + 1) { d[i] = i; }", true},
+		{"var m = new Map(); for (var i = 0; i < 100000; i = i + 1) { m.set(i, i); }", true},
+		{"var a = [1, 2]; for (var i = 0; i < 20; i = i + 1) { a = a.concat(a); }", true},
+		{"var a = []; for (var i = 0; i < 2000000; i = i + 1) { a.push(i); }", true},
+		{"var a = [1]; for (var i = 0; i < 21; i = i + 1) { a = [a, a]; } JSON.stringify(a);", true},
+		{`JSON.parse("[" + "1,".repeat(100000) + "1]");`, true},
+		{`"a".repeat(100000).split("");`, true},
+		{"var a = []; a[30000] = 1; new Set(a);", true},
+		{"var a = []; a[40000] = 1; a.map(function(x) { return x; }).map(function(x) { return x; });", true},
+		{"var m = new Map(); for (var i = 0; i < 15000; i = i + 1) { m.set(i, i); } m.entries();", true},
+		{`var a = [1, 2, 3]; a[10] = 1; "x".repeat(100) + [1, 2].join("-");`, false},
+	}
+
+	for _, tt := range tests {
+		com := compiler.New()
+		if err := com.Compile(parser.Parse("", []byte(tt.input))); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		vm := New(com.ByteCode())
+		limits := object.NewLimits(context.Background(), 0)
+		limits.SetMemoryLimit(1 << 20)
+		vm.SetLimits(limits)
+		err := vm.Run()
+		if !tt.fails {
+			if err != nil {
+				t.Errorf("%q unexpected error: %s", tt.input, err)
+			}
+			continue
+		}
+		if !errors.Is(err, object.ErrMemoryLimit) {
+			t.Errorf("%q expected the memory limit error. got=%v", tt.input, err)
+			continue
+		}
+		if err.Error() != "RangeError: memory limit of 1048576 bytes exceeded" {
+			t.Errorf("%q wrong error message. got=%q", tt.input, err.Error())
+		}
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	tests := []struct {
+		input    string
+		depth    int
+		expected string
+	}{
+		{"var f = function() { return f(); }; f();", 0, "RangeError: Maximum call stack size exceeded in f 1:9"},
+		{"var count = function(n) { if (n == 0) { return 0; } return 1 + count(n - 1); }; count(100);", 50, "RangeError: Maximum call stack size exceeded in count 1:13"},
+		{"[1].map(function(x) { var g = function() { return g(); }; return g(); });", 10, "RangeError: Maximum call stack size exceeded in g 1:31"},
+	}
+
+	for _, tt := range tests {
+		com := compiler.New()
+		if err := com.Compile(parser.Parse("", []byte(tt.input))); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		vm := New(com.ByteCode())
+		limits := object.NewLimits(context.Background(), 0)
+		limits.SetMaxDepth(tt.depth)
+		vm.SetLimits(limits)
+		err := vm.Run()
+		if !errors.Is(err, object.ErrStackOverflow) {
+			t.Errorf("%q expected the stack overflow error. got=%v", tt.input, err)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("%q wrong error message. want=%q got=%q", tt.input, tt.expected, err.Error())
+		}
+	}
+
+	input := "var count = function(n) { if (n == 0) { return 0; } return 1 + count(n - 1); }; count(50);"
+	testVmTests(t, []vmTestCase{{input, 50}})
+}
+
+func TestGrowingStack(t *testing.T) {
+	input := "var count = function(n) { if (n == 0) { return 0; } return 1 + count(n - 1); }; count(5000);"
+	com := compiler.New()
+	if err := com.Compile(parser.Parse("", []byte(input))); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	program := com.ByteCode()
+	if program.NumGlobals != 1 {
+		t.Errorf("wrong number of globals. want=1 got=%d", program.NumGlobals)
+	}
+
+	vm := New(program)
+	limits := object.NewLimits(context.Background(), 0)
+	limits.SetMaxDepth(10000)
+	vm.SetLimits(limits)
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	testObject(t, 5000, vm.LastPopStack())
+	if len(vm.globals) != 1 {
+		t.Errorf("wrong size of the globals. want=1 got=%d", len(vm.globals))
+	}
+
+	vm = New(program)
+	vm.SetLimits(limits)
+	vm.SetMaxStackSize(1000)
+	err := vm.Run()
+	if !errors.Is(err, object.ErrStackOverflow) {
+		t.Fatalf("expected the stack overflow error. got=%v", err)
+	}
+	if want := "RangeError: Maximum call stack size exceeded in count 1:13"; err.Error() != want {
+		t.Errorf("wrong error message. want=%q got=%q", want, err.Error())
+	}
+}
+
+func TestErrorStackTrace(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // the end of the stack trace
+	}{
+		{
+			"var inner = function(x) {\n  return x.y.z;\n};\nvar outer = function() {\n  return inner(null);\n};\nouter();",
+			"\n    at inner (main.js:2:10)\n    at outer (main.js:5:10)\n    at <main> (main.js:7:1)",
+		},
+		{
+			"var f = function(n) { return n.a.b; };\n[1, 2].map(function(x) {\n  return f(x);\n});",
+			"\n    at f (main.js:1:30)\n    at <anonymous> (main.js:3:10)\n    at map (native)\n    at <main> (main.js:2:1)",
+		},
+		{
+			"var a = 1;\n\"a\".repeat(-1);",
+			"RangeError: Invalid count value: -1\n    at <main> (main.js:2:1)",
+		},
+		{
+			"var a = 1;\na();",
+			"\n    at <main> (main.js:2:1)",
+		},
+		{"var x = x;", "variable not defined: x\n    at <main> (main.js:1:9)"},
+		{"var a = null;\na.foo;", "cannot read property foo of null\n    at <main> (main.js:2:1)"},
+		{"var a = 5;\nvar f = function() {\n  return a(1);\n};\nf();", "not a function: a (NUMBER)\n    at f (main.js:3:10)\n    at <main> (main.js:5:1)"},
+		{"var d = {\"x\": true};\nd.x();", "not a function: BOOLEAN\n    at <main> (main.js:2:1)"},
+		{"var a = 1;\n\"abc\".repeat(1000000000000000000);", "RangeError: Invalid string length\n    at <main> (main.js:2:1)"},
+		{"var a = 1;\n\"abc\".padEnd(10000000000);", "RangeError: Invalid string length\n    at <main> (main.js:2:1)"},
+		{"var a = [];\na[-1] = 5;", "RangeError: Invalid array index -1\n    at <main> (main.js:2:2)"},
+		{"var a = [];\na[1.5] = 5;", "RangeError: Invalid array index 1.5\n    at <main> (main.js:2:2)"},
+	}
+
+	for _, tt := range tests {
+		com := compiler.New()
+		if err := com.Compile(parser.Parse("main.js", []byte(tt.input))); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		err := New(com.ByteCode()).Run()
+		errObj, ok := err.(*object.Error)
+		if !ok {
+			t.Fatalf("%q expected an *object.Error. got=%T (%v)", tt.input, err, err)
+		}
+		if trace := errObj.StackTrace(); !strings.HasSuffix(trace, tt.expected) {
+			t.Errorf("%q wrong stack trace. want suffix=%q got=%q", tt.input, tt.expected, trace)
+		}
+	}
+}
+
+func TestEncodedProgram(t *testing.T) {
+	input := `
+var fib = function(n) { if (n < 2) { return n; } return fib(n - 1) + fib(n - 2); };
+var adder = function(n) { return function(x) { return x + n; }; };
+var words = ["a", "b"].map(function(s) { return s + "!"; });
+double(adder(fib(10))(0.5)) + words.length;`
+
+	registry := object.NewStandardRegistry()
+	registry.Function("double", 1, func(_ object.Caller, args ...object.Object) object.Object {
+		return &object.Float{Value: args[0].(*object.Float).Value * 2}
+	})
+	com := compiler.NewWithBuiltins(registry)
+	if err := com.Compile(parser.Parse("main.js", []byte(input))); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	data, err := com.ByteCode().Encode()
+	if err != nil {
+		t.Fatalf("encode error: %s", err)
+	}
+
+	// the program runs with a registry defining its builtins at other indices
+	other := object.NewRegistry()
+	double, _ := registry.Lookup("double")
+	other.Define("double", double)
+	for _, entry := range object.Builtins.Entries() {
+		other.Define(entry.Name, entry.Value)
+	}
+	program, err := compiler.Decode(data, other)
+	if err != nil {
+		t.Fatalf("decode error: %s", err)
+	}
+	vm := New(program)
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	testObject(t, 113.0, vm.LastPopStack())
+
+	// errors of a decoded program keep their positions
+	com = compiler.New()
+	if err := com.Compile(parser.Parse("main.js", []byte("var f = function(a) {\n  return a.b.c;\n};\nf(null);"))); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	data, _ = com.ByteCode().Encode()
+	program, err = compiler.Decode(data, nil)
+	if err != nil {
+		t.Fatalf("decode error: %s", err)
+	}
+	errObj, ok := New(program).Run().(*object.Error)
+	if !ok {
+		t.Fatalf("expected an *object.Error")
+	}
+	if want := "\n    at f (main.js:2:10)\n    at <main> (main.js:4:1)"; !strings.HasSuffix(errObj.StackTrace(), want) {
+		t.Errorf("wrong stack trace. want suffix=%q got=%q", want, errObj.StackTrace())
+	}
+}
+
+// A decoded program with a corrupted byte is either rejected or runs without taking down the vm.
+func TestCorruptedProgram(t *testing.T) {
+	input := `
+var fib = function(n) { if (n < 2) { return n; } return fib(n - 1) + fib(n - 2); };
+var adder = function(n) { return function(x) { return x + n; }; };
+var words = {"a": [1, 2], "b": "c"};
+words["a"][1] = adder(fib(5))(2);
+delete words["b"];
+words.a.length;`
+
+	com := compiler.New()
+	if err := com.Compile(parser.Parse("main.js", []byte(input))); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	data, err := com.ByteCode().Encode()
+	if err != nil {
+		t.Fatalf("encode error: %s", err)
+	}
+
+	for i := range data {
+		for _, flip := range []byte{0x01, 0x02, 0x10, 0xff} {
+			corrupted := append([]byte{}, data...)
+			corrupted[i] ^= flip
+			program, err := compiler.Decode(corrupted, nil)
+			if err != nil {
+				continue
+			}
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						t.Fatalf("byte %d flipped by %#x: vm panic: %v", i, flip, r)
+					}
+				}()
+				limits := object.NewLimits(context.Background(), 10000)
+				limits.SetMemoryLimit(1 << 20)
+				vm := New(program)
+				vm.SetLimits(limits)
+				vm.Run()
+			}()
+		}
+	}
+}